@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const abiUsage = `usage: eventsdb abi <command> [flags]
+
+commands:
+  list [-name NAME] [-source FILE]   list registered events with their signature hash and source
+  show HASH                          show every definition registered for a signature hash
+  usage HASH                         count the stored events that use a signature hash
+  delete [-source FILE] HASH         remove the definitions of a signature hash
+  replace -source FILE ABI_FILE      replace the events registered for a source with an ABI file
+  conflicts                          list hashes registered with different definitions
+
+Entries deleted while their file is still in ABI_DIR are registered again on the next start.`
+
+func runABI(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", abiUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("abi "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "list":
+		name := flags.String("name", "", "only list events with this name")
+		source := flags.String("source", "", "only list events loaded from this source file")
+		flags.Parse(args)
+
+		events, err := service.ListABIEvents(*name, *source)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(events)
+		}
+		return printABIEvents(events)
+
+	case "show":
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb abi show HASH")
+		}
+
+		events, err := service.GetABIEvents(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return fmt.Errorf("no ABI event registered for %s", flags.Arg(0))
+		}
+		return printJSON(events)
+
+	case "usage":
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb abi usage HASH")
+		}
+
+		usage, err := service.ABIEventUsage(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(usage)
+		}
+
+		fmt.Printf("%s: %d stored events, %d decoded\n", usage.SignatureHash, usage.Total, usage.Decoded)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CONTRACT\tEVENTS\tFIRST BLOCK\tLAST BLOCK")
+		for _, contract := range usage.Contracts {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", contract.ContractAddress, contract.Count, contract.FirstBlock, contract.LastBlock)
+		}
+		return w.Flush()
+
+	case "delete":
+		source := flags.String("source", "", "only delete the definition loaded from this source file")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb abi delete [-source FILE] HASH")
+		}
+
+		deleted, err := service.DeleteABIEvents(flags.Arg(0), *source)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %d ABI events\n", deleted)
+		return nil
+
+	case "replace":
+		source := flags.String("source", "", "source file whose events are replaced")
+		flags.Parse(args)
+		if *source == "" || flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb abi replace -source FILE ABI_FILE")
+		}
+
+		abiData, err := os.ReadFile(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", flags.Arg(0), err)
+		}
+
+		written, err := service.ReplaceABISource(*source, abiData)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Registered %d ABI events for %s\n", written, *source)
+		return nil
+
+	case "conflicts":
+		flags.Parse(args)
+
+		conflicts, err := service.FindABIConflicts()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(conflicts)
+		}
+		if len(conflicts) == 0 {
+			fmt.Println("No conflicting ABI definitions")
+			return nil
+		}
+		for _, conflict := range conflicts {
+			fmt.Printf("%s has %d definitions:\n", conflict.SignatureHash, len(conflict.Definitions))
+			for _, definition := range conflict.Definitions {
+				fmt.Printf("  [%d] %s from %q: %s\n", definition.ID, definition.Signature, definition.SourceFile, definition.ABIEvent)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown abi command %q\n%s", command, abiUsage)
+	}
+}
+
+func printABIEvents(events []eventsdb.ABIEventSummary) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSIGNATURE HASH\tSIGNATURE\tSOURCE")
+	for _, event := range events {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", event.ID, event.SignatureHash, event.Signature, event.SourceFile)
+	}
+	return w.Flush()
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
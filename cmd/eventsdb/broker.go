@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const brokerUsage = `usage: eventsdb broker <command> [flags]
+
+commands:
+  status  show the pending messages and lag of the broker outbox
+
+Messages are published to BROKER_URL by eventsdb run.`
+
+func runBroker(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", brokerUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("broker "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "status":
+		flags.Parse(args)
+
+		status, err := service.BrokerStatus()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(status)
+		}
+
+		oldest := "-"
+		if status.OldestPending != nil {
+			oldest = time.Since(*status.OldestPending).Round(time.Second).String()
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PENDING\tTOMBSTONES\tRETAINED\tLAG BLOCKS\tOLDEST PENDING")
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", status.Pending, status.Tombstones, status.Retained, status.LagBlocks, oldest)
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown broker command %q\n%s", command, brokerUsage)
+	}
+}
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Matltin/event-fetcher/eventsdb"
 )
@@ -11,7 +17,98 @@ func main() {
 
 	service := eventsdb.NewIndexerService(cfg)
 
-	if err := service.Start(); err != nil {
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	// SIGINT and SIGTERM stop run and serve cleanly, a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	var err error
+	switch command {
+	case "run":
+		err = service.Start(ctx)
+	case "serve":
+		err = service.Serve(ctx)
+	case "redecode":
+		err = runRedecode(service, args)
+	case "abi":
+		err = runABI(service, args)
+	case "partitions":
+		err = runPartitions(service, args)
+	case "migrate":
+		err = runMigrate(service, args)
+	case "projections":
+		err = runProjections(service, args)
+	case "export":
+		err = runExport(service, args)
+	case "retention":
+		err = runRetention(ctx, service, args)
+	case "rollups":
+		err = runRollups(ctx, service, args)
+	case "query":
+		err = runQuery(service, args)
+	case "webhooks":
+		err = runWebhooks(service, args)
+	case "broker":
+		err = runBroker(service, args)
+	default:
+		log.Fatalf("unknown command %q (available: run, serve, redecode, abi, partitions, migrate, projections, export, retention, rollups, query, webhooks, broker)", command)
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
+
+func runRedecode(service *eventsdb.IndexerService, args []string) error {
+	flags := flag.NewFlagSet("redecode", flag.ExitOnError)
+	all := flags.Bool("all", false, "re-decode every stored event, not only the ones without a known signature")
+	batchSize := flags.Int("batch", eventsdb.DefaultRedecodeBatchSize, "number of events updated per transaction")
+	flags.Parse(args)
+
+	return service.Redecode(*all, *batchSize)
+}
+
+func runExport(service *eventsdb.IndexerService, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", eventsdb.ExportJSONL, "file format: csv, jsonl or parquet")
+	dir := flags.String("out", ".", "directory the files are written to")
+	prefix := flags.String("prefix", "events", "file name prefix")
+	fromBlock := flags.Uint64("from", 0, "first exported block")
+	toBlock := flags.Uint64("to", 0, "last exported block (default: the last indexed block)")
+	contract := flags.String("contract", "", "only export events of this contract address")
+	eventName := flags.String("event", "", "only export events with this name")
+	maxBytes := flags.Int64("rotate-bytes", 0, "start a new file after this many bytes, Parquet files grow 1000 rows at a time")
+	maxBlocks := flags.Uint64("rotate-blocks", 0, "start a new file every this many blocks")
+	cursor := flags.String("cursor", "", "name of a resumable export, continues after the block the last run ended at")
+	batchSize := flags.Int("batch", eventsdb.DefaultExportBatchSize, "number of events read per query")
+	flags.Parse(args)
+
+	result, err := service.Export(eventsdb.ExportOptions{
+		Format:        *format,
+		Dir:           *dir,
+		Prefix:        *prefix,
+		FromBlock:     *fromBlock,
+		ToBlock:       *toBlock,
+		Contract:      *contract,
+		EventName:     *eventName,
+		MaxFileBytes:  *maxBytes,
+		MaxFileBlocks: *maxBlocks,
+		Cursor:        *cursor,
+		BatchSize:     *batchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range result.Files {
+		fmt.Println(file)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const migrateUsage = `usage: eventsdb migrate <command> [flags]
+
+commands:
+  up                 apply every pending schema migration
+  down [-steps N]    revert the latest N applied migrations (default 1)
+  status             list the migrations known to this binary and when they were applied
+  binary             convert hashes, addresses, topics and data of blockchain_events to bytea (postgres only)
+
+After migrate binary, run the indexer with BINARY_STORAGE=true. The conversion rewrites the table and cannot be reverted.`
+
+func runMigrate(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+
+	switch command {
+	case "up":
+		flags.Parse(args)
+
+		applied, err := service.MigrateUp()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+		return nil
+
+	case "down":
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		flags.Parse(args)
+
+		reverted, err := service.MigrateDown(*steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migrations\n", reverted)
+		return nil
+
+	case "status":
+		jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+		flags.Parse(args)
+
+		statuses, err := service.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(statuses)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+
+	case "binary":
+		flags.Parse(args)
+		return service.MigrateToBinaryStorage()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const partitionsUsage = `usage: eventsdb partitions <command> [flags]
+
+commands:
+  list                   list the block range partitions of blockchain_events
+  detach -before BLOCK   detach the partitions that only hold blocks below BLOCK
+
+Detached partitions are renamed to <partition>_detached and kept as standalone tables, archive them
+with pg_dump and drop them. Writes to their blocks fail until the table is attached again or dropped.`
+
+func runPartitions(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", partitionsUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("partitions "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "list":
+		flags.Parse(args)
+
+		partitions, err := service.ListPartitions()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(partitions)
+		}
+		return printPartitions(partitions)
+
+	case "detach":
+		before := flags.Uint64("before", 0, "detach partitions whose blocks are all below this block")
+		flags.Parse(args)
+		if *before == 0 {
+			return fmt.Errorf("usage: eventsdb partitions detach -before BLOCK")
+		}
+
+		detached, err := service.DetachPartitionsBefore(*before)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(detached)
+		}
+		fmt.Printf("Detached %d partitions\n", len(detached))
+		return printPartitions(detached)
+
+	default:
+		return fmt.Errorf("unknown partitions command %q\n%s", command, partitionsUsage)
+	}
+}
+
+func printPartitions(partitions []eventsdb.EventPartition) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tFIRST BLOCK\tLAST BLOCK")
+	for _, partition := range partitions {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", partition.Name, partition.FromBlock, partition.ToBlock-1)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const projectionsUsage = `usage: eventsdb projections <command> [flags]
+
+commands:
+  list                            list the typed table of every registered event
+  rebuild [-event NAME|HASH]      drop and refill projection tables from blockchain_events
+
+Projection tables are kept up to date by the indexer when PROJECTIONS=true.`
+
+func runProjections(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", projectionsUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("projections "+command, flag.ExitOnError)
+
+	switch command {
+	case "list":
+		jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+		flags.Parse(args)
+
+		projections, err := service.ListProjections()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(projections)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TABLE\tSIGNATURE\tCOLUMNS")
+		for _, projection := range projections {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", projection.Table, projection.Signature, strings.Join(projection.Columns, ", "))
+		}
+		return w.Flush()
+
+	case "rebuild":
+		event := flags.String("event", "", "only rebuild the tables of this event name or signature hash")
+		batchSize := flags.Int("batch", eventsdb.DefaultRedecodeBatchSize, "number of events read per query")
+		flags.Parse(args)
+
+		rebuilt, err := service.RebuildProjections(*event, *batchSize)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rebuilt %d projection tables\n", rebuilt)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown projections command %q\n%s", command, projectionsUsage)
+	}
+}
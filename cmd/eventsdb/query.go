@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const queryUsage = `usage: eventsdb query <command> [flags]
+
+commands:
+  list         list the shipped reports of query/ for the configured storage
+  run NAME     run a shipped report, for example eventsdb query run query3
+  sql QUERY    run a read only SQL statement against the configured storage
+
+Reports read the rollup tables, which the indexer keeps when ROLLUPS=true.`
+
+func runQuery(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", queryUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("query "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "list":
+		flags.Parse(args)
+
+		reports, err := service.ListReports()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(reports)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "REPORT\tDESCRIPTION")
+		for _, report := range reports {
+			fmt.Fprintf(w, "%s\t%s\n", report.Name, report.Description)
+		}
+		return w.Flush()
+
+	case "run", "sql":
+		flags.Parse(args)
+		if flags.NArg() == 0 {
+			return fmt.Errorf("usage: eventsdb query %s [-json] %s", command, map[string]string{"run": "NAME", "sql": "QUERY"}[command])
+		}
+
+		var result *eventsdb.QueryResult
+		var err error
+		if command == "run" {
+			result, err = service.RunReport(flags.Arg(0))
+		} else {
+			result, err = service.Query(strings.Join(flags.Args(), " "))
+		}
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(result)
+		}
+		return printQueryResult(result)
+
+	default:
+		return fmt.Errorf("unknown query command %q\n%s", command, queryUsage)
+	}
+}
+
+func printQueryResult(result *eventsdb.QueryResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(result.Columns, "\t")))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			if value == nil {
+				cells[i] = "NULL"
+			} else {
+				cells[i] = fmt.Sprint(value)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const retentionUsage = `usage: eventsdb retention <command> [flags]
+
+commands:
+  run          apply the rules in RETENTION_FILE once
+  list         list the block ranges moved to archive files
+  restore ID   import the events of an archived range back into blockchain_events
+
+Archive files are gzip JSON Lines in ARCHIVE_DIR, one event per line as written by eventsdb export.`
+
+func runRetention(ctx context.Context, service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", retentionUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("retention "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "run":
+		flags.Parse(args)
+
+		results, err := service.ApplyRetention(ctx)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(results)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "RULE\tCUTOFF BLOCK\tARCHIVED\tFILES\tSTRIPPED")
+		for _, result := range results {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", result.Rule, result.CutoffBlock, result.Archived, result.Files, result.Stripped)
+		}
+		return w.Flush()
+
+	case "list":
+		flags.Parse(args)
+
+		ranges, err := service.ListArchivedRanges()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(ranges)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tRULE\tFIRST BLOCK\tLAST BLOCK\tEVENTS\tRESTORED\tFILE")
+		for _, archived := range ranges {
+			restored := "-"
+			if archived.RestoredAt != nil {
+				restored = archived.RestoredAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\n", archived.ID, archived.Rule, archived.FromBlock, archived.ToBlock, archived.Events, restored, archived.File)
+		}
+		return w.Flush()
+
+	case "restore":
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb retention restore ID")
+		}
+		id, err := strconv.ParseUint(flags.Arg(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid archived range id %q", flags.Arg(0))
+		}
+
+		restored, err := service.RestoreArchivedRange(uint(id))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d events\n", restored)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown retention command %q\n%s", command, retentionUsage)
+	}
+}
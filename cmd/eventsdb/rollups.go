@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const rollupsUsage = `usage: eventsdb rollups <command>
+
+commands:
+  rebuild   recompute event_counts_block, event_counts_hourly and event_counts_daily from blockchain_events
+
+The indexer keeps the rollups up to date when ROLLUPS=true. Rebuilding fetches the time of
+every block with events from RPC_URL in batched calls and no longer counts events removed by retention rules.`
+
+func runRollups(ctx context.Context, service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", rollupsUsage)
+	}
+
+	switch command := args[0]; command {
+	case "rebuild":
+		rows, err := service.RebuildRollups(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rebuilt rollups from %d block counts\n", rows)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown rollups command %q\n%s", command, rollupsUsage)
+	}
+}
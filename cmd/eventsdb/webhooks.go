@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Matltin/event-fetcher/eventsdb"
+)
+
+const webhooksUsage = `usage: eventsdb webhooks <command> [flags]
+
+commands:
+  add [-events A,B] [-contract ADDRESS] [-secret SECRET] NAME URL
+               POST the matching events to URL, every event when -events is empty
+  list         list the registered webhooks
+  remove NAME  delete a webhook and its queued deliveries
+  status       show the pending, retrying and dead deliveries of every webhook
+  retry NAME   queue the dead deliveries of a webhook again
+
+Deliveries are sent by eventsdb run. Each POST carries X-Eventsdb-Signature: sha256=<hex HMAC-SHA256 of the body>.`
+
+func runWebhooks(service *eventsdb.IndexerService, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", webhooksUsage)
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("webhooks "+command, flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of a table")
+
+	switch command {
+	case "add":
+		events := flags.String("events", "", "comma separated event names")
+		contract := flags.String("contract", "", "only events of this contract")
+		secret := flags.String("secret", "", "HMAC secret, generated when empty")
+		flags.Parse(args)
+		if flags.NArg() != 2 {
+			return fmt.Errorf("usage: eventsdb webhooks add [-events A,B] [-contract ADDRESS] [-secret SECRET] NAME URL")
+		}
+
+		var eventNames []string
+		if *events != "" {
+			eventNames = strings.Split(*events, ",")
+		}
+		webhook, err := service.AddWebhook(flags.Arg(0), flags.Arg(1), *contract, eventNames, *secret)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added webhook %s, signing secret: %s\n", webhook.Name, webhook.Secret)
+		return nil
+
+	case "list":
+		flags.Parse(args)
+
+		webhooks, err := service.ListWebhooks()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(webhooks)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tURL\tCONTRACT\tEVENTS")
+		for _, webhook := range webhooks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", webhook.Name, webhook.URL, orAll(webhook.ContractAddress), orAll(webhook.EventNames))
+		}
+		return w.Flush()
+
+	case "remove":
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb webhooks remove NAME")
+		}
+		return service.RemoveWebhook(flags.Arg(0))
+
+	case "status":
+		flags.Parse(args)
+
+		statuses, err := service.WebhookStatuses()
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(statuses)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPENDING\tRETRYING\tDEAD\tDELIVERED\tLAG BLOCKS\tOLDEST PENDING\tLAST ERROR")
+		for _, status := range statuses {
+			oldest := "-"
+			if status.OldestPending != nil {
+				oldest = time.Since(*status.OldestPending).Round(time.Second).String()
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", status.Name, status.Pending, status.Retrying, status.Dead, status.Delivered, status.LagBlocks, oldest, status.LastError)
+		}
+		return w.Flush()
+
+	case "retry":
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: eventsdb webhooks retry NAME")
+		}
+
+		queued, err := service.RetryWebhook(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d dead deliveries again\n", queued)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown webhooks command %q\n%s", command, webhooksUsage)
+	}
+}
+
+// orAll shows an empty filter as matching everything
+func orAll(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
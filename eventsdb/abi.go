@@ -43,7 +43,7 @@ type ABIInput struct {
 // BuildABIJSONArray constructs a valid ABI JSON array from database records
 func GetABIEventBySignatureHash(db *gorm.DB, signatureHash string) (*ABIEvent, error) {
 	var record ABIEventRecord
-	err := db.Where("event_signature_hash = ?", signatureHash).First(&record).Error
+	err := db.Where("event_signature_hash = ?", signatureHash).Order("id").First(&record).Error
 	if err != nil {
 		return nil, err
 	}
@@ -104,8 +104,37 @@ func Keccak256Hash(text string) string {
 	return fmt.Sprintf("0x%x", hasher.Sum(nil))
 }
 
+// abiFileExtensions lists the file types loaded from the ABI directory
+var abiFileExtensions = []string{".json", ".abi", ".txt"}
+
+// parseABIData parses any supported ABI format: a JSON ABI array, a Hardhat or Foundry
+// artifact with the ABI under an "abi" key, or human-readable declarations one per line
+func parseABIData(abiData []byte) ([]ABIEvent, error) {
+	trimmed := bytes.TrimSpace(abiData)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseABIJSON(trimmed)
+	}
+
+	return parseHumanReadableABI(string(abiData))
+}
+
 // Parse the original ABI JSON to extract component information
 func parseABIJSON(abiData []byte) ([]ABIEvent, error) {
+	// Hardhat and Foundry artifacts keep the ABI under an "abi" key
+	if trimmed := bytes.TrimSpace(abiData); len(trimmed) > 0 && trimmed[0] == '{' {
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(trimmed, &artifact); err != nil {
+			return nil, err
+		}
+		if len(artifact.ABI) == 0 {
+			// Debug and build-info files produced next to the artifacts carry no ABI
+			return nil, nil
+		}
+		abiData = artifact.ABI
+	}
+
 	var abiArray []json.RawMessage
 	if err := json.Unmarshal(abiData, &abiArray); err != nil {
 		return nil, err
@@ -113,6 +142,20 @@ func parseABIJSON(abiData []byte) ([]ABIEvent, error) {
 
 	var events []ABIEvent
 	for _, item := range abiArray {
+		// Human-readable ABIs are arrays of declarations
+		var declaration string
+		if err := json.Unmarshal(item, &declaration); err == nil {
+			if !strings.HasPrefix(strings.TrimSpace(declaration), "event ") {
+				continue
+			}
+			event, err := parseHumanReadableEvent(declaration)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+			continue
+		}
+
 		var entry map[string]any
 		if err := json.Unmarshal(item, &entry); err != nil {
 			continue
@@ -142,6 +185,17 @@ func parseABIJSON(abiData []byte) ([]ABIEvent, error) {
 	return events, nil
 }
 
+// isABIFile reports whether a file in the ABI directory should be loaded
+func isABIFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, abiExt := range abiFileExtensions {
+		if ext == abiExt {
+			return true
+		}
+	}
+	return false
+}
+
 // loadEventSignaturesOnDB scans ABI files and stores event signatures in the database
 func loadEventSignaturesOnDB(db *gorm.DB, abiDir string) error {
 	var counter int
@@ -151,7 +205,7 @@ func loadEventSignaturesOnDB(db *gorm.DB, abiDir string) error {
 			return fmt.Errorf("error walking path %s: %w", path, err)
 		}
 
-		if info.IsDir() || !strings.HasSuffix(strings.ToLower(info.Name()), ".json") {
+		if info.IsDir() || !isABIFile(info.Name()) {
 			return nil
 		}
 
@@ -160,42 +214,21 @@ func loadEventSignaturesOnDB(db *gorm.DB, abiDir string) error {
 			return fmt.Errorf("error reading file %s: %w", path, err)
 		}
 
-		// Parse the original ABI JSON first to get component information
-		abiEvents, err := parseABIJSON(abiData)
+		// Parse the original ABI first to get component information
+		abiEvents, err := parseABIData(abiData)
 		if err != nil {
-			log.Printf("Warning: Could not parse ABI JSON from %s: %v\n", path, err)
+			log.Printf("Warning: Could not parse ABI from %s: %v\n", path, err)
 			return nil
 		}
 
-		for _, e := range abiEvents {
-			counter++
-			eventSignature := BuildEventSignature(e)
-
-			// hash eventSignature
-			signatureHash := Keccak256Hash(eventSignature)
-
-			var record ABIEventRecord
-			err := db.Where("event_signature_hash = ?", signatureHash).First(&record).Error
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				eventJSON, err := json.Marshal(e)
-				if err != nil {
-					log.Println("Failed to Marshal: ", err)
-					continue
-				}
-				newRecord := ABIEventRecord{
-					EventSignatureHash: signatureHash,
-					EventName:          e.Name,
-					ABIEventJSON:       string(eventJSON),
-				}
-				if err := db.Create(&newRecord).Error; err != nil {
-					log.Println("Failed to add DataBase: ", err)
-					continue
-				}
-
-			} else if err != nil {
-				log.Println("Failed to get from database: ", err)
-				continue
-			}
+		sourceFile, err := filepath.Rel(abiDir, path)
+		if err != nil {
+			sourceFile = path
+		}
+
+		counter += len(abiEvents)
+		if _, err := storeABIEvents(db, abiEvents, sourceFile); err != nil {
+			return fmt.Errorf("failed to store ABI events of %s: %w", path, err)
 		}
 
 		return nil
@@ -210,6 +243,56 @@ func loadEventSignaturesOnDB(db *gorm.DB, abiDir string) error {
 	return nil
 }
 
+// storeABIEvents inserts new ABI events of a source file and updates the ones whose definition changed.
+// The first definition of a hash within the file wins.
+func storeABIEvents(db *gorm.DB, abiEvents []ABIEvent, sourceFile string) (int, error) {
+	var written int
+	seen := make(map[string]bool)
+
+	for _, e := range abiEvents {
+		eventSignature := BuildEventSignature(e)
+
+		// hash eventSignature
+		signatureHash := Keccak256Hash(eventSignature)
+		if seen[signatureHash] {
+			continue
+		}
+		seen[signatureHash] = true
+
+		eventJSON, err := json.Marshal(e)
+		if err != nil {
+			log.Println("Failed to Marshal: ", err)
+			continue
+		}
+
+		var record ABIEventRecord
+		err = db.Where("event_signature_hash = ? AND source_file = ?", signatureHash, sourceFile).First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			newRecord := ABIEventRecord{
+				EventSignatureHash: signatureHash,
+				EventName:          e.Name,
+				ABIEventJSON:       string(eventJSON),
+				SourceFile:         sourceFile,
+			}
+			if err := db.Create(&newRecord).Error; err != nil {
+				return written, fmt.Errorf("failed to add ABI event %s: %w", e.Name, err)
+			}
+			written++
+
+		} else if err != nil {
+			return written, fmt.Errorf("failed to query ABI event %s: %w", e.Name, err)
+
+		} else if record.ABIEventJSON != string(eventJSON) {
+			if err := db.Model(&record).Update("abi_event_json", string(eventJSON)).Error; err != nil {
+				return written, fmt.Errorf("failed to update ABI event %s: %w", e.Name, err)
+			}
+			written++
+		}
+	}
+
+	return written, nil
+}
+
 func BuildABIJSONArray(records []ABIEventRecord) ([]byte, error) {
 	var buffer bytes.Buffer
 	buffer.WriteByte('[')
@@ -225,51 +308,67 @@ func BuildABIJSONArray(records []ABIEventRecord) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
-// Enhanced loadEventSignatures function that includes original ABI information
-func loadEventSignatures(db *gorm.DB) (map[string]EventSignatureInfo, error) {
-	eventSigs := make(map[string]EventSignatureInfo)
+// loadEventSignatures builds the decoding signatures of every stored ABI event.
+// The global map keeps the oldest record of each hash, bySource keeps every source file.
+func loadEventSignatures(db *gorm.DB) (*signatureSet, error) {
+	set := newSignatureSet()
 
 	var records []ABIEventRecord
-	if err := db.Find(&records).Error; err != nil {
-		log.Fatal("failed to load ABI events:", err)
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load ABI events: %w", err)
 	}
 
-	abiData, err := BuildABIJSONArray(records)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get recoreds from database: %w", err)
+	for _, record := range records {
+		sigHash, sigInfo, err := eventSignatureFromRecord(record)
+		if err != nil {
+			log.Printf("Warning: Skipping ABI event %s from %q: %v\n", record.EventName, record.SourceFile, err)
+			continue
+		}
+
+		if _, exists := set.global[sigHash]; !exists {
+			set.global[sigHash] = sigInfo
+		}
+		if set.bySource[record.SourceFile] == nil {
+			set.bySource[record.SourceFile] = make(map[string]EventSignatureInfo)
+		}
+		set.bySource[record.SourceFile][sigHash] = sigInfo
+
+		log.Printf("Loaded event: %s with signature: %s\n", sigInfo.Name, sigHash)
+	}
+
+	return set, nil
+}
+
+// eventSignatureFromRecord parses a stored ABI event into its signature hash and decoding information
+func eventSignatureFromRecord(record ABIEventRecord) (string, EventSignatureInfo, error) {
+	var abiEvent ABIEvent
+	if err := json.Unmarshal([]byte(record.ABIEventJSON), &abiEvent); err != nil {
+		return "", EventSignatureInfo{}, fmt.Errorf("failed to parse stored ABI event: %w", err)
 	}
 
 	// Then parse with go-ethereum library for signature generation
-	parsedABI, err := abi.JSON(strings.NewReader(string(abiData)))
+	abiData, err := BuildABIJSONArray([]ABIEventRecord{record})
+	if err != nil {
+		return "", EventSignatureInfo{}, err
+	}
+	parsedABI, err := abi.JSON(bytes.NewReader(abiData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to Parsed the abi")
+		return "", EventSignatureInfo{}, fmt.Errorf("failed to parse the abi: %w", err)
 	}
 
 	for _, event := range parsedABI.Events {
-		sigHash := event.ID.Hex()
-
-		// Find the corresponding event in our parsed JSON
-		var abiEvent *ABIEvent
-		abiEvent, err := GetABIEventBySignatureHash(db, sigHash)
-		if err != nil {
-			log.Println("failed to get abi from database: %w", err)
-			continue
-		}
-
 		var inputParams []string
 		for _, input := range event.Inputs {
 			inputParams = append(inputParams, input.Type.String())
 		}
 
-		eventSigs[sigHash] = EventSignatureInfo{
-			Name:        event.Name,
-			Signature:   fmt.Sprintf("%s(%s)", event.Name, strings.Join(inputParams, ",")),
+		return event.ID.Hex(), EventSignatureInfo{
+			Name:        abiEvent.Name,
+			Signature:   fmt.Sprintf("%s(%s)", abiEvent.Name, strings.Join(inputParams, ",")),
 			Inputs:      event.Inputs,
-			OriginalABI: abiEvent, // Store the original ABI event information
-		}
-
-		log.Printf("Loaded event: %s with signature: %s\n", event.Name, sigHash)
+			OriginalABI: &abiEvent, // Store the original ABI event information
+		}, nil
 	}
 
-	return eventSigs, nil
+	return "", EventSignatureInfo{}, fmt.Errorf("no event in stored ABI")
 }
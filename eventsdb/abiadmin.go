@@ -0,0 +1,233 @@
+package eventsdb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ABIEventSummary describes a registered ABI event
+type ABIEventSummary struct {
+	ID            uint            `json:"id"`
+	SignatureHash string          `json:"signatureHash"`
+	Name          string          `json:"name"`
+	Signature     string          `json:"signature"`
+	SourceFile    string          `json:"sourceFile"`
+	ABIEvent      json.RawMessage `json:"abi,omitempty"`
+}
+
+// ABIEventUsage describes the stored events that carry a signature hash
+type ABIEventUsage struct {
+	SignatureHash string          `json:"signatureHash"`
+	Total         int64           `json:"total"`
+	Decoded       int64           `json:"decoded"`
+	Contracts     []ContractUsage `json:"contracts"`
+}
+
+// ContractUsage counts the stored events of a signature emitted by one contract
+type ContractUsage struct {
+	ContractAddress string `json:"contractAddress" gorm:"serializer:address"`
+	Count           int64  `json:"count"`
+	FirstBlock      uint64 `json:"firstBlock"`
+	LastBlock       uint64 `json:"lastBlock"`
+}
+
+// ABIConflict lists the different definitions registered for the same signature hash
+type ABIConflict struct {
+	SignatureHash string            `json:"signatureHash"`
+	Definitions   []ABIEventSummary `json:"definitions"`
+}
+
+func summarizeABIEvent(record ABIEventRecord, withABI bool) ABIEventSummary {
+	summary := ABIEventSummary{
+		ID:            record.ID,
+		SignatureHash: record.EventSignatureHash,
+		Name:          record.EventName,
+		SourceFile:    record.SourceFile,
+	}
+
+	var abiEvent ABIEvent
+	if err := json.Unmarshal([]byte(record.ABIEventJSON), &abiEvent); err == nil {
+		summary.Signature = BuildEventSignature(abiEvent)
+	}
+	if withABI {
+		summary.ABIEvent = json.RawMessage(record.ABIEventJSON)
+	}
+
+	return summary
+}
+
+// listABIEvents returns the registered ABI events, optionally filtered by name or source file
+func listABIEvents(db *gorm.DB, name, sourceFile string) ([]ABIEventSummary, error) {
+	query := db.Order("event_name, source_file")
+	if name != "" {
+		query = query.Where("event_name = ?", name)
+	}
+	if sourceFile != "" {
+		query = query.Where("source_file = ?", sourceFile)
+	}
+
+	var records []ABIEventRecord
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ABI events: %w", err)
+	}
+
+	summaries := make([]ABIEventSummary, 0, len(records))
+	for _, record := range records {
+		summaries = append(summaries, summarizeABIEvent(record, false))
+	}
+	return summaries, nil
+}
+
+// getABIEvents returns every definition registered for a signature hash, including its ABI
+func getABIEvents(db *gorm.DB, signatureHash string) ([]ABIEventSummary, error) {
+	var records []ABIEventRecord
+	if err := db.Where("event_signature_hash = ?", signatureHash).Order("id").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ABI events: %w", err)
+	}
+
+	summaries := make([]ABIEventSummary, 0, len(records))
+	for _, record := range records {
+		summaries = append(summaries, summarizeABIEvent(record, true))
+	}
+	return summaries, nil
+}
+
+// abiEventUsage counts the stored events that carry a signature hash, per contract
+func abiEventUsage(db *gorm.DB, signatureHash string, binary bool) (*ABIEventUsage, error) {
+	usage := &ABIEventUsage{SignatureHash: signatureHash, Contracts: []ContractUsage{}}
+
+	err := db.Model(&BlockchainEvent{}).
+		Select("contract_address, COUNT(*) AS count, MIN(block_number) AS first_block, MAX(block_number) AS last_block").
+		Where("event_signature = ?", hexParam(binary, signatureHash)).
+		Group("contract_address").
+		Order("count DESC").
+		Scan(&usage.Contracts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	for _, contract := range usage.Contracts {
+		usage.Total += contract.Count
+	}
+
+	err = db.Model(&BlockchainEvent{}).
+		Where("event_signature = ? AND event_name IS NOT NULL", hexParam(binary, signatureHash)).
+		Count(&usage.Decoded).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count decoded events: %w", err)
+	}
+
+	return usage, nil
+}
+
+// deleteABIEvents removes the definitions of a signature hash, only from sourceFile when it is set
+func deleteABIEvents(db *gorm.DB, signatureHash, sourceFile string) (int64, error) {
+	query := db.Where("event_signature_hash = ?", signatureHash)
+	if sourceFile != "" {
+		query = query.Where("source_file = ?", sourceFile)
+	}
+
+	result := query.Delete(&ABIEventRecord{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete ABI events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// replaceABISource replaces every event registered for sourceFile with the events of a new ABI
+func replaceABISource(db *gorm.DB, sourceFile string, abiEvents []ABIEvent) (int, error) {
+	var written int
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_file = ?", sourceFile).Delete(&ABIEventRecord{}).Error; err != nil {
+			return fmt.Errorf("failed to delete ABI events of %s: %w", sourceFile, err)
+		}
+
+		var err error
+		written, err = storeABIEvents(tx, abiEvents, sourceFile)
+		return err
+	})
+
+	return written, err
+}
+
+// findABIConflicts returns the signature hashes registered with more than one distinct definition
+func findABIConflicts(db *gorm.DB) ([]ABIConflict, error) {
+	var hashes []string
+	err := db.Model(&ABIEventRecord{}).
+		Select("event_signature_hash").
+		Group("event_signature_hash").
+		Having("COUNT(DISTINCT abi_event_json) > 1").
+		Order("event_signature_hash").
+		Pluck("event_signature_hash", &hashes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conflicting ABI events: %w", err)
+	}
+
+	conflicts := make([]ABIConflict, 0, len(hashes))
+	for _, signatureHash := range hashes {
+		definitions, err := getABIEvents(db, signatureHash)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, ABIConflict{SignatureHash: signatureHash, Definitions: definitions})
+	}
+
+	return conflicts, nil
+}
+
+// ListABIEvents returns the registered ABI events, optionally filtered by name or source file
+func (s *IndexerService) ListABIEvents(name, sourceFile string) ([]ABIEventSummary, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return listABIEvents(s.db, name, sourceFile)
+}
+
+// GetABIEvents returns every definition registered for a signature hash
+func (s *IndexerService) GetABIEvents(signatureHash string) ([]ABIEventSummary, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return getABIEvents(s.db, signatureHash)
+}
+
+// ABIEventUsage counts the stored events that use a signature hash
+func (s *IndexerService) ABIEventUsage(signatureHash string) (*ABIEventUsage, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return abiEventUsage(s.db, signatureHash, s.config.BinaryStorage)
+}
+
+// DeleteABIEvents removes the definitions of a signature hash, only from sourceFile when it is set.
+// Definitions that still exist in AbiDir are registered again on the next load.
+func (s *IndexerService) DeleteABIEvents(signatureHash, sourceFile string) (int64, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return 0, err
+	}
+	return deleteABIEvents(s.db, signatureHash, sourceFile)
+}
+
+// ReplaceABISource replaces the events registered for sourceFile with the events of abiData
+func (s *IndexerService) ReplaceABISource(sourceFile string, abiData []byte) (int, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return 0, err
+	}
+
+	abiEvents, err := parseABIData(abiData)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ABI: %w", err)
+	}
+	return replaceABISource(s.db, sourceFile, abiEvents)
+}
+
+// FindABIConflicts returns the signature hashes registered with more than one distinct definition
+func (s *IndexerService) FindABIConflicts() ([]ABIConflict, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return findABIConflicts(s.db)
+}
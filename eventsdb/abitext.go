@@ -0,0 +1,195 @@
+package eventsdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseHumanReadableABI parses human-readable declarations, one per line.
+// Lines that do not declare an event (functions, errors, comments) are ignored.
+func parseHumanReadableABI(text string) ([]ABIEvent, error) {
+	var events []ABIEvent
+
+	for i, line := range strings.Split(text, "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "event ") {
+			continue
+		}
+
+		event, err := parseHumanReadableEvent(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// parseHumanReadableEvent parses a declaration such as
+// "event Transfer(address indexed from, address indexed to, uint256 value)"
+func parseHumanReadableEvent(decl string) (ABIEvent, error) {
+	decl = strings.TrimSuffix(strings.TrimSpace(decl), ";")
+	if !strings.HasPrefix(decl, "event ") {
+		return ABIEvent{}, fmt.Errorf("not an event declaration: %q", decl)
+	}
+	decl = strings.TrimSpace(strings.TrimPrefix(decl, "event "))
+
+	open := strings.Index(decl, "(")
+	if open < 1 {
+		return ABIEvent{}, fmt.Errorf("missing parameter list in %q", decl)
+	}
+	closing, err := matchingParen(decl, open)
+	if err != nil {
+		return ABIEvent{}, err
+	}
+
+	inputs, err := parseHumanReadableParams(decl[open+1 : closing])
+	if err != nil {
+		return ABIEvent{}, fmt.Errorf("event %s: %w", decl[:open], err)
+	}
+
+	modifier := strings.TrimSpace(decl[closing+1:])
+	if modifier != "" && modifier != "anonymous" {
+		return ABIEvent{}, fmt.Errorf("unexpected %q after parameters of event %s", modifier, decl[:open])
+	}
+
+	return ABIEvent{
+		Name:      strings.TrimSpace(decl[:open]),
+		Type:      "event",
+		Anonymous: modifier == "anonymous",
+		Inputs:    inputs,
+	}, nil
+}
+
+// parseHumanReadableParams parses a comma separated parameter list, naming unnamed parameters argN
+func parseHumanReadableParams(list string) ([]ABIInput, error) {
+	if strings.TrimSpace(list) == "" {
+		return []ABIInput{}, nil
+	}
+
+	var inputs []ABIInput
+	for i, param := range splitTopLevel(list) {
+		input, err := parseHumanReadableParam(strings.TrimSpace(param))
+		if err != nil {
+			return nil, err
+		}
+		if input.Name == "" {
+			input.Name = fmt.Sprintf("arg%d", i)
+		}
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
+
+// parseHumanReadableParam parses a single parameter such as "address indexed from" or "(uint256 a, bool b)[] items"
+func parseHumanReadableParam(param string) (ABIInput, error) {
+	var input ABIInput
+	var rest string
+
+	if strings.HasPrefix(param, "tuple(") {
+		param = strings.TrimPrefix(param, "tuple")
+	}
+
+	if strings.HasPrefix(param, "(") {
+		closing, err := matchingParen(param, 0)
+		if err != nil {
+			return ABIInput{}, err
+		}
+
+		components, err := parseHumanReadableParams(param[1:closing])
+		if err != nil {
+			return ABIInput{}, err
+		}
+
+		rest = param[closing+1:]
+		suffix := rest[:len(rest)-len(strings.TrimLeft(rest, "[]0123456789"))]
+		input.Type = "tuple" + suffix
+		input.Components = components
+		rest = rest[len(suffix):]
+	} else {
+		fields := strings.Fields(param)
+		if len(fields) == 0 {
+			return ABIInput{}, fmt.Errorf("empty parameter")
+		}
+		input.Type = normalizeSolidityType(fields[0])
+		rest = strings.TrimPrefix(param, fields[0])
+	}
+
+	for _, field := range strings.Fields(rest) {
+		switch field {
+		case "indexed":
+			input.Indexed = true
+		case "memory", "calldata", "storage":
+		default:
+			if input.Name != "" {
+				return ABIInput{}, fmt.Errorf("unexpected %q in parameter %q", field, param)
+			}
+			input.Name = field
+		}
+	}
+
+	return input, nil
+}
+
+// normalizeSolidityType expands the uint, int and byte aliases to their canonical types
+func normalizeSolidityType(t string) string {
+	base, suffix := t, ""
+	if idx := strings.Index(t, "["); idx >= 0 {
+		base, suffix = t[:idx], t[idx:]
+	}
+
+	switch base {
+	case "uint":
+		base = "uint256"
+	case "int":
+		base = "int256"
+	case "byte":
+		base = "bytes1"
+	}
+
+	return base + suffix
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open
+func matchingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced parentheses in %q", s)
+}
+
+// splitTopLevel splits a list on commas that are not nested in parentheses
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, list[start:])
+}
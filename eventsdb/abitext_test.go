@@ -0,0 +1,148 @@
+package eventsdb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHumanReadableEvent(t *testing.T) {
+	pair := []ABIInput{{Name: "a", Type: "address"}, {Name: "b", Type: "uint256"}}
+	tests := []struct {
+		decl string
+		want ABIEvent
+	}{
+		{
+			"event Transfer(address indexed from, address indexed to, uint256 value)",
+			ABIEvent{Name: "Transfer", Type: "event", Inputs: []ABIInput{
+				{Name: "from", Type: "address", Indexed: true},
+				{Name: "to", Type: "address", Indexed: true},
+				{Name: "value", Type: "uint256"},
+			}},
+		},
+		// Aliases are expanded, unnamed parameters get their position as name
+		{
+			"event Aliases(uint, int indexed, byte[2] memory b);",
+			ABIEvent{Name: "Aliases", Type: "event", Inputs: []ABIInput{
+				{Name: "arg0", Type: "uint256"},
+				{Name: "arg1", Type: "int256", Indexed: true},
+				{Name: "b", Type: "bytes1[2]"},
+			}},
+		},
+		{
+			"event Arrays(uint256[] amounts, bytes32[3] indexed roots, string[][] names)",
+			ABIEvent{Name: "Arrays", Type: "event", Inputs: []ABIInput{
+				{Name: "amounts", Type: "uint256[]"},
+				{Name: "roots", Type: "bytes32[3]", Indexed: true},
+				{Name: "names", Type: "string[][]"},
+			}},
+		},
+		{
+			"event Tuples((address a, uint256 b) pair, tuple(address a, uint256 b)[] pairs, ((address a, uint256 b)[2] inner, bool c) nested)",
+			ABIEvent{Name: "Tuples", Type: "event", Inputs: []ABIInput{
+				{Name: "pair", Type: "tuple", Components: pair},
+				{Name: "pairs", Type: "tuple[]", Components: pair},
+				{Name: "nested", Type: "tuple", Components: []ABIInput{
+					{Name: "inner", Type: "tuple[2]", Components: pair},
+					{Name: "c", Type: "bool"},
+				}},
+			}},
+		},
+		{
+			"event Ping(uint256 indexed id) anonymous",
+			ABIEvent{Name: "Ping", Type: "event", Anonymous: true, Inputs: []ABIInput{{Name: "id", Type: "uint256", Indexed: true}}},
+		},
+		{"event Paused()", ABIEvent{Name: "Paused", Type: "event", Inputs: []ABIInput{}}},
+	}
+	for _, test := range tests {
+		got, err := parseHumanReadableEvent(test.decl)
+		if err != nil {
+			t.Fatalf("parsing %q failed: %v", test.decl, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%q parsed as %+v, want %+v", test.decl, got, test.want)
+		}
+	}
+}
+
+func TestParseHumanReadableEventErrors(t *testing.T) {
+	tests := []struct {
+		decl string
+		err  string
+	}{
+		{"function transfer(address to, uint256 value)", "not an event declaration"},
+		{"event Transfer", "missing parameter list"},
+		{"event (uint256 value)", "missing parameter list"},
+		{"event Transfer(address from, uint256 value", "unbalanced parentheses"},
+		{"event Transfer((address a, uint256 b pair)", "unbalanced parentheses"},
+		{"event Transfer(uint256 value) indexed", `unexpected "indexed" after parameters`},
+		{"event Transfer(address from to)", `unexpected "to" in parameter`},
+		{"event Transfer(address from,)", "empty parameter"},
+	}
+	for _, test := range tests {
+		_, err := parseHumanReadableEvent(test.decl)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("parsing %q failed with %v, want %q", test.decl, err, test.err)
+		}
+	}
+}
+
+func TestParseABIData(t *testing.T) {
+	transfer := ABIEvent{Name: "Transfer", Type: "event", Inputs: []ABIInput{
+		{Name: "from", Type: "address", Indexed: true},
+		{Name: "value", Type: "uint256"},
+	}}
+	jsonTransfer := `{"type":"event","name":"Transfer","anonymous":false,"inputs":[` +
+		`{"name":"from","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}`
+	jsonFunction := `{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"}],"outputs":[]}`
+
+	tests := []struct {
+		name string
+		data string
+		want []ABIEvent
+	}{
+		{"json array", `[` + jsonFunction + `,` + jsonTransfer + `]`, []ABIEvent{transfer}},
+		{"artifact", `{"contractName":"Token","abi":[` + jsonTransfer + `,` + jsonFunction + `],"bytecode":"0x"}`, []ABIEvent{transfer}},
+		{"artifact without abi", `{"_format":"hh-sol-dbg-1","buildInfo":"../build-info/1.json"}`, nil},
+		{"artifact with declarations", `{"abi":["function transfer(address to)","event Transfer(address indexed from, uint256 value)"]}`, []ABIEvent{transfer}},
+		{"declaration array", ` ["event Transfer(address indexed from, uint256 value)", "error Unauthorized()"]`, []ABIEvent{transfer}},
+		{
+			"text",
+			"// Token events\n" +
+				"function transfer(address to, uint256 value) returns (bool)\n" +
+				"\n" +
+				"  event Transfer(address indexed from, uint256 value); // emitted on every transfer\n" +
+				"// event Commented(uint256 value)\n" +
+				"error Unauthorized()\n",
+			[]ABIEvent{transfer},
+		},
+	}
+	for _, test := range tests {
+		got, err := parseABIData([]byte(test.data))
+		if err != nil {
+			t.Fatalf("%s: parsing failed: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s: parsed as %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseABIDataErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"malformed line", "event Transfer(address indexed from)\nfunction f()\nevent Approval(address owner spender)\n", "line 3: event Approval"},
+		{"malformed declaration", `["event Transfer(address from"]`, "unbalanced parentheses"},
+		{"malformed json", `[{"type":"event"`, "unexpected end of JSON input"},
+		{"artifact abi not an array", `{"abi":{"type":"event"}}`, "cannot unmarshal object"},
+	}
+	for _, test := range tests {
+		_, err := parseABIData([]byte(test.data))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%s: parsing failed with %v, want %q", test.name, err, test.err)
+		}
+	}
+}
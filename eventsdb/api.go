@@ -0,0 +1,278 @@
+package eventsdb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// Page sizes of the events API
+const (
+	DefaultEventPageSize = 100
+	MaxEventPageSize     = 1_000
+)
+
+// eventFilterKeys are the query parameters of GET /events, every other parameter filters on decoded_params
+var eventFilterKeys = map[string]bool{
+	"contract": true, "event": true, "signature": true, "tx": true,
+	"fromBlock": true, "toBlock": true, "fromTime": true, "toTime": true,
+	"cursor": true, "limit": true, "order": true,
+}
+
+// EventQuery selects stored events, zero values do not filter
+type EventQuery struct {
+	Contract  string
+	EventName string
+	Signature string
+	TxHash    string
+	FromBlock uint64
+	ToBlock   uint64 // Inclusive, 0 is unbounded
+	FromTime  time.Time
+	ToTime    time.Time         // Inclusive, zero is unbounded
+	Params    map[string]string // Top level decoded parameters compared as text, case insensitive
+
+	Cursor     string // NextCursor of the previous page
+	Limit      int
+	Descending bool
+}
+
+// EventPage is one page of events in block and log order
+type EventPage struct {
+	Events     []EventRecord `json:"events"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// encodeEventCursor returns the cursor of the page after the event at block and log index
+func encodeEventCursor(block uint64, logIndex uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", block, logIndex)))
+}
+
+func decodeEventCursor(cursor string) (uint64, uint64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor")
+	}
+	blockText, logText, ok := strings.Cut(string(data), ":")
+	block, blockErr := strconv.ParseUint(blockText, 10, 64)
+	logIndex, logErr := strconv.ParseUint(logText, 10, 64)
+	if !ok || blockErr != nil || logErr != nil {
+		return 0, 0, fmt.Errorf("invalid cursor")
+	}
+	return block, logIndex, nil
+}
+
+// blockRangeForTimes narrows a block range to the blocks with events between fromTime and toTime.
+// Events do not store their block time, so the range is looked up in event_counts_block; ok is false when no block matches.
+func blockRangeForTimes(db *gorm.DB, q *EventQuery) (bool, error) {
+	if !q.FromTime.IsZero() {
+		var first *uint64
+		if err := db.Model(&BlockEventCount{}).Where("block_time >= ?", q.FromTime.UTC()).Select("MIN(block_number)").Scan(&first).Error; err != nil {
+			return false, fmt.Errorf("failed to look up block times: %w", err)
+		}
+		if first == nil {
+			return false, nil
+		}
+		q.FromBlock = max(q.FromBlock, *first)
+	}
+
+	if !q.ToTime.IsZero() {
+		var last *uint64
+		if err := db.Model(&BlockEventCount{}).Where("block_time <= ?", q.ToTime.UTC()).Select("MAX(block_number)").Scan(&last).Error; err != nil {
+			return false, fmt.Errorf("failed to look up block times: %w", err)
+		}
+		if last == nil {
+			return false, nil
+		}
+		if q.ToBlock == 0 || *last < q.ToBlock {
+			q.ToBlock = *last
+		}
+	}
+	return q.ToBlock == 0 || q.FromBlock <= q.ToBlock, nil
+}
+
+// decodedParamCondition compares a top level decoded parameter as text, booleans read as true and false in both dialects
+func decodedParamCondition(storage string) string {
+	if storage == StorageSQLite {
+		return "LOWER(CASE json_type(decoded_params, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE json_extract(decoded_params, ?) END) = LOWER(?)"
+	}
+	return "LOWER(decoded_params ->> ?) = LOWER(?)"
+}
+
+// queryEvents returns a page of the events selected by q
+func queryEvents(db *gorm.DB, q EventQuery, storage string, binary, rollups bool) (*EventPage, error) {
+	page := &EventPage{Events: []EventRecord{}}
+
+	if q.Limit < 1 {
+		q.Limit = DefaultEventPageSize
+	}
+	q.Limit = min(q.Limit, MaxEventPageSize)
+
+	if !q.FromTime.IsZero() || !q.ToTime.IsZero() {
+		if !rollups {
+			return nil, fmt.Errorf("time filters need the rollup tables, set ROLLUPS=true")
+		}
+		ok, err := blockRangeForTimes(db, &q)
+		if err != nil || !ok {
+			return page, err
+		}
+	}
+
+	query := db.Where("block_number >= ?", q.FromBlock)
+	if q.ToBlock > 0 {
+		query = query.Where("block_number <= ?", q.ToBlock)
+	}
+	if q.Contract != "" {
+		// Addresses are stored checksummed
+		contract := q.Contract
+		if common.IsHexAddress(contract) {
+			contract = common.HexToAddress(contract).Hex()
+		}
+		query = query.Where("contract_address = ?", hexParam(binary, contract))
+	}
+	if q.EventName != "" {
+		query = query.Where("event_name = ?", q.EventName)
+	}
+	if q.Signature != "" {
+		query = query.Where("event_signature = ?", hexParam(binary, strings.ToLower(q.Signature)))
+	}
+	if q.TxHash != "" {
+		query = query.Where("tx_hash = ?", hexParam(binary, strings.ToLower(q.TxHash)))
+	}
+	for name, value := range q.Params {
+		if storage == StorageSQLite {
+			path := `$."` + strings.ReplaceAll(name, `"`, ``) + `"`
+			query = query.Where(decodedParamCondition(storage), path, path, value)
+		} else {
+			query = query.Where(decodedParamCondition(storage), name, value)
+		}
+	}
+
+	order := "block_number, log_index"
+	if q.Descending {
+		order = "block_number DESC, log_index DESC"
+	}
+	if q.Cursor != "" {
+		block, logIndex, err := decodeEventCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if q.Descending {
+			query = query.Where("block_number < ? OR (block_number = ? AND log_index < ?)", block, block, logIndex)
+		} else {
+			query = query.Where("block_number > ? OR (block_number = ? AND log_index > ?)", block, block, logIndex)
+		}
+	}
+
+	// One extra row tells whether there is a next page
+	var events []BlockchainEvent
+	if err := query.Order(order).Limit(q.Limit + 1).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+
+	if len(events) > q.Limit {
+		events = events[:q.Limit]
+		last := events[len(events)-1]
+		page.NextCursor = encodeEventCursor(last.BlockNumber, last.LogIndex)
+	}
+	for i := range events {
+		page.Events = append(page.Events, newEventRecord(&events[i]))
+	}
+	return page, nil
+}
+
+// QueryEvents returns a page of the stored events selected by q
+func (s *IndexerService) QueryEvents(q EventQuery) (*EventPage, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return queryEvents(s.db, q, s.config.Storage, s.config.BinaryStorage, s.config.Rollups)
+}
+
+// parseEventQuery reads the filters of GET /events
+func parseEventQuery(r *http.Request) (EventQuery, error) {
+	values := r.URL.Query()
+	q := EventQuery{
+		Contract:   values.Get("contract"),
+		EventName:  values.Get("event"),
+		Signature:  values.Get("signature"),
+		TxHash:     values.Get("tx"),
+		Cursor:     values.Get("cursor"),
+		Descending: values.Get("order") == "desc",
+		Params:     make(map[string]string),
+	}
+
+	var err error
+	parseBlock := func(name string, dst *uint64) {
+		if v := values.Get(name); v != "" && err == nil {
+			if *dst, err = strconv.ParseUint(v, 10, 64); err != nil {
+				err = fmt.Errorf("invalid %s %q", name, v)
+			}
+		}
+	}
+	parseTime := func(name string, dst *time.Time) {
+		if v := values.Get(name); v != "" && err == nil {
+			if *dst, err = parseAPITime(v); err != nil {
+				err = fmt.Errorf("invalid %s %q, use RFC 3339 or unix seconds", name, v)
+			}
+		}
+	}
+	parseBlock("fromBlock", &q.FromBlock)
+	parseBlock("toBlock", &q.ToBlock)
+	parseTime("fromTime", &q.FromTime)
+	parseTime("toTime", &q.ToTime)
+	if v := values.Get("limit"); v != "" && err == nil {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			err = fmt.Errorf("invalid limit %q", v)
+		}
+	}
+	if order := values.Get("order"); order != "" && order != "asc" && order != "desc" && err == nil {
+		err = fmt.Errorf("invalid order %q (available: asc, desc)", order)
+	}
+	if q.Cursor != "" && err == nil {
+		_, _, err = decodeEventCursor(q.Cursor)
+	}
+	if err != nil {
+		return q, err
+	}
+
+	for name, param := range values {
+		if !eventFilterKeys[name] {
+			q.Params[name] = param[0]
+		}
+	}
+	return q, nil
+}
+
+func parseAPITime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// handleEvents serves GET /events, see EventQuery for the filters
+func (s *IndexerService) handleEvents(w http.ResponseWriter, r *http.Request) {
+	q, err := parseEventQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if (!q.FromTime.IsZero() || !q.ToTime.IsZero()) && !s.config.Rollups {
+		writeJSONError(w, http.StatusBadRequest, "time filters need the rollup tables, set ROLLUPS=true")
+		return
+	}
+
+	page, err := queryEvents(s.db, q, s.config.Storage, s.config.BinaryStorage, s.config.Rollups)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,112 @@
+package eventsdb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEventCursor(t *testing.T) {
+	block, logIndex, err := decodeEventCursor(encodeEventCursor(18_000_000, 42))
+	if err != nil || block != 18_000_000 || logIndex != 42 {
+		t.Fatalf("cursor decoded as %d:%d (error %v), want 18000000:42", block, logIndex, err)
+	}
+
+	for _, cursor := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("100")), base64.RawURLEncoding.EncodeToString([]byte("a:1")), base64.RawURLEncoding.EncodeToString([]byte("1:-1"))} {
+		if _, _, err := decodeEventCursor(cursor); err == nil {
+			t.Fatalf("cursor %q decoded, want an error", cursor)
+		}
+	}
+}
+
+// queryTestEvents pages through the events of q and returns them as block:logIndex, failing the test on errors
+func queryTestEvents(t *testing.T, s *IndexerService, q EventQuery) []string {
+	t.Helper()
+	var events []string
+	for pages := 0; ; pages++ {
+		page, err := queryEvents(s.db, q, s.config.Storage, s.config.BinaryStorage, s.config.Rollups)
+		if err != nil {
+			t.Fatalf("query %+v failed: %v", q, err)
+		}
+		if len(page.Events) > q.Limit && q.Limit > 0 {
+			t.Fatalf("page of %d events, want at most %d", len(page.Events), q.Limit)
+		}
+		for _, event := range page.Events {
+			events = append(events, fmt.Sprintf("%d:%d", event.BlockNumber, event.LogIndex))
+		}
+		if page.NextCursor == "" {
+			return events
+		}
+		if pages > 100 {
+			t.Fatalf("query %+v does not end", q)
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func TestQueryEvents(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	s := openTestService(t, testSQLiteConfig(t))
+	writeTestRange(t, s.sink, 100, 104, testTransfers(t, &sig, 100, 104, 2))
+
+	var all []string
+	for block := 100; block <= 104; block++ {
+		all = append(all, fmt.Sprintf("%d:0", block), fmt.Sprintf("%d:1", block))
+	}
+	reversed := make([]string, len(all))
+	for i, event := range all {
+		reversed[len(all)-1-i] = event
+	}
+
+	tests := []struct {
+		name string
+		q    EventQuery
+		want []string
+	}{
+		{"pages", EventQuery{Limit: 3}, all},
+		{"descending pages", EventQuery{Limit: 3, Descending: true}, reversed},
+		{"exact pages", EventQuery{Limit: 5, Descending: true}, reversed},
+		{"after a cursor", EventQuery{Limit: 4, Cursor: encodeEventCursor(102, 0)}, all[5:]},
+		{"descending before a cursor", EventQuery{Limit: 4, Descending: true, Cursor: encodeEventCursor(102, 0)}, reversed[6:]},
+		{"block range", EventQuery{FromBlock: 101, ToBlock: 102, Descending: true}, []string{"102:1", "102:0", "101:1", "101:0"}},
+		// Decoded parameters compare as text, case insensitive
+		{"decoded address", EventQuery{Limit: 2, Params: map[string]string{"from": "0x0000000000000000000000000000000000000002"}}, []string{"100:1", "101:1", "102:1", "103:1", "104:1"}},
+		{"decoded number", EventQuery{Params: map[string]string{"value": "103001"}}, []string{"103:1"}},
+		{"decoded parameters", EventQuery{Params: map[string]string{"from": "0x0000000000000000000000000000000000000001", "value": "103001"}}, nil},
+		{"unknown parameter", EventQuery{Params: map[string]string{"owner": "0x01"}}, nil},
+		{"contract", EventQuery{Contract: strings.ToLower(testContract), ToBlock: 100}, []string{"100:0", "100:1"}},
+		{"event", EventQuery{EventName: "Approval"}, nil},
+	}
+	for _, test := range tests {
+		if got := queryTestEvents(t, s, test.q); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := queryEvents(s.db, EventQuery{Cursor: "not base64!"}, s.config.Storage, false, false); err == nil || err.Error() != "invalid cursor" {
+		t.Fatalf("invalid cursor failed with %v, want invalid cursor", err)
+	}
+}
+
+func TestQueryEventsTimeFilters(t *testing.T) {
+	s := openTestService(t, testSQLiteConfig(t))
+	writeTestRange(t, s.sink, 100, 104, timedTransfers(t, 100, 104, 1))
+
+	q := EventQuery{FromTime: testBlockTime(102), ToTime: testBlockTime(103)}
+	if _, err := s.QueryEvents(q); err == nil || !strings.Contains(err.Error(), "set ROLLUPS=true") {
+		t.Fatalf("time filter without rollups failed with %v, want the ROLLUPS=true error", err)
+	}
+
+	config := testSQLiteConfig(t)
+	config.Rollups = true
+	s = openTestService(t, config)
+	writeTestRange(t, s.sink, 100, 104, timedTransfers(t, 100, 104, 1))
+	if got, want := queryTestEvents(t, s, q), []string{"102:0", "103:0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("events between the times of blocks 102 and 103 are %v, want %v", got, want)
+	}
+	if got := queryTestEvents(t, s, EventQuery{FromTime: testBlockTime(105)}); got != nil {
+		t.Fatalf("events after the last block time are %v, want none", got)
+	}
+}
@@ -0,0 +1,216 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// binaryStorageDDL converts the hex columns of blockchain_events to bytea.
+// ALTER COLUMN ... USING does not allow subqueries, so topics are converted by a helper function.
+const binaryStorageDDL = `CREATE OR REPLACE FUNCTION eventsdb_decode_hex_array(topics text[]) RETURNS bytea[] AS $$
+	SELECT coalesce(array_agg(decode(substr(topic, 3), 'hex') ORDER BY position), '{}')
+	FROM unnest(topics) WITH ORDINALITY AS t(topic, position)
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE blockchain_events
+	ALTER COLUMN tx_hash TYPE bytea USING decode(substr(tx_hash, 3), 'hex'),
+	ALTER COLUMN block_hash TYPE bytea USING decode(substr(block_hash, 3), 'hex'),
+	ALTER COLUMN contract_address TYPE bytea USING decode(substr(contract_address, 3), 'hex'),
+	ALTER COLUMN event_signature TYPE bytea USING decode(substr(event_signature, 3), 'hex'),
+	ALTER COLUMN other_topics TYPE bytea[] USING eventsdb_decode_hex_array(other_topics),
+	ALTER COLUMN raw_data TYPE bytea USING decode(raw_data, 'hex');
+
+DROP FUNCTION eventsdb_decode_hex_array(text[]);`
+
+func init() {
+	// Hex columns are read back as hex whether they are stored as text or as bytea
+	schema.RegisterSerializer("hash", hexSerializer{format: hexutil.Encode})
+	schema.RegisterSerializer("address", hexSerializer{format: func(b []byte) string { return common.BytesToAddress(b).Hex() }})
+	schema.RegisterSerializer("hexdata", hexSerializer{format: hex.EncodeToString})
+}
+
+// hexSerializer scans text columns as they are and bytea columns with format
+type hexSerializer struct {
+	format func([]byte) string
+}
+
+func (s hexSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = s.format(v)
+	default:
+		return fmt.Errorf("unsupported value %T for hex column %s", dbValue, field.DBName)
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (hexSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	return fieldValue, nil
+}
+
+// binaryEvent is a blockchain_events row with binary storage, hashes, addresses, topics and data are bytea
+type binaryEvent struct {
+	ID                 uint            `gorm:"primaryKey"`
+	TxHash             []byte          `gorm:"type:bytea"`
+	TxIndex            uint            `gorm:"type:bigint"`
+	BlockNumber        uint64          `gorm:"type:bigint"`
+	BlockHash          []byte          `gorm:"type:bytea"`
+	LogIndex           uint            `gorm:"type:bigint"`
+	Removed            bool            `gorm:"type:boolean"`
+	ContractAddress    []byte          `gorm:"type:bytea"`
+	EventSignature     []byte          `gorm:"type:bytea"`
+	EventName          *string         `gorm:"type:varchar(255)"`
+	EventFullSignature *string         `gorm:"type:text"`
+	OtherTopics        [][]byte        `gorm:"type:bytea[]"`
+	RawData            []byte          `gorm:"type:bytea"`
+	DecodedParams      json.RawMessage `gorm:"type:jsonb"`
+	GlobalABIMatch     bool            `gorm:"type:boolean"`
+	InsertTime         time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (binaryEvent) TableName() string {
+	return "blockchain_events"
+}
+
+// toBinaryEvent decodes the hex fields of an event for binary storage
+func toBinaryEvent(event *BlockchainEvent) binaryEvent {
+	topics := make([][]byte, 0, len(event.OtherTopics))
+	for _, topic := range event.OtherTopics {
+		topics = append(topics, common.FromHex(topic))
+	}
+
+	return binaryEvent{
+		TxHash:             common.FromHex(event.TxHash),
+		TxIndex:            event.TxIndex,
+		BlockNumber:        event.BlockNumber,
+		BlockHash:          common.FromHex(event.BlockHash),
+		LogIndex:           event.LogIndex,
+		Removed:            event.Removed,
+		ContractAddress:    common.FromHex(event.ContractAddress),
+		EventSignature:     common.FromHex(event.EventSignature),
+		EventName:          event.EventName,
+		EventFullSignature: event.EventFullSignature,
+		OtherTopics:        topics,
+		RawData:            common.FromHex(event.RawData),
+		DecodedParams:      event.DecodedParams,
+		GlobalABIMatch:     event.GlobalABIMatch,
+		InsertTime:         event.InsertTime,
+	}
+}
+
+// toBinaryEvents decodes the hex fields of every event for binary storage
+func toBinaryEvents(events []BlockchainEvent) []binaryEvent {
+	rows := make([]binaryEvent, 0, len(events))
+	for i := range events {
+		rows = append(rows, toBinaryEvent(&events[i]))
+	}
+	return rows
+}
+
+// hexParam returns a hex query parameter in the representation of the hash and address columns
+func hexParam(binary bool, value string) interface{} {
+	if binary {
+		return common.FromHex(value)
+	}
+	return value
+}
+
+// hexParams returns hex query parameters in the representation of the hash and address columns
+func hexParams(binary bool, values []string) interface{} {
+	if !binary {
+		return values
+	}
+
+	params := make([][]byte, 0, len(values))
+	for _, value := range values {
+		params = append(params, common.FromHex(value))
+	}
+	return params
+}
+
+// eventsStoredAsBinary reports whether the hex columns of blockchain_events are bytea
+func eventsStoredAsBinary(db *gorm.DB) (bool, error) {
+	var dataType string
+	err := db.Raw("SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'blockchain_events' AND column_name = 'tx_hash'").
+		Scan(&dataType).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect blockchain_events: %w", err)
+	}
+	return dataType == "bytea", nil
+}
+
+// convertToBinaryStorage rewrites the hex columns of blockchain_events as bytea in one transaction
+func convertToBinaryStorage(db *gorm.DB) error {
+	binary, err := eventsStoredAsBinary(db)
+	if err != nil {
+		return err
+	}
+	if binary {
+		return nil
+	}
+
+	log.Println("Converting blockchain_events to binary storage, this rewrites the whole table...")
+	start := time.Now()
+	if err := db.Transaction(func(tx *gorm.DB) error { return tx.Exec(binaryStorageDDL).Error }); err != nil {
+		return fmt.Errorf("failed to convert blockchain_events to binary storage: %w", err)
+	}
+	log.Printf("Converted blockchain_events to binary storage in %v\n", time.Since(start))
+
+	return nil
+}
+
+// checkBinaryStorage verifies the column types of blockchain_events match BINARY_STORAGE.
+// Hex columns are converted when convert is set, binary columns are never converted back.
+func checkBinaryStorage(db *gorm.DB, binary, convert bool) error {
+	stored, err := eventsStoredAsBinary(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case stored == binary:
+		return nil
+	case stored:
+		return fmt.Errorf("blockchain_events uses binary storage, set BINARY_STORAGE=true")
+	case !convert:
+		return fmt.Errorf("blockchain_events uses hex storage, run eventsdb migrate binary")
+	}
+	return convertToBinaryStorage(db)
+}
+
+// MigrateToBinaryStorage converts the hex columns of blockchain_events to bytea
+func (s *IndexerService) MigrateToBinaryStorage() error {
+	if s.config.Storage != StoragePostgres {
+		return fmt.Errorf("binary storage requires the postgres storage")
+	}
+
+	db, migrations, err := s.openMigrations()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	if pending, err := pendingMigrations(db, migrations); err != nil {
+		return err
+	} else if len(pending) > 0 {
+		return fmt.Errorf("database schema has %d pending migrations, run eventsdb migrate up", len(pending))
+	}
+
+	return convertToBinaryStorage(db)
+}
@@ -0,0 +1,70 @@
+package eventsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// abiBinding restricts the decoding of a contract's logs to one ABI source, optionally within a block window
+type abiBinding struct {
+	Contract   string  `json:"contract"`
+	SourceFile string  `json:"abi"`
+	FromBlock  uint64  `json:"fromBlock,omitempty"`
+	ToBlock    *uint64 `json:"toBlock,omitempty"`
+}
+
+// covers reports whether the binding is valid at blockNumber
+func (b abiBinding) covers(blockNumber uint64) bool {
+	if blockNumber < b.FromBlock {
+		return false
+	}
+	return b.ToBlock == nil || blockNumber <= *b.ToBlock
+}
+
+// loadABIBindings reads the contract bindings file, a missing file means no bindings.
+//
+//	{"bindings": [{"contract": "0x...", "abi": "symmio.json", "fromBlock": 100, "toBlock": 200}]}
+func loadABIBindings(path string, set *signatureSet) (map[common.Address][]abiBinding, error) {
+	bindings := make(map[common.Address][]abiBinding)
+	if path == "" {
+		return bindings, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return bindings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ABI bindings %s: %w", path, err)
+	}
+
+	var file struct {
+		Bindings []abiBinding `json:"bindings"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ABI bindings %s: %w", path, err)
+	}
+
+	for i, binding := range file.Bindings {
+		if !common.IsHexAddress(binding.Contract) {
+			return nil, fmt.Errorf("binding %d: invalid contract address %q", i, binding.Contract)
+		}
+		if binding.ToBlock != nil && *binding.ToBlock < binding.FromBlock {
+			return nil, fmt.Errorf("binding %d: toBlock %d is before fromBlock %d", i, *binding.ToBlock, binding.FromBlock)
+		}
+		if _, exists := set.bySource[binding.SourceFile]; !exists {
+			log.Printf("Warning: ABI binding %d for %s refers to unknown ABI %q\n", i, binding.Contract, binding.SourceFile)
+		}
+
+		contract := common.HexToAddress(binding.Contract)
+		bindings[contract] = append(bindings[contract], binding)
+	}
+
+	log.Printf("Loaded %d ABI bindings from %s\n", len(file.Bindings), path)
+	return bindings, nil
+}
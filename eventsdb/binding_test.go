@@ -0,0 +1,79 @@
+package eventsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testTransferDefinition parses a Transfer event whose parameters carry the given names
+func testTransferDefinition(t *testing.T, from, to, value string) (string, EventSignatureInfo) {
+	t.Helper()
+	sigHash, sig, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Transfer", ABIEventJSON: `{"type":"event","name":"Transfer","inputs":[` +
+		`{"name":"` + from + `","type":"address","indexed":true},{"name":"` + to + `","type":"address","indexed":true},{"name":"` + value + `","type":"uint256","indexed":false}]}`})
+	if err != nil {
+		t.Fatalf("failed to parse Transfer: %v", err)
+	}
+	return sigHash, sig
+}
+
+func TestSignatureLookup(t *testing.T) {
+	sigHash, global := testTransferDefinition(t, "from", "to", "value")
+	_, v1 := testTransferDefinition(t, "src", "dst", "wad")
+	_, v2 := testTransferDefinition(t, "sender", "recipient", "amount")
+	approvalHash, approval, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Approval", ABIEventJSON: `{"type":"event","name":"Approval","inputs":[` +
+		`{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}`})
+	if err != nil {
+		t.Fatalf("failed to parse Approval: %v", err)
+	}
+
+	set := newSignatureSet()
+	set.global[sigHash] = global
+	set.global[approvalHash] = approval
+	set.bySource["token.json"] = map[string]EventSignatureInfo{sigHash: global, approvalHash: approval}
+	set.bySource["v1.json"] = map[string]EventSignatureInfo{sigHash: v1}
+	set.bySource["v2.json"] = map[string]EventSignatureInfo{sigHash: v2}
+
+	bound := "0x00000000000000000000000000000000000000b0"
+	path := filepath.Join(t.TempDir(), "bindings.json")
+	bindings := `{"bindings": [` +
+		`{"contract": "` + bound + `", "abi": "v1.json", "fromBlock": 100, "toBlock": 199},` +
+		`{"contract": "` + bound + `", "abi": "v2.json", "fromBlock": 200}]}`
+	if err := os.WriteFile(path, []byte(bindings), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if set.bindings, err = loadABIBindings(path, set); err != nil {
+		t.Fatalf("failed to load bindings: %v", err)
+	}
+
+	contract := common.HexToAddress(bound)
+	other := common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	tests := []struct {
+		name        string
+		contract    common.Address
+		block       uint64
+		topic       string
+		param       string // Name of the first parameter of the found definition, empty when none is found
+		globalMatch bool
+	}{
+		{"first window start", contract, 100, sigHash, "src", false},
+		{"first window end", contract, 199, sigHash, "src", false},
+		{"open window", contract, 1_000_000, sigHash, "sender", false},
+		{"before the windows", contract, 99, sigHash, "from", true},
+		{"unbound contract", other, 150, sigHash, "from", true},
+		{"signature missing from the bound ABI", contract, 150, approvalHash, "owner", true},
+		{"unknown signature", contract, 150, common.HexToHash("0x01").Hex(), "", false},
+	}
+	for _, test := range tests {
+		sig, globalMatch := set.lookup(test.contract, test.block, test.topic)
+		param := ""
+		if sig != nil {
+			param = sig.Inputs[0].Name
+		}
+		if param != test.param || globalMatch != test.globalMatch {
+			t.Fatalf("%s: found %q with globalMatch %v, want %q with %v", test.name, param, globalMatch, test.param, test.globalMatch)
+		}
+	}
+}
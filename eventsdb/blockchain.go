@@ -4,35 +4,44 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
-// connectWithRetry attempts to connect to the RPC endpoint with retries
-func connectWithRetry(rpcURL string, maxRetries int, retryDelay time.Duration) (*ethclient.Client, error) {
+// blockTimesBatchSize is the number of blocks asked for in one batched eth_getBlockByNumber call
+const blockTimesBatchSize = 100
+
+// connectWithRetry attempts to connect to the RPC endpoint with retries, it gives up when ctx is done
+func connectWithRetry(ctx context.Context, rpcURL string, maxRetries int, retryDelay time.Duration) (*ethclient.Client, error) {
 	var client *ethclient.Client
 	var err error
 
 	for i := 0; i < maxRetries; i++ {
 		log.Printf("Connection attempt %d to %s...\n", i+1, rpcURL)
 
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectionTimeout)
-		client, err = ethclient.DialContext(ctx, rpcURL)
+		dialCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		client, err = ethclient.DialContext(dialCtx, rpcURL)
 		cancel()
 
 		if err != nil {
 			log.Printf("Dial failed on attempt %d: %v\n", i+1, err)
 			if i < maxRetries-1 {
 				log.Printf("Retrying in %v...\n", retryDelay)
-				time.Sleep(retryDelay)
+				if !sleepContext(ctx, retryDelay) {
+					return nil, ctx.Err()
+				}
 			}
 			continue
 		}
 
 		log.Printf("Connection established, testing with HeaderByNumber...\n")
-		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
-		header, testErr := client.HeaderByNumber(ctx, nil)
+		testCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		header, testErr := client.HeaderByNumber(testCtx, nil)
 		cancel()
 
 		if testErr != nil {
@@ -41,7 +50,9 @@ func connectWithRetry(rpcURL string, maxRetries int, retryDelay time.Duration) (
 			err = testErr
 			if i < maxRetries-1 {
 				log.Printf("Retrying in %v...\n", retryDelay)
-				time.Sleep(retryDelay)
+				if !sleepContext(ctx, retryDelay) {
+					return nil, ctx.Err()
+				}
 			}
 			continue
 		}
@@ -52,7 +63,9 @@ func connectWithRetry(rpcURL string, maxRetries int, retryDelay time.Duration) (
 			err = fmt.Errorf("nil header returned")
 			if i < maxRetries-1 {
 				fmt.Printf("Retrying in %v...\n", retryDelay)
-				time.Sleep(retryDelay)
+				if !sleepContext(ctx, retryDelay) {
+					return nil, ctx.Err()
+				}
 			}
 			continue
 		}
@@ -64,3 +77,82 @@ func connectWithRetry(rpcURL string, maxRetries int, retryDelay time.Duration) (
 
 	return nil, fmt.Errorf("failed to connect after %d attempts: %w", maxRetries, err)
 }
+
+// fetchHeader gets the header of a block, retrying on failures until ctx is done
+func fetchHeader(ctx context.Context, client *ethclient.Client, number *big.Int, maxRetries int, retryDelay time.Duration) (*types.Header, error) {
+	var header *types.Header
+	var err error
+
+	for i := 0; i < maxRetries; i++ {
+		callCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		header, err = client.HeaderByNumber(callCtx, number)
+		cancel()
+
+		if err == nil {
+			return header, nil
+		}
+
+		if i < maxRetries-1 {
+			log.Printf("Failed to get header of block %s (attempt %d): %v. Retrying...\n", number, i+1, err)
+			if !sleepContext(ctx, retryDelay) {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("failed to get header of block %s after %d attempts: %w", number, maxRetries, err)
+}
+
+// fetchBlockTimes gets the timestamps of blocks with batched RPC calls, retrying a failed batch until ctx is done
+func fetchBlockTimes(ctx context.Context, client *ethclient.Client, blocks []uint64, maxRetries int, retryDelay time.Duration) (map[uint64]time.Time, error) {
+	times := make(map[uint64]time.Time, len(blocks))
+	for start := 0; start < len(blocks); start += blockTimesBatchSize {
+		batch := blocks[start:min(start+blockTimesBatchSize, len(blocks))]
+
+		var err error
+		for i := 0; i < maxRetries; i++ {
+			if err = fetchBlockTimesBatch(ctx, client, batch, times); err == nil {
+				break
+			}
+			if i < maxRetries-1 {
+				log.Printf("Failed to get times of %d blocks from %d (attempt %d): %v. Retrying...\n", len(batch), batch[0], i+1, err)
+				if !sleepContext(ctx, retryDelay) {
+					return nil, ctx.Err()
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get times of %d blocks from %d after %d attempts: %w", len(batch), batch[0], maxRetries, err)
+		}
+	}
+	return times, nil
+}
+
+// fetchBlockTimesBatch asks for the timestamps of blocks in one batched call and adds them to times
+func fetchBlockTimesBatch(ctx context.Context, client *ethclient.Client, blocks []uint64, times map[uint64]time.Time) error {
+	// Only the timestamp is decoded, the rest of the block is not needed
+	type blockTime struct {
+		Timestamp *hexutil.Uint64 `json:"timestamp"`
+	}
+	results := make([]*blockTime, len(blocks))
+	elems := make([]rpc.BatchElem, len(blocks))
+	for i, block := range blocks {
+		elems[i] = rpc.BatchElem{Method: "eth_getBlockByNumber", Args: []interface{}{hexutil.EncodeUint64(block), false}, Result: &results[i]}
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+	defer cancel()
+	if err := client.Client().BatchCallContext(callCtx, elems); err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		if elem.Error != nil {
+			return fmt.Errorf("block %d: %w", blocks[i], elem.Error)
+		}
+		if results[i] == nil || results[i].Timestamp == nil {
+			return fmt.Errorf("block %d not found", blocks[i])
+		}
+		times[blocks[i]] = time.Unix(int64(*results[i].Timestamp), 0).UTC()
+	}
+	return nil
+}
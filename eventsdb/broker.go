@@ -0,0 +1,345 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of a broker message
+const (
+	BrokerEvent     = "event"
+	BrokerTombstone = "tombstone" // Retracts an event removed by a reorg, its payload is the event with removed set
+)
+
+// Message keys of the broker, see BrokerKey
+const (
+	BrokerKeyContract = "contract"
+	BrokerKeyEvent    = "event"
+)
+
+// Broker publishing settings
+const (
+	DefaultBrokerTopic  = "eventsdb.events"
+	brokerPollInterval  = time.Second
+	brokerBatchSize     = 500
+	brokerTimeout       = 30 * time.Second
+	brokerRetryDelay    = time.Second // Delay of the first retry, doubled for every further failure
+	brokerMaxRetryDelay = time.Minute
+	brokerPruneInterval = time.Minute
+)
+
+// Publisher sends the messages of the broker outbox to a message broker
+type Publisher interface {
+	// Publish returns once the broker acknowledged every message, in order.
+	// A failed call is repeated with the same messages, so some may be delivered twice.
+	Publish(ctx context.Context, messages []BrokerMessage) error
+	// Close releases the connection to the broker
+	Close() error
+}
+
+// brokerEventID identifies an event across messages, tombstones carry the id of the event they retract
+func brokerEventID(event *BlockchainEvent) string {
+	return fmt.Sprintf("%s:%d", event.TxHash, event.LogIndex)
+}
+
+// brokerOutbox queues a message per event in the write transaction of the events,
+// so a committed event is never missed and a rolled back one is retracted
+type brokerOutbox struct {
+	keyBy string
+}
+
+func (o brokerOutbox) key(event *BlockchainEvent) string {
+	if o.keyBy == BrokerKeyEvent {
+		if event.EventName != nil {
+			return *event.EventName
+		}
+		return event.EventSignature
+	}
+	return event.ContractAddress
+}
+
+func (o brokerOutbox) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	messages := make([]BrokerMessage, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		payload, err := json.Marshal(newEventRecord(event))
+		if err != nil {
+			return fmt.Errorf("failed to encode broker message: %w", err)
+		}
+		messages = append(messages, BrokerMessage{
+			Kind:        BrokerEvent,
+			MessageKey:  o.key(event),
+			EventID:     brokerEventID(event),
+			BlockNumber: event.BlockNumber,
+			Payload:     string(payload),
+			CreatedAt:   now,
+		})
+	}
+
+	if err := tx.CreateInBatches(messages, rollupBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to queue broker messages: %w", err)
+	}
+	return nil
+}
+
+// AfterRollback replaces the queued events of the removed blocks with tombstones.
+// Unpublished events get one as well, the publisher may be sending them while the rollback commits;
+// consumers ignore tombstones of events they never saw.
+func (o brokerOutbox) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	var removed []BrokerMessage
+	if err := tx.Where("kind = ? AND block_number >= ?", BrokerEvent, fromBlock).Order("id").Find(&removed).Error; err != nil {
+		return fmt.Errorf("failed to query rolled back broker messages: %w", err)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("kind = ? AND block_number >= ?", BrokerEvent, fromBlock).Delete(&BrokerMessage{}).Error; err != nil {
+		return fmt.Errorf("failed to drop rolled back broker messages: %w", err)
+	}
+
+	now := time.Now().UTC()
+	tombstones := make([]BrokerMessage, 0, len(removed))
+	for _, message := range removed {
+		// Tombstones keep a value, a null one would let Kafka compaction drop every event of the key
+		var record EventRecord
+		if err := json.Unmarshal([]byte(message.Payload), &record); err != nil {
+			return fmt.Errorf("failed to decode broker message %d: %w", message.ID, err)
+		}
+		record.Removed = true
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode broker tombstone: %w", err)
+		}
+
+		tombstones = append(tombstones, BrokerMessage{
+			Kind:        BrokerTombstone,
+			MessageKey:  message.MessageKey,
+			EventID:     message.EventID,
+			BlockNumber: message.BlockNumber,
+			Payload:     string(payload),
+			CreatedAt:   now,
+		})
+	}
+	if err := tx.CreateInBatches(tombstones, rollupBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to queue broker tombstones: %w", err)
+	}
+	return nil
+}
+
+// publishBrokerMessages publishes the oldest unpublished messages and returns how many were published.
+// Events are marked published and kept for later tombstones, published tombstones are deleted.
+func publishBrokerMessages(ctx context.Context, db *gorm.DB, publisher Publisher) (int, error) {
+	var pending []BrokerMessage
+	if err := db.Where("published_at IS NULL").Order("id").Limit(brokerBatchSize).Find(&pending).Error; err != nil {
+		return 0, fmt.Errorf("failed to query broker messages: %w", err)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, brokerTimeout)
+	defer cancel()
+	if err := publisher.Publish(ctx, pending); err != nil {
+		return 0, fmt.Errorf("failed to publish %d broker messages: %w", len(pending), err)
+	}
+
+	var events, tombstones []uint
+	for _, message := range pending {
+		if message.Kind == BrokerTombstone {
+			tombstones = append(tombstones, message.ID)
+		} else {
+			events = append(events, message.ID)
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(events) > 0 {
+			if err := tx.Model(&BrokerMessage{}).Where("id IN ?", events).Update("published_at", time.Now().UTC()).Error; err != nil {
+				return err
+			}
+		}
+		if len(tombstones) > 0 {
+			if err := tx.Where("id IN ?", tombstones).Delete(&BrokerMessage{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark broker messages published: %w", err)
+	}
+	return len(pending), nil
+}
+
+// pruneBrokerMessages deletes the published events of blocks a reorg can no longer remove
+func pruneBrokerMessages(db *gorm.DB, reorgDepth int64) error {
+	cursor, ok, err := readCursor(db)
+	if err != nil || !ok || int64(cursor.Count) <= reorgDepth {
+		return err
+	}
+
+	final := int64(cursor.Count) - reorgDepth
+	if err := db.Where("published_at IS NOT NULL AND block_number < ?", final).Delete(&BrokerMessage{}).Error; err != nil {
+		return fmt.Errorf("failed to prune broker messages: %w", err)
+	}
+	return nil
+}
+
+// runBroker publishes the broker outbox until ctx is done, then tries once more.
+// A failing broker is retried with backoff.
+func (s *IndexerService) runBroker(ctx context.Context) {
+	ticker := time.NewTicker(brokerPollInterval)
+	defer ticker.Stop()
+
+	var failures int
+	var retryAt, pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			// Publish what the last writes queued, the rest waits in the outbox for the next start
+			flushCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+			if err := s.drainBroker(flushCtx); err != nil {
+				log.Printf("Warning: %v\n", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+		if time.Now().Before(retryAt) {
+			continue
+		}
+
+		if err := s.drainBroker(ctx); err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			failures++
+			delay := min(brokerRetryDelay<<min(failures-1, 10), brokerMaxRetryDelay)
+			retryAt = time.Now().Add(delay)
+			log.Printf("Warning: %v, retrying in %v\n", err, delay)
+			continue
+		}
+		failures = 0
+
+		if time.Since(pruned) > brokerPruneInterval {
+			pruned = time.Now()
+			if err := pruneBrokerMessages(s.db, s.config.ReorgDepth); err != nil {
+				log.Printf("Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+// drainBroker publishes batches until the outbox is empty
+func (s *IndexerService) drainBroker(ctx context.Context) error {
+	for {
+		published, err := publishBrokerMessages(ctx, s.db, s.publisher)
+		if err != nil || published < brokerBatchSize {
+			return err
+		}
+	}
+}
+
+// SetPublisher publishes the broker outbox to publisher instead of BROKER_URL, call it before Start
+func (s *IndexerService) SetPublisher(publisher Publisher) {
+	s.publisher = publisher
+}
+
+// BrokerStatus is the state of the broker outbox
+type BrokerStatus struct {
+	Pending       int64      `json:"pending"`    // Events and tombstones waiting to be published
+	Tombstones    int64      `json:"tombstones"` // Pending tombstones
+	Retained      int64      `json:"retained"`   // Published events kept until their block is final
+	LagBlocks     uint64     `json:"lagBlocks"`  // Indexed blocks since the oldest pending message
+	OldestPending *time.Time `json:"oldestPending,omitempty"`
+}
+
+// BrokerStatus returns how far publishing lags behind indexing
+func (s *IndexerService) BrokerStatus() (*BrokerStatus, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	cursor, _, err := readCursor(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BrokerStatus{}
+	if err := s.db.Model(&BrokerMessage{}).Where("published_at IS NULL").Count(&status.Pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count broker messages: %w", err)
+	}
+	if err := s.db.Model(&BrokerMessage{}).Where("published_at IS NULL AND kind = ?", BrokerTombstone).Count(&status.Tombstones).Error; err != nil {
+		return nil, fmt.Errorf("failed to count broker messages: %w", err)
+	}
+	if err := s.db.Model(&BrokerMessage{}).Where("published_at IS NOT NULL").Count(&status.Retained).Error; err != nil {
+		return nil, fmt.Errorf("failed to count broker messages: %w", err)
+	}
+
+	var oldest []BrokerMessage
+	if err := s.db.Where("published_at IS NULL").Order("id").Limit(1).Find(&oldest).Error; err != nil {
+		return nil, fmt.Errorf("failed to query broker messages: %w", err)
+	}
+	if len(oldest) > 0 {
+		status.OldestPending = &oldest[0].CreatedAt
+		if uint64(cursor.Count) > oldest[0].BlockNumber {
+			status.LagBlocks = uint64(cursor.Count) - oldest[0].BlockNumber
+		}
+	}
+	return status, nil
+}
+
+// errBrokerFailure is returned by MemoryBroker while failures are injected
+var errBrokerFailure = errors.New("injected broker failure")
+
+// MemoryBroker is a Publisher keeping the published messages in memory, a stand-in for a broker in tests
+type MemoryBroker struct {
+	mu       sync.Mutex
+	messages []BrokerMessage
+	failures int
+}
+
+// NewMemoryBroker creates an empty in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, messages []BrokerMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures > 0 {
+		b.failures--
+		return errBrokerFailure
+	}
+	b.messages = append(b.messages, messages...)
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+// Messages returns the published messages in publishing order
+func (b *MemoryBroker) Messages() []BrokerMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BrokerMessage(nil), b.messages...)
+}
+
+// FailNext makes the next n calls of Publish fail, to exercise redelivery
+func (b *MemoryBroker) FailNext(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = n
+}
@@ -0,0 +1,115 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// openTestBroker connects a service publishing to a memory broker
+func openTestBroker(t *testing.T, config Config) (*IndexerService, *MemoryBroker) {
+	t.Helper()
+	broker := NewMemoryBroker()
+	s := NewIndexerService(config)
+	s.SetPublisher(broker)
+	if err := s.ensureDatabase(); err != nil {
+		t.Fatalf("failed to open %s database: %v", config.Storage, err)
+	}
+	t.Cleanup(func() { s.sink.Close() })
+	return s, broker
+}
+
+// assertBrokerStatus fails the test unless the outbox holds pending messages, of them tombstones, and retained events
+func assertBrokerStatus(t *testing.T, s *IndexerService, pending, tombstones, retained int64) {
+	t.Helper()
+	status, err := s.BrokerStatus()
+	if err != nil {
+		t.Fatalf("failed to read broker status: %v", err)
+	}
+	if status.Pending != pending || status.Tombstones != tombstones || status.Retained != retained {
+		t.Fatalf("broker status %+v, want %d pending, %d tombstones and %d retained", status, pending, tombstones, retained)
+	}
+}
+
+// brokerRecord decodes the payload of a published message
+func brokerRecord(t *testing.T, message *BrokerMessage) EventRecord {
+	t.Helper()
+	var record EventRecord
+	if err := json.Unmarshal([]byte(message.Payload), &record); err != nil {
+		t.Fatalf("message %s has payload %q: %v", message.EventID, message.Payload, err)
+	}
+	return record
+}
+
+func TestBrokerOutbox(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	s, broker := openTestBroker(t, testSQLiteConfig(t))
+	ctx := context.Background()
+
+	writeTestRange(t, s.sink, 100, 109, testTransfers(t, &sig, 100, 109, 2))
+	assertBrokerStatus(t, s, 20, 0, 0)
+
+	// A failed publish leaves the messages queued, the next one delivers them
+	broker.FailNext(1)
+	if err := s.drainBroker(ctx); err == nil {
+		t.Fatal("publishing succeeded although the broker failed")
+	}
+	if published := broker.Messages(); len(published) != 0 {
+		t.Fatalf("%d messages published by a failed call", len(published))
+	}
+	assertBrokerStatus(t, s, 20, 0, 0)
+	if err := s.drainBroker(ctx); err != nil {
+		t.Fatalf("publishing failed: %v", err)
+	}
+	assertBrokerStatus(t, s, 0, 0, 20)
+
+	events := storedEvents(t, s.db)
+	published := broker.Messages()
+	if len(published) != len(events) {
+		t.Fatalf("%d messages published, want %d", len(published), len(events))
+	}
+	for i := range events {
+		message := &published[i]
+		record := brokerRecord(t, message)
+		if message.Kind != BrokerEvent || message.MessageKey != testContract || message.EventID != brokerEventID(&events[i]) ||
+			record.TxHash != events[i].TxHash || record.Removed {
+			t.Fatalf("message %d is %+v, want event %s:%d keyed by its contract", i, message, events[i].TxHash, events[i].LogIndex)
+		}
+	}
+
+	// A rollback retracts published and unpublished events with tombstones that keep key and value
+	writeTestRange(t, s.sink, 110, 114, testTransfers(t, &sig, 110, 114, 1))
+	events = storedEvents(t, s.db)
+	if err := s.sink.Rollback(108); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	assertBrokerStatus(t, s, 9, 9, 16)
+
+	broker.FailNext(1)
+	if err := s.drainBroker(ctx); err == nil {
+		t.Fatal("publishing succeeded although the broker failed")
+	}
+	if err := s.drainBroker(ctx); err != nil {
+		t.Fatalf("publishing failed: %v", err)
+	}
+	tombstones := broker.Messages()[len(published):]
+	if len(tombstones) != 9 {
+		t.Fatalf("%d tombstones published, want 9", len(tombstones))
+	}
+	for i, removed := range events[16:] {
+		message := &tombstones[i]
+		record := brokerRecord(t, message)
+		if message.Kind != BrokerTombstone || message.MessageKey != testContract || message.EventID != brokerEventID(&removed) ||
+			record.TxHash != removed.TxHash || record.BlockNumber != removed.BlockNumber || !record.Removed {
+			t.Fatalf("tombstone %d is %+v, want a removed copy of event %s:%d", i, message, removed.TxHash, removed.LogIndex)
+		}
+	}
+	// Published tombstones are deleted, the events they retract are gone from the outbox
+	assertBrokerStatus(t, s, 0, 0, 16)
+
+	// Published events are pruned once a reorg can no longer remove them
+	if err := pruneBrokerMessages(s.db, 5); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	assertBrokerStatus(t, s, 0, 0, 12)
+}
@@ -3,6 +3,7 @@ package eventsdb
 import (
 	"math/big"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -15,6 +16,12 @@ const (
 	DefaultRetryDelay        = 5 * time.Second
 	DefaultMaxBlockRange     = 10_000
 	DefaultFinalityBlock     = 10
+	DefaultRedecodeBatchSize = 1_000
+	DefaultABIReloadInterval = 10 * time.Second
+	DefaultReorgDepth        = 64
+	DefaultWriteBatchSize    = 500
+	DefaultRetentionInterval = time.Hour
+	DefaultShutdownTimeout   = 10 * time.Second // How long requests in flight and the final flush may take on shutdown
 )
 
 // Configuration for the application
@@ -24,6 +31,15 @@ type Config struct {
 	AbiDir         string
 	StartBlock     int64
 	FinalityBlock  int64
+	ReorgDepth     int64
+	Storage        string
+	SQLitePath     string
+	WriteMode      string
+	WriteBatchSize int
+	PartitionSize  uint64 // Blocks per blockchain_events partition, 0 keeps a plain table, PostgreSQL only
+	BinaryStorage  bool   // Store hashes, addresses, topics and data as bytea instead of hex text, PostgreSQL only
+	Projections    bool   // Keep a typed table per event signature next to blockchain_events
+	Rollups        bool   // Keep per block, hour and day event counts, block times are fetched for logs without one
 	PgHost         string
 	PgPort         string
 	PgUser         string
@@ -33,6 +49,35 @@ type Config struct {
 	MaxBlockRange  int64
 	RetryDelay     time.Duration
 	EnableGormLogs bool
+	AutoMigrate    bool   // Apply pending schema migrations on start instead of refusing to run
+	NotifyChannel  string // PostgreSQL channel every committed write and rollback is sent to with NOTIFY, empty disables it
+
+	// ABI bindings and hot reload
+	ABIBindingsFile   string        // JSON file binding ABI sources to contracts, missing file means global matching only
+	ABIReloadInterval time.Duration // How often AbiDir is checked for changes, 0 disables watching
+	RedecodeOnReload  bool          // Re-decode stored events of new or changed signatures
+
+	// Retention
+	RetentionFile     string        // JSON file with retention rules, missing file means events are kept forever
+	ArchiveDir        string        // Directory archive files are written to
+	RetentionInterval time.Duration // How often the rules are applied while indexing, 0 only applies them on eventsdb retention run
+
+	// HTTP server
+	HTTPAddr   string // Listen address of the HTTP server, empty disables it
+	AdminToken string // Bearer token required by /admin endpoints
+	RPCProxy   bool   // Send eth_getLogs ranges the database does not cover to RPC_URL
+	ChainID    int64  // Returned by eth_chainId, 0 asks RPC_URL
+
+	// Webhooks
+	WebhookMaxAttempts int // Attempts of a webhook delivery before it is dead-lettered
+
+	// gRPC server
+	GRPCAddr string // Listen address of the gRPC server, empty disables it
+
+	// Message broker
+	BrokerURL   string // kafka://host:9092[,host:9092], nats://host:4222 or redis://host:6379/0, empty disables publishing
+	BrokerTopic string // Kafka topic, NATS subject prefix or Redis stream the events are published to
+	BrokerKey   string // Message key of an event, contract or event
 }
 
 func LoadConfig() Config {
@@ -42,6 +87,11 @@ func LoadConfig() Config {
 		AbiDir:         "./abi",
 		StartBlock:     8443806, // first block
 		FinalityBlock:  DefaultFinalityBlock,
+		ReorgDepth:     DefaultReorgDepth,
+		Storage:        StoragePostgres,
+		SQLitePath:     "./eventsdb.sqlite",
+		WriteMode:      WriteModeBatch,
+		WriteBatchSize: DefaultWriteBatchSize,
 		PgHost:         "127.0.0.1",
 		PgPort:         "15432",
 		PgUser:         "postgres",
@@ -51,6 +101,18 @@ func LoadConfig() Config {
 		RetryDelay:     DefaultRetryDelay,
 		MaxBlockRange:  DefaultMaxBlockRange,
 		EnableGormLogs: false,
+		AutoMigrate:    true,
+
+		ABIReloadInterval: DefaultABIReloadInterval,
+
+		RetentionFile:     "./retention.json",
+		ArchiveDir:        "./archive",
+		RetentionInterval: DefaultRetentionInterval,
+
+		WebhookMaxAttempts: DefaultWebhookMaxAttempts,
+
+		BrokerTopic: DefaultBrokerTopic,
+		BrokerKey:   BrokerKeyContract,
 	}
 
 	if rpc := os.Getenv("RPC_URL"); rpc != "" {
@@ -59,12 +121,27 @@ func LoadConfig() Config {
 	if logFlag := os.Getenv("ENABLE_GORM_LOGS"); strings.ToLower(logFlag) == "true" {
 		config.EnableGormLogs = true
 	}
+	if autoMigrate := os.Getenv("AUTO_MIGRATE"); autoMigrate != "" {
+		config.AutoMigrate = strings.ToLower(autoMigrate) == "true"
+	}
+	if maxAttempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); maxAttempts != "" {
+		if attempts, ok := big.NewInt(0).SetString(maxAttempts, 10); ok && attempts.Int64() > 0 {
+			config.WebhookMaxAttempts = int(attempts.Int64())
+		}
+	}
+	if notifyChannel := os.Getenv("NOTIFY_CHANNEL"); notifyChannel != "" {
+		config.NotifyChannel = notifyChannel
+	}
 	if contractAddr := os.Getenv("CONTRACT_ADDRESS"); contractAddr != "" {
 		config.ContractAddr = contractAddr
 	}
 	if abiDir := os.Getenv("ABI_DIR"); abiDir != "" {
 		config.AbiDir = abiDir
 	}
+	config.ABIBindingsFile = filepath.Join(config.AbiDir, "bindings.json")
+	if bindingsFile := os.Getenv("ABI_BINDINGS_FILE"); bindingsFile != "" {
+		config.ABIBindingsFile = bindingsFile
+	}
 	if blocksStr := os.Getenv("START_BLOCK"); blocksStr != "" {
 		if blocks, ok := big.NewInt(0).SetString(blocksStr, 10); ok {
 			config.StartBlock = blocks.Int64()
@@ -78,6 +155,39 @@ func LoadConfig() Config {
 			config.FinalityBlock = finality.Int64()
 		}
 	}
+	if reorgDepthStr := os.Getenv("REORG_DEPTH"); reorgDepthStr != "" {
+		if depth, ok := big.NewInt(0).SetString(reorgDepthStr, 10); ok && depth.Int64() > 0 {
+			config.ReorgDepth = depth.Int64()
+		}
+	}
+	if storage := os.Getenv("STORAGE"); storage != "" {
+		config.Storage = strings.ToLower(storage)
+	}
+	if sqlitePath := os.Getenv("SQLITE_PATH"); sqlitePath != "" {
+		config.SQLitePath = sqlitePath
+	}
+	if writeMode := os.Getenv("WRITE_MODE"); writeMode != "" {
+		config.WriteMode = strings.ToLower(writeMode)
+	}
+	if batchSizeStr := os.Getenv("WRITE_BATCH_SIZE"); batchSizeStr != "" {
+		if batchSize, ok := big.NewInt(0).SetString(batchSizeStr, 10); ok && batchSize.Int64() > 0 {
+			config.WriteBatchSize = int(batchSize.Int64())
+		}
+	}
+	if partitionSizeStr := os.Getenv("PARTITION_SIZE"); partitionSizeStr != "" {
+		if partitionSize, ok := big.NewInt(0).SetString(partitionSizeStr, 10); ok && partitionSize.Sign() >= 0 {
+			config.PartitionSize = partitionSize.Uint64()
+		}
+	}
+	if binaryStorage := os.Getenv("BINARY_STORAGE"); strings.ToLower(binaryStorage) == "true" {
+		config.BinaryStorage = true
+	}
+	if projections := os.Getenv("PROJECTIONS"); strings.ToLower(projections) == "true" {
+		config.Projections = true
+	}
+	if rollups := os.Getenv("ROLLUPS"); strings.ToLower(rollups) == "true" {
+		config.Rollups = true
+	}
 	if pgHost := os.Getenv("PG_HOST"); pgHost != "" {
 		config.PgHost = pgHost
 	}
@@ -110,6 +220,51 @@ func LoadConfig() Config {
 			config.RetryDelay = time.Duration(delay.Int64()) * time.Second
 		}
 	}
+	if reloadInterval := os.Getenv("ABI_RELOAD_INTERVAL_SECONDS"); reloadInterval != "" {
+		if interval, ok := big.NewInt(0).SetString(reloadInterval, 10); ok {
+			config.ABIReloadInterval = time.Duration(interval.Int64()) * time.Second
+		}
+	}
+	if redecode := os.Getenv("REDECODE_ON_ABI_RELOAD"); strings.ToLower(redecode) == "true" {
+		config.RedecodeOnReload = true
+	}
+	if retentionFile := os.Getenv("RETENTION_FILE"); retentionFile != "" {
+		config.RetentionFile = retentionFile
+	}
+	if archiveDir := os.Getenv("ARCHIVE_DIR"); archiveDir != "" {
+		config.ArchiveDir = archiveDir
+	}
+	if retentionInterval := os.Getenv("RETENTION_INTERVAL_MINUTES"); retentionInterval != "" {
+		if interval, ok := big.NewInt(0).SetString(retentionInterval, 10); ok {
+			config.RetentionInterval = time.Duration(interval.Int64()) * time.Minute
+		}
+	}
+	if httpAddr := os.Getenv("HTTP_ADDR"); httpAddr != "" {
+		config.HTTPAddr = httpAddr
+	}
+	if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+		config.AdminToken = adminToken
+	}
+	if rpcProxy := os.Getenv("RPC_PROXY"); strings.ToLower(rpcProxy) == "true" {
+		config.RPCProxy = true
+	}
+	if chainID := os.Getenv("CHAIN_ID"); chainID != "" {
+		if id, ok := big.NewInt(0).SetString(chainID, 10); ok {
+			config.ChainID = id.Int64()
+		}
+	}
+	if grpcAddr := os.Getenv("GRPC_ADDR"); grpcAddr != "" {
+		config.GRPCAddr = grpcAddr
+	}
+	if brokerURL := os.Getenv("BROKER_URL"); brokerURL != "" {
+		config.BrokerURL = brokerURL
+	}
+	if brokerTopic := os.Getenv("BROKER_TOPIC"); brokerTopic != "" {
+		config.BrokerTopic = brokerTopic
+	}
+	if brokerKey := os.Getenv("BROKER_KEY"); brokerKey != "" {
+		config.BrokerKey = strings.ToLower(brokerKey)
+	}
 
 	return config
 }
@@ -6,15 +6,64 @@ import (
 	"os"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func initDB(config Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.PgHost, config.PgPort, config.PgUser, config.PgPassword, config.PgDbName)
+// Storage backends
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+// initDB opens the database, brings its schema up to date and creates the sink of the storage backend
+func initDB(config Config) (*gorm.DB, Sink, error) {
+	switch config.WriteMode {
+	case WriteModeRow, WriteModeBatch:
+	case WriteModeCopy:
+		if config.Storage != StoragePostgres {
+			return nil, nil, fmt.Errorf("write mode %q requires the postgres storage", config.WriteMode)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown write mode %q", config.WriteMode)
+	}
+
+	if config.PartitionSize > 0 && config.Storage != StoragePostgres {
+		return nil, nil, fmt.Errorf("PARTITION_SIZE requires the postgres storage")
+	}
+	if config.BinaryStorage && config.Storage != StoragePostgres {
+		return nil, nil, fmt.Errorf("BINARY_STORAGE requires the postgres storage")
+	}
+
+	db, err := openDB(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := migrateSchema(db, config); err != nil {
+		closeDB(db)
+		return nil, nil, err
+	}
+
+	if config.Storage == StorageSQLite {
+		return db, NewSQLiteSink(db, config), nil
+	}
+
+	if err := checkEventsPartitioning(db, config.PartitionSize > 0); err != nil {
+		closeDB(db)
+		return nil, nil, err
+	}
+	if err := checkBinaryStorage(db, config.BinaryStorage, config.AutoMigrate); err != nil {
+		closeDB(db)
+		return nil, nil, err
+	}
+	return db, NewPostgresSink(db, config), nil
+}
 
+// openDB connects to the configured storage backend without touching the schema
+func openDB(config Config) (*gorm.DB, error) {
 	logLevel := logger.Silent
 	if config.EnableGormLogs {
 		logLevel = logger.Info
@@ -30,18 +79,56 @@ func initDB(config Config) (*gorm.DB, error) {
 		},
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	var dialector gorm.Dialector
+	switch config.Storage {
+	case StoragePostgres:
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+			config.PgHost, config.PgPort, config.PgUser, config.PgPassword, config.PgDbName)
+		dialector = postgres.Open(dsn)
+	case StorageSQLite:
+		// WAL lets readers work while the indexer writes, the busy timeout waits for the single writer
+		dialector = sqlite.Open(config.SQLitePath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Storage)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// AutoMigrate
-	err = db.AutoMigrate(&BlockchainEvent{}, &ABIEventRecord{}, &Cursor{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	if config.Storage == StorageSQLite {
+		// SQLite allows a single writer, sharing one connection avoids lock errors
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database handle: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 
 	return db, nil
 }
+
+// migrateSchema applies pending migrations when AutoMigrate is set and refuses a schema this binary does not match
+func migrateSchema(db *gorm.DB, config Config) error {
+	migrations, err := loadMigrations(config.Storage, migrationSettings{Partitioned: config.PartitionSize > 0})
+	if err != nil {
+		return err
+	}
+
+	pending, err := pendingMigrations(db, migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if !config.AutoMigrate {
+		return fmt.Errorf("database schema has %d pending migrations, run eventsdb migrate up", len(pending))
+	}
+	_, err = migrateUp(db, migrations)
+	return err
+}
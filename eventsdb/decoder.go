@@ -7,32 +7,23 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // Simplified decoding with component information
 func decodeParameterWithComponents(value interface{}, input ABIInput, abiInput abi.Argument) interface{} {
 	rv := reflect.ValueOf(value)
 
-	// Handle single [4]uint8 → bytes4
-	if rv.Kind() == reflect.Array && rv.Len() == 4 && rv.Type().Elem().Kind() == reflect.Uint8 {
-		var b4 [4]byte
-		for i := 0; i < 4; i++ {
-			b4[i] = byte(rv.Index(i).Uint())
-		}
-		return fmt.Sprintf("0x%x", b4)
+	// Handle fixed-size byte arrays of any length → bytesN → hex
+	if rv.IsValid() && isFixedBytes(rv.Type()) {
+		return fixedBytesHex(rv)
 	}
 
-	// Handle slices of [4]uint8 → []bytes4 → []hex
-	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Array &&
-		rv.Type().Elem().Len() == 4 && rv.Type().Elem().Elem().Kind() == reflect.Uint8 {
-		var result []string
+	// Handle slices and arrays of fixed-size byte arrays → []bytesN → []hex
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && isFixedBytes(rv.Type().Elem()) {
+		result := make([]string, 0, rv.Len())
 		for i := 0; i < rv.Len(); i++ {
-			item := rv.Index(i)
-			var b4 [4]byte
-			for j := 0; j < 4; j++ {
-				b4[j] = byte(item.Index(j).Uint())
-			}
-			result = append(result, fmt.Sprintf("0x%x", b4))
+			result = append(result, fixedBytesHex(rv.Index(i)))
 		}
 		return result
 	}
@@ -72,11 +63,99 @@ func decodeParameterWithComponents(value interface{}, input ABIInput, abiInput a
 		return val.Hex()
 	case []byte:
 		return fmt.Sprintf("0x%x", val)
-	case [4]byte:
-		return fmt.Sprintf("0x%x", val[:])
-	case [32]byte:
-		return fmt.Sprintf("0x%x", val[:])
 	default:
 		return val
 	}
 }
+
+// isFixedBytes reports whether t is a bytes1 to bytes32 value as the ABI decoder returns it
+func isFixedBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8
+}
+
+// fixedBytesHex encodes a fixed-size byte array as 0x prefixed hex
+func fixedBytesHex(rv reflect.Value) string {
+	b := make([]byte, rv.Len())
+	for i := range b {
+		b[i] = byte(rv.Index(i).Uint())
+	}
+	return fmt.Sprintf("0x%x", b)
+}
+
+// decodeLogParams decodes the indexed topics and unindexed data of a log into named parameters
+func decodeLogParams(log types.Log, eventSig *EventSignatureInfo) map[string]interface{} {
+	decodedParams := make(map[string]interface{})
+	if eventSig != nil && eventSig.OriginalABI != nil {
+		var indexedInputs []abi.Argument
+		var nonIndexedInputs []abi.Argument
+		var originalIndexedInputs []ABIInput
+		var originalNonIndexedInputs []ABIInput
+
+		// Separate indexed and non-indexed inputs
+		for i, input := range eventSig.Inputs {
+			if input.Indexed {
+				indexedInputs = append(indexedInputs, input)
+				if i < len(eventSig.OriginalABI.Inputs) {
+					originalIndexedInputs = append(originalIndexedInputs, eventSig.OriginalABI.Inputs[i])
+				}
+			} else {
+				nonIndexedInputs = append(nonIndexedInputs, input)
+				if i < len(eventSig.OriginalABI.Inputs) {
+					originalNonIndexedInputs = append(originalNonIndexedInputs, eventSig.OriginalABI.Inputs[i])
+				}
+			}
+		}
+
+		// Process indexed parameters (topics)
+		for i, input := range indexedInputs {
+			topicIndex := i + 1
+			if topicIndex < len(log.Topics) {
+				topic := log.Topics[topicIndex]
+
+				var decodedValue interface{}
+				switch input.Type.T {
+				case abi.AddressTy:
+					decodedValue = common.HexToAddress(topic.Hex())
+				case abi.IntTy, abi.UintTy:
+					decodedValue = big.NewInt(0).SetBytes(topic.Bytes())
+				case abi.BoolTy:
+					decodedValue = topic.Bytes()[31] == 1
+				case abi.StringTy:
+					decodedValue = string(topic.Bytes())
+				case abi.FixedBytesTy, abi.BytesTy:
+					decodedValue = fmt.Sprintf("%x", topic.Bytes())
+				default:
+					decodedValue = topic.Bytes()
+				}
+
+				// Use simplified decoding
+				if i < len(originalIndexedInputs) {
+					decodedParams[input.Name] = decodeParameterWithComponents(decodedValue, originalIndexedInputs[i], input)
+				} else {
+					decodedParams[input.Name] = decodedValue
+				}
+			}
+		}
+
+		// Process non-indexed parameters from data
+		if len(log.Data) > 0 && len(nonIndexedInputs) > 0 {
+			method := abi.NewMethod(eventSig.Name, eventSig.Name, abi.Function, "", false, false, nonIndexedInputs, nil)
+
+			v, err := method.Inputs.UnpackValues(log.Data)
+			if err == nil {
+				for i, input := range nonIndexedInputs {
+					if i < len(v) {
+						// Use simplified decoding
+						if i < len(originalNonIndexedInputs) {
+							decodedParams[input.Name] = decodeParameterWithComponents(v[i], originalNonIndexedInputs[i], input)
+						} else {
+							decodedParams[input.Name] = v[i]
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return decodedParams
+}
@@ -0,0 +1,203 @@
+package eventsdb
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultExportBatchSize is the number of events read per query while exporting
+const DefaultExportBatchSize = 5_000
+
+// ExportOptions select the events written by Export and how the files are split
+type ExportOptions struct {
+	Format    string // csv, jsonl or parquet
+	Dir       string // Directory the files are written to
+	Prefix    string // File name prefix, defaults to events
+	FromBlock uint64 // First exported block, ignored when Cursor has a stored position
+	ToBlock   uint64 // Last exported block, 0 exports up to the indexer cursor
+	Contract  string // Only export events of this contract
+	EventName string // Only export events with this name
+
+	MaxFileBytes  int64  // Start a new file once this many bytes are written, 0 disables
+	MaxFileBlocks uint64 // Start a new file every this many blocks, 0 disables
+
+	// Cursor names a resumable export, it continues after the last block the previous run exported
+	Cursor    string
+	BatchSize int
+}
+
+// ExportResult reports the files written by Export
+type ExportResult struct {
+	Files     []string
+	Events    int
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// exportEvents streams the events of opts in block and log order into rotating files.
+// The export cursor is moved after every completed file, so an interrupted run resumes at the first missing file.
+func exportEvents(db *gorm.DB, opts ExportOptions, binary bool) (*ExportResult, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = "events"
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultExportBatchSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	switch opts.Format {
+	case ExportCSV, ExportJSONL, ExportParquet:
+	default:
+		return nil, fmt.Errorf("unknown export format %q (available: csv, jsonl, parquet)", opts.Format)
+	}
+	if opts.Contract != "" {
+		if !common.IsHexAddress(opts.Contract) {
+			return nil, fmt.Errorf("invalid contract address %q", opts.Contract)
+		}
+		// Addresses are stored checksummed
+		opts.Contract = common.HexToAddress(opts.Contract).Hex()
+	}
+
+	if opts.Cursor != "" {
+		cursor, ok, err := readExportCursor(db, opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			opts.FromBlock = cursor.BlockNumber + 1
+		}
+	}
+
+	if opts.ToBlock == 0 {
+		indexed, ok, err := readCursor(db)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("nothing indexed yet")
+		}
+		opts.ToBlock = uint64(indexed.Count)
+	}
+
+	result := &ExportResult{FromBlock: opts.FromBlock, ToBlock: opts.ToBlock}
+	if opts.FromBlock > opts.ToBlock {
+		return result, nil
+	}
+
+	exporter := &rotatingExporter{
+		dir:       opts.Dir,
+		prefix:    opts.Prefix,
+		format:    opts.Format,
+		maxBytes:  opts.MaxFileBytes,
+		maxBlocks: opts.MaxFileBlocks,
+	}
+
+	// completeFile finishes the current file and records that every block before nextBlock is exported
+	completeFile := func(nextBlock uint64) error {
+		if err := exporter.closeFile(); err != nil {
+			return err
+		}
+		if opts.Cursor != "" {
+			return storeExportCursor(db, opts.Cursor, nextBlock-1)
+		}
+		return nil
+	}
+
+	lastBlock, lastLogIndex := opts.FromBlock, int64(-1)
+	for {
+		query := db.Where("block_number <= ? AND (block_number > ? OR (block_number = ? AND log_index > ?))",
+			opts.ToBlock, lastBlock, lastBlock, lastLogIndex)
+		if opts.Contract != "" {
+			query = query.Where("contract_address = ?", hexParam(binary, opts.Contract))
+		}
+		if opts.EventName != "" {
+			query = query.Where("event_name = ?", opts.EventName)
+		}
+
+		var events []BlockchainEvent
+		if err := query.Order("block_number, log_index").Limit(opts.BatchSize).Find(&events).Error; err != nil {
+			exporter.closeFile()
+			return result, fmt.Errorf("failed to load events after block %d: %w", lastBlock, err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for i := range events {
+			record := newEventRecord(&events[i])
+			rotate, err := exporter.needsRotation(record.BlockNumber)
+			if err != nil {
+				exporter.closeFile()
+				return result, err
+			}
+			if rotate {
+				if err := completeFile(record.BlockNumber); err != nil {
+					return result, err
+				}
+			}
+			if err := exporter.write(&record); err != nil {
+				exporter.closeFile()
+				return result, err
+			}
+			result.Events++
+		}
+
+		last := events[len(events)-1]
+		lastBlock, lastLogIndex = last.BlockNumber, int64(last.LogIndex)
+	}
+
+	if err := completeFile(opts.ToBlock + 1); err != nil {
+		return result, err
+	}
+	result.Files = exporter.files
+	return result, nil
+}
+
+// readExportCursor returns the last block exported under name, ok is false for a new export
+func readExportCursor(db *gorm.DB, name string) (ExportCursor, bool, error) {
+	var cursor ExportCursor
+	err := db.Where("name = ?", name).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ExportCursor{}, false, nil
+	}
+	if err != nil {
+		return ExportCursor{}, false, fmt.Errorf("failed to query export cursor %s: %w", name, err)
+	}
+	return cursor, true, nil
+}
+
+// storeExportCursor records that every block up to block is exported under name
+func storeExportCursor(db *gorm.DB, name string, block uint64) error {
+	cursor := ExportCursor{Name: name, BlockNumber: block, UpdatedAt: time.Now().UTC()}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_number", "updated_at"}),
+	}).Create(&cursor).Error
+	if err != nil {
+		return fmt.Errorf("failed to store export cursor %s: %w", name, err)
+	}
+	return nil
+}
+
+// Export writes the stored events selected by opts to CSV, JSON Lines or Parquet files
+func (s *IndexerService) Export(opts ExportOptions) (*ExportResult, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	result, err := exportEvents(s.db, opts, s.config.BinaryStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Exported %d events of blocks %d to %d into %d files\n", result.Events, result.FromBlock, result.ToBlock, len(result.Files))
+	return result, nil
+}
@@ -0,0 +1,204 @@
+package eventsdb
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// readExportFile reads the records of an export file back
+func readExportFile(t *testing.T, format, path string) []EventRecord {
+	t.Helper()
+	var records []EventRecord
+
+	switch format {
+	case ExportJSONL:
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var record EventRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("invalid line in %s: %v", path, err)
+			}
+			records = append(records, record)
+		}
+
+	case ExportParquet:
+		var err error
+		if records, err = parquet.ReadFile[EventRecord](path); err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+	}
+	return records
+}
+
+// exportedRecords are the records Export is expected to write for the stored events
+func exportedRecords(t *testing.T, events []BlockchainEvent) []EventRecord {
+	t.Helper()
+	records := make([]EventRecord, 0, len(events))
+	for i := range events {
+		records = append(records, newEventRecord(&events[i]))
+	}
+	return records
+}
+
+// assertSameRecords compares records ignoring the sub-second part of insert times, which Parquet stores in milliseconds
+func assertSameRecords(t *testing.T, got, want []EventRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d records back, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.InsertTime.Unix() != w.InsertTime.Unix() {
+			t.Fatalf("record %d has insert time %v, want %v", i, g.InsertTime, w.InsertTime)
+		}
+		g.InsertTime = w.InsertTime
+
+		var gotParams, wantParams interface{}
+		json.Unmarshal(g.DecodedParams, &gotParams)
+		json.Unmarshal(w.DecodedParams, &wantParams)
+		if !reflect.DeepEqual(gotParams, wantParams) {
+			t.Fatalf("record %d has parameters %s, want %s", i, g.DecodedParams, w.DecodedParams)
+		}
+		g.DecodedParams, w.DecodedParams = nil, nil
+
+		if !reflect.DeepEqual(g, w) {
+			t.Fatalf("record %d read back as %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 100, 129, testTransfers(t, &sig, 100, 119, 3))
+	writeTestRange(t, sink, 130, 139, testTransfers(t, nil, 130, 139, 1))
+	want := exportedRecords(t, storedEvents(t, db))
+
+	for _, format := range []string{ExportJSONL, ExportParquet} {
+		t.Run(format, func(t *testing.T) {
+			result, err := exportEvents(db, ExportOptions{Format: format, Dir: t.TempDir(), MaxFileBlocks: 10}, false)
+			if err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			if result.Events != len(want) || len(result.Files) != 3 || result.ToBlock != 139 {
+				t.Fatalf("exported %d events into %d files up to block %d, want %d events into 3 files up to 139",
+					result.Events, len(result.Files), result.ToBlock, len(want))
+			}
+
+			var got []EventRecord
+			for _, file := range result.Files {
+				got = append(got, readExportFile(t, format, file)...)
+			}
+			assertSameRecords(t, got, want)
+		})
+	}
+
+	t.Run(ExportCSV, func(t *testing.T) {
+		result, err := exportEvents(db, ExportOptions{Format: ExportCSV, Dir: t.TempDir()}, false)
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		file, err := os.Open(result.Files[0])
+		if err != nil {
+			t.Fatalf("failed to open export: %v", err)
+		}
+		defer file.Close()
+		rows, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV: %v", err)
+		}
+		if len(rows) != len(want)+1 || !reflect.DeepEqual(rows[0], exportCSVHeader) {
+			t.Fatalf("CSV has %d rows and header %v, want %d rows", len(rows), rows[0], len(want)+1)
+		}
+		for i := range want {
+			if !reflect.DeepEqual(rows[i+1], want[i].csvRow()) {
+				t.Fatalf("CSV row %d is %v, want %v", i, rows[i+1], want[i].csvRow())
+			}
+		}
+	})
+}
+
+func TestExportFilters(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 100, 129, testTransfers(t, &sig, 100, 119, 2))
+	writeTestRange(t, sink, 130, 139, testTransfers(t, nil, 130, 139, 1))
+
+	// A lowercase address matches the checksummed stored one
+	result, err := exportEvents(db, ExportOptions{Format: ExportJSONL, Dir: t.TempDir(), Contract: strings.ToLower(testContract), EventName: "Transfer"}, false)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if result.Events != 40 {
+		t.Fatalf("exported %d Transfer events of the contract, want 40", result.Events)
+	}
+
+	if _, err := exportEvents(db, ExportOptions{Format: ExportJSONL, Dir: t.TempDir(), Contract: "0x1234"}, false); err == nil {
+		t.Fatal("export accepted an invalid contract address")
+	}
+
+	// A named export continues after the block its last run ended at
+	dir := t.TempDir()
+	first, err := exportEvents(db, ExportOptions{Format: ExportJSONL, Dir: dir, Cursor: "daily", ToBlock: 119}, false)
+	if err != nil || first.Events != 40 {
+		t.Fatalf("first run exported %d events (error %v), want 40", first.Events, err)
+	}
+	second, err := exportEvents(db, ExportOptions{Format: ExportJSONL, Dir: dir, Cursor: "daily"}, false)
+	if err != nil || second.Events != 10 || second.FromBlock != 120 {
+		t.Fatalf("second run exported %d events from block %d (error %v), want 10 from block 120", second.Events, second.FromBlock, err)
+	}
+}
+
+func TestExportRotateBytes(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 0, 999, testTransfers(t, &sig, 0, 999, 3))
+	want := exportedRecords(t, storedEvents(t, db))
+
+	// Parquet buffers whole row groups, its files pass the limit by up to a row group
+	for format, maxBytes := range map[string]int64{ExportCSV: 4_000, ExportJSONL: 4_000, ExportParquet: 8_000} {
+		t.Run(format, func(t *testing.T) {
+			result, err := exportEvents(db, ExportOptions{Format: format, Dir: t.TempDir(), MaxFileBytes: maxBytes}, false)
+			if err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			if len(result.Files) < 2 {
+				t.Fatalf("exported %d events into %d files, want several files of about %d bytes", result.Events, len(result.Files), maxBytes)
+			}
+
+			var got []EventRecord
+			for i, file := range result.Files {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatalf("failed to stat %s: %v", file, err)
+				}
+				limit := maxBytes + 2_000 // One more block of three events
+				if format == ExportParquet {
+					limit = maxBytes + 15_000 // One more row group of these events and the footer
+				}
+				if i < len(result.Files)-1 && (info.Size() < maxBytes || info.Size() > limit) {
+					t.Fatalf("%s has %d bytes, want %d to %d", file, info.Size(), maxBytes, limit)
+				}
+				if format != ExportCSV {
+					got = append(got, readExportFile(t, format, file)...)
+				}
+			}
+			if format != ExportCSV {
+				assertSameRecords(t, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package eventsdb
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export file formats
+const (
+	ExportCSV     = "csv"
+	ExportJSONL   = "jsonl"
+	ExportParquet = "parquet"
+)
+
+// exportCSVHeader names the columns of CSV exports in the order of EventRecord.csvRow
+var exportCSVHeader = []string{"block_number", "block_hash", "tx_hash", "tx_index", "log_index", "removed", "contract_address", "event_signature", "event_name", "event_full_signature", "other_topics", "raw_data", "decoded_params", "global_abi_match", "insert_time"}
+
+// EventRecord is a stored event as exported and served by the API, decoded_params stays a JSON document in every format
+type EventRecord struct {
+	BlockNumber        uint64          `json:"blockNumber" parquet:"block_number"`
+	BlockHash          string          `json:"blockHash" parquet:"block_hash"`
+	TxHash             string          `json:"txHash" parquet:"tx_hash"`
+	TxIndex            uint64          `json:"txIndex" parquet:"tx_index"`
+	LogIndex           uint64          `json:"logIndex" parquet:"log_index"`
+	Removed            bool            `json:"removed" parquet:"removed"`
+	ContractAddress    string          `json:"contractAddress" parquet:"contract_address,dict"`
+	EventSignature     string          `json:"eventSignature" parquet:"event_signature,dict"`
+	EventName          *string         `json:"eventName" parquet:"event_name,optional,dict"`
+	EventFullSignature *string         `json:"eventFullSignature" parquet:"event_full_signature,optional,dict"`
+	OtherTopics        []string        `json:"otherTopics" parquet:"other_topics,list"`
+	RawData            string          `json:"rawData" parquet:"raw_data"`
+	DecodedParams      json.RawMessage `json:"decodedParams" parquet:"decoded_params,json"`
+	GlobalABIMatch     bool            `json:"globalAbiMatch" parquet:"global_abi_match"`
+	InsertTime         time.Time       `json:"insertTime" parquet:"insert_time,timestamp(millisecond)"`
+}
+
+func newEventRecord(event *BlockchainEvent) EventRecord {
+	decodedParams := event.DecodedParams
+	if len(decodedParams) == 0 {
+		decodedParams = json.RawMessage("{}")
+	}
+	otherTopics := []string(event.OtherTopics)
+	if otherTopics == nil {
+		otherTopics = []string{}
+	}
+
+	return EventRecord{
+		BlockNumber:        event.BlockNumber,
+		BlockHash:          event.BlockHash,
+		TxHash:             event.TxHash,
+		TxIndex:            uint64(event.TxIndex),
+		LogIndex:           uint64(event.LogIndex),
+		Removed:            event.Removed,
+		ContractAddress:    event.ContractAddress,
+		EventSignature:     event.EventSignature,
+		EventName:          event.EventName,
+		EventFullSignature: event.EventFullSignature,
+		OtherTopics:        otherTopics,
+		RawData:            event.RawData,
+		DecodedParams:      decodedParams,
+		GlobalABIMatch:     event.GlobalABIMatch,
+		InsertTime:         event.InsertTime.UTC(),
+	}
+}
+
+func (r *EventRecord) csvRow() []string {
+	var eventName, eventFullSignature string
+	if r.EventName != nil {
+		eventName = *r.EventName
+	}
+	if r.EventFullSignature != nil {
+		eventFullSignature = *r.EventFullSignature
+	}
+
+	return []string{
+		strconv.FormatUint(r.BlockNumber, 10),
+		r.BlockHash,
+		r.TxHash,
+		strconv.FormatUint(r.TxIndex, 10),
+		strconv.FormatUint(r.LogIndex, 10),
+		strconv.FormatBool(r.Removed),
+		r.ContractAddress,
+		r.EventSignature,
+		eventName,
+		eventFullSignature,
+		strings.Join(r.OtherTopics, " "),
+		r.RawData,
+		string(r.DecodedParams),
+		strconv.FormatBool(r.GlobalABIMatch),
+		r.InsertTime.Format(time.RFC3339),
+	}
+}
+
+// exportParquetGroupRows is the number of rows a Parquet row group holds before it is written
+// while files are rotated by size, Parquet buffers the rows of a group until it is complete
+const exportParquetGroupRows = 1_000
+
+// recordEncoder writes records of one format to an open file
+type recordEncoder interface {
+	Encode(record *EventRecord) error
+	// Flush writes the buffered records to the file so its size is current
+	Flush() error
+	Close() error
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvEncoder) Encode(record *EventRecord) error {
+	return e.writer.Write(record.csvRow())
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return e.Flush()
+}
+
+type jsonlEncoder struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(record *EventRecord) error {
+	return e.encoder.Encode(record)
+}
+
+func (e *jsonlEncoder) Flush() error {
+	return e.buffer.Flush()
+}
+
+func (e *jsonlEncoder) Close() error {
+	return e.buffer.Flush()
+}
+
+type parquetEncoder struct {
+	writer   *parquet.GenericWriter[EventRecord]
+	buffered int // Rows of the unwritten row group
+}
+
+func (e *parquetEncoder) Encode(record *EventRecord) error {
+	_, err := e.writer.Write([]EventRecord{*record})
+	e.buffered++
+	return err
+}
+
+// Flush writes the buffered rows as a row group once there are exportParquetGroupRows of them,
+// smaller row groups would compress poorly
+func (e *parquetEncoder) Flush() error {
+	if e.buffered < exportParquetGroupRows {
+		return nil
+	}
+	e.buffered = 0
+	return e.writer.Flush()
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.writer.Close()
+}
+
+// newRecordEncoder creates the encoder of format on w
+func newRecordEncoder(format string, w io.Writer) (recordEncoder, error) {
+	switch format {
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportCSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{writer: writer}, nil
+	case ExportJSONL:
+		buffer := bufio.NewWriter(w)
+		return &jsonlEncoder{buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+	case ExportParquet:
+		// Unbuffered, so the row groups written by Flush are counted at once
+		return &parquetEncoder{writer: parquet.NewGenericWriter[EventRecord](w, parquet.Compression(&parquet.Zstd), parquet.WriteBufferSize(0))}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q (available: csv, jsonl, parquet)", format)
+	}
+}
+
+// countingWriter counts the bytes written to a file so it can be rotated by size
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.written += int64(n)
+	return n, err
+}
+
+// rotatingExporter writes records to files of at most maxBytes bytes or maxBlocks blocks.
+// Parquet files grow by whole row groups of exportParquetGroupRows, so they can pass maxBytes by one.
+// Files are only rotated between blocks and are written as .partial until they are complete,
+// then renamed to <prefix>-<first block>-<last block>.<format>.
+type rotatingExporter struct {
+	dir       string
+	prefix    string
+	format    string
+	maxBytes  int64
+	maxBlocks uint64
+
+	file       *os.File
+	counter    *countingWriter
+	encoder    recordEncoder
+	firstBlock uint64
+	lastBlock  uint64
+	files      []string
+}
+
+// needsRotation reports whether the record of block starts a new file.
+// The encoder is flushed first so the size of the file includes the buffered records.
+func (e *rotatingExporter) needsRotation(block uint64) (bool, error) {
+	if e.file == nil || block == e.lastBlock {
+		return false, nil
+	}
+	if e.maxBytes > 0 {
+		if err := e.encoder.Flush(); err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", e.file.Name(), err)
+		}
+		if e.counter.written >= e.maxBytes {
+			return true, nil
+		}
+	}
+	return e.maxBlocks > 0 && block >= e.firstBlock+e.maxBlocks, nil
+}
+
+// write appends a record, closing the current file first when it is full
+func (e *rotatingExporter) write(record *EventRecord) error {
+	rotate, err := e.needsRotation(record.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if rotate {
+		if err := e.closeFile(); err != nil {
+			return err
+		}
+	}
+	if e.file == nil {
+		if err := e.openFile(record.BlockNumber); err != nil {
+			return err
+		}
+	}
+
+	if err := e.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write %s: %w", e.file.Name(), err)
+	}
+	e.lastBlock = record.BlockNumber
+	return nil
+}
+
+func (e *rotatingExporter) openFile(firstBlock uint64) error {
+	file, err := os.Create(filepath.Join(e.dir, fmt.Sprintf("%s-%012d.%s.partial", e.prefix, firstBlock, e.format)))
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	e.file = file
+	e.counter = &countingWriter{w: file}
+	e.firstBlock = firstBlock
+	e.lastBlock = firstBlock
+	e.encoder, err = newRecordEncoder(e.format, e.counter)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		e.file = nil
+		return err
+	}
+	return nil
+}
+
+// closeFile completes the current file and gives it its final name
+func (e *rotatingExporter) closeFile() error {
+	if e.file == nil {
+		return nil
+	}
+	partial := e.file.Name()
+
+	err := e.encoder.Close()
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	e.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to complete %s: %w", partial, err)
+	}
+
+	name := filepath.Join(e.dir, fmt.Sprintf("%s-%012d-%012d.%s", e.prefix, e.firstBlock, e.lastBlock, e.format))
+	if err := os.Rename(partial, name); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", partial, err)
+	}
+	e.files = append(e.files, name)
+	return nil
+}
@@ -0,0 +1,345 @@
+package eventsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// graphqlReservedTypes are type names an event cannot take
+var graphqlReservedTypes = map[string]bool{"Query": true, "BigInt": true, "JSON": true, "OrderDirection": true, "String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// graphqlBaseFields are served for every event next to its decoded parameters
+var graphqlBaseFields = []string{"cursor", "txHash", "txIndex", "logIndex", "blockNumber", "blockHash", "contractAddress", "eventSignature", "globalAbiMatch"}
+
+// bigIntScalar carries integers of any size as decimal strings
+var bigIntScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "BigInt",
+	Description: "Integer of any size, serialized as a decimal string",
+	Serialize:   func(value interface{}) interface{} { return fmt.Sprint(value) },
+	ParseValue:  func(value interface{}) interface{} { return fmt.Sprint(value) },
+	ParseLiteral: func(value ast.Value) interface{} {
+		switch v := value.(type) {
+		case *ast.IntValue:
+			return v.Value
+		case *ast.StringValue:
+			return v.Value
+		}
+		return nil
+	},
+})
+
+// jsonScalar carries arrays and tuples as they are stored in decoded_params
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:         "JSON",
+	Description:  "Array or tuple parameter as stored in decoded_params",
+	Serialize:    func(value interface{}) interface{} { return value },
+	ParseValue:   func(value interface{}) interface{} { return value },
+	ParseLiteral: func(value ast.Value) interface{} { return value.GetValue() },
+})
+
+var orderDirectionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderDirection",
+	Values: graphql.EnumValueConfigMap{
+		"asc":  &graphql.EnumValueConfig{Value: false},
+		"desc": &graphql.EnumValueConfig{Value: true},
+	},
+})
+
+// graphqlParam is a decoded parameter exposed as a field of an event type
+type graphqlParam struct {
+	Field string // GraphQL field name
+	Key   string // Key in decoded_params
+	Type  graphql.Output
+}
+
+// graphqlName turns an ABI name into a valid GraphQL name
+func graphqlName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) && i > 0):
+			b.WriteRune(r)
+		case unicode.IsDigit(r):
+			b.WriteString("_" + string(r))
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// graphqlParamType maps a Solidity type to the GraphQL type of its field
+func graphqlParamType(solidityType string) graphql.Output {
+	switch {
+	case strings.HasSuffix(solidityType, "]") || strings.HasPrefix(solidityType, "tuple"):
+		return jsonScalar
+	case strings.HasPrefix(solidityType, "uint") || strings.HasPrefix(solidityType, "int"):
+		return bigIntScalar
+	case solidityType == "bool":
+		return graphql.Boolean
+	default:
+		return graphql.String
+	}
+}
+
+// buildGraphQLSchema creates one query field per event signature of set, named after the event in plural camel case.
+// Events sharing a name, or whose type, where type or field name another event already took, get the first bytes
+// of their signature hash appended.
+func buildGraphQLSchema(set *signatureSet, query func(EventQuery) (*EventPage, error)) (graphql.Schema, error) {
+	hashes := make([]string, 0, len(set.global))
+	names := make(map[string]int)
+	for hash, sig := range set.global {
+		hashes = append(hashes, hash)
+		names[sig.Name]++
+	}
+	sort.Strings(hashes)
+
+	// Generated type and field names, a type defined twice fails the schema and a field defined twice hides one
+	used := make(map[string]bool)
+	for name := range graphqlReservedTypes {
+		used[name] = true
+	}
+
+	fields := graphql.Fields{}
+	for _, hash := range hashes {
+		sig := set.global[hash]
+		typeName := graphqlName(sig.Name)
+		if typeName == "" {
+			continue
+		}
+		if graphqlReservedTypes[typeName] || strings.HasPrefix(typeName, "__") {
+			typeName += "Event"
+		}
+		fieldName := strings.ToLower(typeName[:1]) + typeName[1:] + "s"
+		if names[sig.Name] > 1 || used[typeName] || used[typeName+"Where"] || used[fieldName] {
+			suffix := "_" + strings.TrimPrefix(hash, "0x")[:8]
+			typeName += suffix
+			fieldName += suffix
+		}
+		for used[typeName] || used[typeName+"Where"] || used[fieldName] {
+			typeName += "_"
+			fieldName += "_"
+		}
+		used[typeName], used[typeName+"Where"], used[fieldName] = true, true, true
+
+		field, err := buildGraphQLEventField(hash, typeName, &sig, query)
+		if err != nil {
+			return graphql.Schema{}, err
+		}
+		fields[fieldName] = field
+	}
+
+	if len(fields) == 0 {
+		// A schema needs at least one query field
+		fields["events"] = &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "No ABI events are loaded yet",
+			Resolve:     func(graphql.ResolveParams) (interface{}, error) { return []string{}, nil },
+		}
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: fields}),
+	})
+}
+
+// buildGraphQLEventField creates the object and where types of one event signature and the field querying them
+func buildGraphQLEventField(hash, typeName string, sig *EventSignatureInfo, query func(EventQuery) (*EventPage, error)) (*graphql.Field, error) {
+	objectFields := graphql.Fields{
+		"cursor":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "Pass as after to continue after this event"},
+		"txHash":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"txIndex":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"logIndex":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"blockNumber":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"blockHash":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"contractAddress": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"eventSignature":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"globalAbiMatch":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	}
+	whereFields := graphql.InputObjectConfigFieldMap{
+		"contract":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"tx":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"fromBlock": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"toBlock":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	}
+
+	var params []graphqlParam
+	if sig.OriginalABI != nil {
+		used := make(map[string]bool)
+		for _, field := range graphqlBaseFields {
+			used[field] = true
+		}
+		for _, field := range []string{"contract", "tx", "fromBlock", "toBlock"} {
+			used[field] = true
+		}
+
+		for i, input := range sig.OriginalABI.Inputs {
+			field := graphqlName(input.Name)
+			if field == "" {
+				field = fmt.Sprintf("arg%d", i)
+			}
+			for used[field] {
+				field += "_"
+			}
+			used[field] = true
+
+			param := graphqlParam{Field: field, Key: input.Name, Type: graphqlParamType(input.Type)}
+			params = append(params, param)
+			objectFields[field] = &graphql.Field{Type: param.Type, Description: input.Type + " " + input.Name}
+			if param.Type != jsonScalar {
+				whereFields[field] = &graphql.InputObjectFieldConfig{Type: param.Type.(graphql.Input)}
+			}
+		}
+	}
+
+	object := graphql.NewObject(graphql.ObjectConfig{Name: typeName, Fields: objectFields, Description: sig.Signature})
+	where := graphql.NewInputObject(graphql.InputObjectConfig{Name: typeName + "Where", Fields: whereFields})
+
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(object))),
+		Description: sig.Signature,
+		Args: graphql.FieldConfigArgument{
+			"where":          &graphql.ArgumentConfig{Type: where},
+			"first":          &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultEventPageSize},
+			"after":          &graphql.ArgumentConfig{Type: graphql.String},
+			"orderDirection": &graphql.ArgumentConfig{Type: orderDirectionEnum, DefaultValue: false},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			q := EventQuery{Signature: hash, Params: make(map[string]string)}
+			q.Limit, _ = p.Args["first"].(int)
+			q.Cursor, _ = p.Args["after"].(string)
+			q.Descending, _ = p.Args["orderDirection"].(bool)
+			if q.Cursor != "" {
+				if _, _, err := decodeEventCursor(q.Cursor); err != nil {
+					return nil, err
+				}
+			}
+
+			where, _ := p.Args["where"].(map[string]interface{})
+			q.Contract, _ = where["contract"].(string)
+			q.TxHash, _ = where["tx"].(string)
+			if fromBlock, ok := where["fromBlock"].(int); ok {
+				q.FromBlock = uint64(max(fromBlock, 0))
+			}
+			if toBlock, ok := where["toBlock"].(int); ok {
+				q.ToBlock = uint64(max(toBlock, 0))
+			}
+			for _, param := range params {
+				if value, ok := where[param.Field]; ok && value != nil {
+					q.Params[param.Key] = fmt.Sprint(value)
+				}
+			}
+
+			page, err := query(q)
+			if err != nil {
+				return nil, err
+			}
+			return graphqlEvents(page.Events, params)
+		},
+	}, nil
+}
+
+// graphqlEvents turns event records into the field values of their GraphQL type
+func graphqlEvents(records []EventRecord, params []graphqlParam) ([]map[string]interface{}, error) {
+	events := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		decoded := make(map[string]interface{})
+		decoder := json.NewDecoder(bytes.NewReader(record.DecodedParams))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("invalid decoded params of %s:%d: %w", record.TxHash, record.LogIndex, err)
+		}
+
+		event := map[string]interface{}{
+			"cursor":          encodeEventCursor(record.BlockNumber, uint(record.LogIndex)),
+			"txHash":          record.TxHash,
+			"txIndex":         record.TxIndex,
+			"logIndex":        record.LogIndex,
+			"blockNumber":     record.BlockNumber,
+			"blockHash":       record.BlockHash,
+			"contractAddress": record.ContractAddress,
+			"eventSignature":  record.EventSignature,
+			"globalAbiMatch":  record.GlobalABIMatch,
+		}
+		for _, param := range params {
+			event[param.Field] = decoded[param.Key]
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// graphqlAPI serves the schema of the current signature set, rebuilding it after ABIs are reloaded
+type graphqlAPI struct {
+	mu     sync.Mutex
+	set    *signatureSet
+	schema graphql.Schema
+}
+
+// schemaFor returns the schema of set, building it when set was replaced since the last request
+func (g *graphqlAPI) schemaFor(set *signatureSet, query func(EventQuery) (*EventPage, error)) (graphql.Schema, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if set == nil {
+		set = newSignatureSet()
+	}
+
+	if g.set != set {
+		schema, err := buildGraphQLSchema(set, query)
+		if err != nil {
+			return graphql.Schema{}, fmt.Errorf("failed to build GraphQL schema: %w", err)
+		}
+		g.set, g.schema = set, schema
+	}
+	return g.schema, nil
+}
+
+// handleGraphQL serves POST /graphql with a JSON body and GET /graphql?query=...
+func (s *IndexerService) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if r.Method == http.MethodGet {
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid variables: %v", err))
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	query := func(q EventQuery) (*EventPage, error) {
+		return queryEvents(s.db, q, s.config.Storage, s.config.BinaryStorage, s.config.Rollups)
+	}
+	schema, err := s.graphql.schemaFor(s.sigs.Snapshot(), query)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        r.Context(),
+	})
+	writeJSON(w, http.StatusOK, result)
+}
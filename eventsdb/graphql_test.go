@@ -0,0 +1,99 @@
+package eventsdb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testEventSignature parses the ABI event of name with the given inputs into its hash and decoding information
+func testEventSignature(t *testing.T, name, inputs string) (string, EventSignatureInfo) {
+	t.Helper()
+	abiJSON := `{"type":"event","name":"` + name + `","inputs":[` + inputs + `]}`
+	sigHash, sig, err := eventSignatureFromRecord(ABIEventRecord{EventName: name, ABIEventJSON: abiJSON})
+	if err != nil {
+		t.Fatalf("failed to parse the %s ABI: %v", name, err)
+	}
+	return sigHash, sig
+}
+
+func TestGraphQLSchemaNames(t *testing.T) {
+	set := newSignatureSet()
+	transfer := `{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}`
+	for _, event := range []struct{ name, inputs string }{
+		{"Transfer", transfer},
+		{"transfer", transfer},
+		{"Foo", `{"name":"value","type":"uint256"}`},
+		{"FooWhere", `{"name":"value","type":"uint256"}`},
+		{"Query", `{"name":"value","type":"uint256"}`},
+		{"Deposit", `{"name":"amount","type":"uint256"}`},
+		{"Deposit", `{"name":"amount","type":"uint128"}`},
+	} {
+		sigHash, sig := testEventSignature(t, event.name, event.inputs)
+		set.global[sigHash] = sig
+	}
+
+	schema, err := buildGraphQLSchema(set, func(EventQuery) (*EventPage, error) { return &EventPage{}, nil })
+	if err != nil {
+		t.Fatalf("failed to build the schema: %v", err)
+	}
+
+	// Every event keeps its own field, names taken by another event get a hash suffix
+	var fields []string
+	types := make(map[string]bool)
+	for name, field := range schema.QueryType().Fields() {
+		fields = append(fields, name)
+		types[strings.Trim(field.Type.String(), "[]!")] = true
+	}
+	sort.Strings(fields)
+	if len(fields) != 7 || len(types) != 7 {
+		t.Fatalf("schema has fields %v of types %v, want one field and type per event", fields, types)
+	}
+	for prefix, want := range map[string]int{"transfers": 2, "foos": 1, "fooWheres": 1, "queryEvents": 1, "deposits_": 2} {
+		matches := 0
+		for _, name := range fields {
+			if strings.HasPrefix(name, prefix) {
+				matches++
+			}
+		}
+		if matches != want {
+			t.Fatalf("schema has fields %v, want %d starting with %s", fields, want, prefix)
+		}
+	}
+}
+
+func TestGraphQLQuery(t *testing.T) {
+	sigHash, sig := testTransferSignature(t)
+	s := openTestService(t, testSQLiteConfig(t))
+	set := newSignatureSet()
+	set.global[sigHash] = sig
+	s.sigs.Replace(set)
+	writeTestRange(t, s.sink, 100, 109, testTransfers(t, &sig, 100, 109, 2))
+
+	body := `{"query":"{ transfers(where: {value: \"105001\"}) { blockNumber logIndex value to } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	s.httpHandler().ServeHTTP(recorder, req)
+
+	var result struct {
+		Data struct {
+			Transfers []struct {
+				BlockNumber int    `json:"blockNumber"`
+				LogIndex    int    `json:"logIndex"`
+				Value       string `json:"value"`
+				To          string `json:"to"`
+			} `json:"transfers"`
+		} `json:"data"`
+		Errors []interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil || len(result.Errors) != 0 {
+		t.Fatalf("GraphQL answered %d %s (error %v)", recorder.Code, recorder.Body, err)
+	}
+	transfers := result.Data.Transfers
+	if len(transfers) != 1 || transfers[0].BlockNumber != 105 || transfers[0].LogIndex != 1 || transfers[0].Value != "105001" {
+		t.Fatalf("GraphQL returned %+v, want the second transfer of block 105", transfers)
+	}
+}
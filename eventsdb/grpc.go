@@ -0,0 +1,184 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/Matltin/event-fetcher/eventspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcAPI implements eventspb.EventService on the stored events and the live event stream
+type grpcAPI struct {
+	eventspb.UnimplementedEventServiceServer
+	s *IndexerService
+}
+
+// listenGRPC creates the gRPC server and listens on GRPCAddr
+func (s *IndexerService) listenGRPC() (net.Listener, error) {
+	listener, err := net.Listen("tcp", s.config.GRPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.config.GRPCAddr, err)
+	}
+
+	s.grpcServer = grpc.NewServer()
+	eventspb.RegisterEventServiceServer(s.grpcServer, &grpcAPI{s: s})
+	log.Printf("gRPC server listening on %s\n", s.config.GRPCAddr)
+	return listener, nil
+}
+
+// startGRPCServer serves the gRPC API on GRPCAddr in the background
+func (s *IndexerService) startGRPCServer() error {
+	listener, err := s.listenGRPC()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.grpcServer.Serve(listener); err != nil {
+			log.Printf("gRPC server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// StreamEvents replays the stored events of the request, then forwards the live ones.
+// Sends block while the client applies flow control; a client that falls behind the stream buffer is ended.
+func (api *grpcAPI) StreamEvents(req *eventspb.StreamEventsRequest, stream eventspb.EventService_StreamEventsServer) error {
+	streamReq := streamRequest{
+		Contract:  req.GetFilter().GetContract(),
+		EventName: req.GetFilter().GetEventName(),
+		FromBlock: req.GetFromBlock(),
+	}
+	if position := req.GetFromPosition(); position != nil {
+		streamReq.Cursor = encodeEventCursor(position.GetBlockNumber(), uint(position.GetLogIndex()))
+	}
+
+	err := api.s.streamEvents(stream.Context(), streamReq, func(message StreamMessage) error {
+		response := &eventspb.StreamEventsResponse{}
+		switch message.Type {
+		case StreamEvent:
+			event, err := protoEvent(message.Event)
+			if err != nil {
+				return err
+			}
+			response.Message = &eventspb.StreamEventsResponse_Event{Event: event}
+		case StreamRollback:
+			response.Message = &eventspb.StreamEventsResponse_Rollback{Rollback: &eventspb.Rollback{FromBlock: message.FromBlock}}
+		}
+		return stream.Send(response)
+	})
+	if errors.Is(err, errStreamTooSlow) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, errStreamClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// ListEvents returns one page of stored events, see EventQuery for the filters
+func (api *grpcAPI) ListEvents(ctx context.Context, req *eventspb.ListEventsRequest) (*eventspb.ListEventsResponse, error) {
+	q := EventQuery{
+		Contract:   req.GetFilter().GetContract(),
+		EventName:  req.GetFilter().GetEventName(),
+		Signature:  req.GetSignature(),
+		TxHash:     req.GetTxHash(),
+		FromBlock:  req.GetFromBlock(),
+		ToBlock:    req.GetToBlock(),
+		Params:     req.GetParams(),
+		Cursor:     req.GetPageToken(),
+		Limit:      int(min(req.GetPageSize(), MaxEventPageSize)),
+		Descending: req.GetDescending(),
+	}
+	if req.FromTime != nil {
+		q.FromTime = req.GetFromTime().AsTime()
+	}
+	if req.ToTime != nil {
+		q.ToTime = req.GetToTime().AsTime()
+	}
+
+	if q.Cursor != "" {
+		if _, _, err := decodeEventCursor(q.Cursor); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+	if (!q.FromTime.IsZero() || !q.ToTime.IsZero()) && !api.s.config.Rollups {
+		return nil, status.Error(codes.FailedPrecondition, "time filters need the rollup tables, set ROLLUPS=true")
+	}
+
+	page, err := queryEvents(api.s.db.WithContext(ctx), q, api.s.config.Storage, api.s.config.BinaryStorage, api.s.config.Rollups)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &eventspb.ListEventsResponse{NextPageToken: page.NextCursor}
+	for i := range page.Events {
+		event, err := protoEvent(&page.Events[i])
+		if err != nil {
+			return nil, err
+		}
+		response.Events = append(response.Events, event)
+	}
+	return response, nil
+}
+
+// GetCursor returns the last indexed block
+func (api *grpcAPI) GetCursor(ctx context.Context, req *eventspb.GetCursorRequest) (*eventspb.GetCursorResponse, error) {
+	cursor, ok, err := readCursor(api.s.db.WithContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return &eventspb.GetCursorResponse{}, nil
+	}
+	return &eventspb.GetCursorResponse{
+		BlockNumber: uint64(cursor.Count),
+		BlockHash:   cursor.BlockHash,
+		Indexed:     true,
+	}, nil
+}
+
+// protoEvent converts a stored event to its protobuf message, decoded parameters become a Struct
+func protoEvent(record *EventRecord) (*eventspb.Event, error) {
+	var params map[string]interface{}
+	if err := json.Unmarshal(record.DecodedParams, &params); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read decoded params of %s:%d: %v", record.TxHash, record.LogIndex, err)
+	}
+	decodedParams, err := structpb.NewStruct(params)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert decoded params of %s:%d: %v", record.TxHash, record.LogIndex, err)
+	}
+
+	return &eventspb.Event{
+		TxHash:             record.TxHash,
+		TxIndex:            record.TxIndex,
+		BlockNumber:        record.BlockNumber,
+		BlockHash:          record.BlockHash,
+		LogIndex:           record.LogIndex,
+		Removed:            record.Removed,
+		ContractAddress:    record.ContractAddress,
+		EventSignature:     record.EventSignature,
+		EventName:          record.EventName,
+		EventFullSignature: record.EventFullSignature,
+		OtherTopics:        record.OtherTopics,
+		RawData:            record.RawData,
+		DecodedParams:      decodedParams,
+		GlobalAbiMatch:     record.GlobalABIMatch,
+		InsertTime:         timestamppb.New(record.InsertTime),
+	}, nil
+}
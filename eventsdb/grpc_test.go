@@ -0,0 +1,21 @@
+package eventsdb
+
+import "testing"
+
+func TestProtoEvent(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	events := testTransfers(t, &sig, 100, 100, 1)
+	events[0].Removed = true
+	record := newEventRecord(&events[0])
+
+	event, err := protoEvent(&record)
+	if err != nil {
+		t.Fatalf("failed to convert the record: %v", err)
+	}
+	if !event.Removed || event.TxHash != record.TxHash || event.LogIndex != record.LogIndex || event.GetEventName() != "Transfer" {
+		t.Fatalf("record %+v converted to %v", record, event)
+	}
+	if value := event.DecodedParams.Fields["value"]; value == nil {
+		t.Fatalf("decoded params %v lack the value", event.DecodedParams)
+	}
+}
@@ -0,0 +1,293 @@
+package eventsdb
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxABIUploadSize limits the body of an ABI upload
+const maxABIUploadSize = 10 << 20
+
+// startHTTPServer serves the HTTP API on HTTPAddr in the background
+func (s *IndexerService) startHTTPServer() {
+	s.httpServer = &http.Server{
+		Addr:    s.config.HTTPAddr,
+		Handler: s.httpHandler(),
+	}
+
+	go func() {
+		log.Printf("HTTP server listening on %s\n", s.config.HTTPAddr)
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server stopped: %v\n", err)
+		}
+	}()
+}
+
+// httpHandler routes the HTTP API. /events, /graphql and the /rpc JSON-RPC facade serve the stored events, /stream and /ws push new ones,
+// the /admin/abi routes manage the ABI registry the same way as the abi subcommands.
+func (s *IndexerService) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /graphql", s.handleGraphQL)
+	mux.HandleFunc("POST /graphql", s.handleGraphQL)
+	mux.HandleFunc("GET /stream", s.handleSSE)
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+	mux.HandleFunc("POST /rpc", s.handleRPC)
+	mux.HandleFunc("POST /admin/abi", s.requireAdmin(s.handleABIUpload))
+	mux.HandleFunc("PUT /admin/abi", s.requireAdmin(s.handleABIReplace))
+	mux.HandleFunc("GET /admin/abi", s.requireAdmin(s.handleABIList))
+	mux.HandleFunc("GET /admin/abi/conflicts", s.requireAdmin(s.handleABIConflicts))
+	mux.HandleFunc("GET /admin/abi/{hash}", s.requireAdmin(s.handleABIShow))
+	mux.HandleFunc("GET /admin/abi/{hash}/usage", s.requireAdmin(s.handleABIUsage))
+	mux.HandleFunc("DELETE /admin/abi/{hash}", s.requireAdmin(s.handleABIDelete))
+	return mux
+}
+
+// Serve runs only the HTTP API on HTTPAddr and the gRPC API on GRPCAddr, without indexing,
+// until ctx is done or a server fails
+func (s *IndexerService) Serve(ctx context.Context) error {
+	if s.config.HTTPAddr == "" && s.config.GRPCAddr == "" {
+		return fmt.Errorf("neither HTTP_ADDR nor GRPC_ADDR is set")
+	}
+	if err := s.ensureDatabase(); err != nil {
+		return err
+	}
+	defer s.sink.Close()
+	if err := s.loadEventSignatures(); err != nil {
+		log.Printf("Warning: Failed to load event signatures: %v\n", err)
+	}
+
+	failed := make(chan error, 2)
+	if s.config.GRPCAddr != "" {
+		listener, err := s.listenGRPC()
+		if err != nil {
+			return err
+		}
+		go func() { failed <- s.grpcServer.Serve(listener) }()
+	}
+	if s.config.HTTPAddr != "" {
+		s.httpServer = &http.Server{
+			Addr:    s.config.HTTPAddr,
+			Handler: s.httpHandler(),
+		}
+		log.Printf("HTTP server listening on %s\n", s.config.HTTPAddr)
+		go func() { failed <- s.httpServer.ListenAndServe() }()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-failed:
+	}
+	s.stopServers()
+	s.rpc.close()
+	return err
+}
+
+// stopServers ends the event streams and shuts the HTTP and gRPC servers down,
+// requests still running after DefaultShutdownTimeout are cut off
+func (s *IndexerService) stopServers() {
+	if s.stream != nil {
+		s.stream.close()
+	}
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			log.Printf("Warning: HTTP server did not shut down in time: %v\n", err)
+			s.httpServer.Close()
+		}
+	}
+
+	if s.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(DefaultShutdownTimeout):
+			log.Println("Warning: gRPC server did not shut down in time")
+			s.grpcServer.Stop()
+		}
+	}
+}
+
+// requireAdmin rejects requests without the configured admin token
+func (s *IndexerService) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminToken == "" {
+			writeJSONError(w, http.StatusForbidden, "admin endpoints are disabled, set ADMIN_TOKEN to enable them")
+			return
+		}
+
+		token := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(token), []byte("Bearer "+s.config.AdminToken)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// readABIBody reads the body of an ABI upload, a body over maxABIUploadSize is answered with 413 instead of being cut
+func readABIBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	abiData, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxABIUploadSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("ABI is larger than %d bytes", maxABIUploadSize))
+		return nil, false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return nil, false
+	}
+	return abiData, true
+}
+
+// handleABIUpload stores the events of an uploaded ABI and reloads the signatures.
+// The body may use any format accepted in AbiDir, the optional name query parameter is kept as its source.
+func (s *IndexerService) handleABIUpload(w http.ResponseWriter, r *http.Request) {
+	abiData, ok := readABIBody(w, r)
+	if !ok {
+		return
+	}
+
+	abiEvents, err := parseABIData(abiData)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse ABI: %v", err))
+		return
+	}
+
+	sourceFile := "upload"
+	if name := r.URL.Query().Get("name"); name != "" {
+		sourceFile = "upload:" + name
+	}
+
+	written, err := storeABIEvents(s.db, abiEvents, sourceFile)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := s.reloadEventSignatures(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reload event signatures: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{
+		"events":  len(abiEvents),
+		"written": written,
+	})
+}
+
+// handleABIReplace replaces every event registered for the source query parameter with the uploaded ABI
+func (s *IndexerService) handleABIReplace(w http.ResponseWriter, r *http.Request) {
+	sourceFile := r.URL.Query().Get("source")
+	if sourceFile == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing source query parameter")
+		return
+	}
+
+	abiData, ok := readABIBody(w, r)
+	if !ok {
+		return
+	}
+
+	abiEvents, err := parseABIData(abiData)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse ABI: %v", err))
+		return
+	}
+
+	written, err := replaceABISource(s.db, sourceFile, abiEvents)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := s.reloadEventSignatures(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reload event signatures: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"written": written})
+}
+
+func (s *IndexerService) handleABIList(w http.ResponseWriter, r *http.Request) {
+	events, err := s.ListABIEvents(r.URL.Query().Get("name"), r.URL.Query().Get("source"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *IndexerService) handleABIConflicts(w http.ResponseWriter, r *http.Request) {
+	conflicts, err := s.FindABIConflicts()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, conflicts)
+}
+
+func (s *IndexerService) handleABIShow(w http.ResponseWriter, r *http.Request) {
+	events, err := s.GetABIEvents(r.PathValue("hash"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(events) == 0 {
+		writeJSONError(w, http.StatusNotFound, "unknown signature hash")
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *IndexerService) handleABIUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.ABIEventUsage(r.PathValue("hash"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// handleABIDelete removes the definitions of a hash, only from the source query parameter when it is set
+func (s *IndexerService) handleABIDelete(w http.ResponseWriter, r *http.Request) {
+	deleted, err := s.DeleteABIEvents(r.PathValue("hash"), r.URL.Query().Get("source"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := s.reloadEventSignatures(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to reload event signatures: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v\n", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,557 @@
+package eventsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gorm.io/gorm"
+)
+
+// Limits of the JSON-RPC facade
+const (
+	maxRPCRequestSize = 1 << 20
+	maxRPCBatchSize   = 100
+	MaxRPCLogs        = 10_000 // eth_getLogs fails instead of returning more logs
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+	rpcLimitExceeded  = -32005
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcFacade answers eth_getLogs, eth_blockNumber and eth_chainId from the stored events.
+// Ranges the database does not cover are sent to RPC_URL when RPCProxy is set.
+type rpcFacade struct {
+	mu       sync.Mutex
+	upstream *ethclient.Client // Connected on first use
+	chainID  *big.Int
+}
+
+// upstreamClient returns the client of RPC_URL, connecting on first use
+func (s *IndexerService) upstreamClient(ctx context.Context) (*ethclient.Client, error) {
+	s.rpc.mu.Lock()
+	defer s.rpc.mu.Unlock()
+	if s.rpc.upstream == nil {
+		client, err := connectWithRetry(ctx, s.config.RPC, s.config.MaxRetries, s.config.RetryDelay)
+		if err != nil {
+			return nil, err
+		}
+		s.rpc.upstream = client
+	}
+	return s.rpc.upstream, nil
+}
+
+// close disconnects the client of RPC_URL
+func (f *rpcFacade) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.upstream != nil {
+		f.upstream.Close()
+		f.upstream = nil
+	}
+}
+
+// rpcChainID returns CHAIN_ID, or the chain id of RPC_URL asked once
+func (s *IndexerService) rpcChainID(ctx context.Context) (*big.Int, error) {
+	if s.config.ChainID > 0 {
+		return big.NewInt(s.config.ChainID), nil
+	}
+
+	client, err := s.upstreamClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.rpc.mu.Lock()
+	defer s.rpc.mu.Unlock()
+	if s.rpc.chainID == nil {
+		ctx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		defer cancel()
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain id: %w", err)
+		}
+		s.rpc.chainID = chainID
+	}
+	return s.rpc.chainID, nil
+}
+
+// logFilter is the parameter of eth_getLogs, block numbers are resolved against the indexed cursor
+type logFilter struct {
+	BlockHash *common.Hash      `json:"blockHash"`
+	FromBlock *rpc.BlockNumber  `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber  `json:"toBlock"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+// filterQuery converts the filter, tags resolve to 0 (earliest) or head (latest, safe, finalized and pending)
+func (f *logFilter) filterQuery(head uint64) (ethereum.FilterQuery, error) {
+	var query ethereum.FilterQuery
+
+	resolve := func(number *rpc.BlockNumber) *big.Int {
+		switch {
+		case number == nil:
+			return new(big.Int).SetUint64(head)
+		case *number == rpc.EarliestBlockNumber:
+			return new(big.Int)
+		case *number < 0:
+			return new(big.Int).SetUint64(head)
+		}
+		return big.NewInt(number.Int64())
+	}
+	if f.BlockHash != nil {
+		if f.FromBlock != nil || f.ToBlock != nil {
+			return query, fmt.Errorf("cannot specify both blockHash and fromBlock/toBlock")
+		}
+		query.BlockHash = f.BlockHash
+	} else {
+		query.FromBlock = resolve(f.FromBlock)
+		query.ToBlock = resolve(f.ToBlock)
+		if query.FromBlock.Cmp(query.ToBlock) > 0 {
+			return query, fmt.Errorf("invalid block range params")
+		}
+	}
+
+	if len(f.Address) > 0 && string(f.Address) != "null" {
+		var addresses []common.Address
+		if f.Address[0] == '[' {
+			if err := json.Unmarshal(f.Address, &addresses); err != nil {
+				return query, fmt.Errorf("invalid address: %v", err)
+			}
+		} else {
+			var address common.Address
+			if err := json.Unmarshal(f.Address, &address); err != nil {
+				return query, fmt.Errorf("invalid address: %v", err)
+			}
+			addresses = []common.Address{address}
+		}
+		query.Addresses = addresses
+	}
+
+	if len(f.Topics) > 4 {
+		return query, fmt.Errorf("exceed max topics")
+	}
+	for i, raw := range f.Topics {
+		var topics []common.Hash
+		switch {
+		case len(raw) == 0 || string(raw) == "null":
+		case raw[0] == '[':
+			var alternatives []*common.Hash
+			if err := json.Unmarshal(raw, &alternatives); err != nil {
+				return query, fmt.Errorf("invalid topic %d: %v", i, err)
+			}
+			for _, topic := range alternatives {
+				if topic == nil {
+					// A null alternative matches every topic
+					topics = nil
+					break
+				}
+				topics = append(topics, *topic)
+			}
+		default:
+			var topic common.Hash
+			if err := json.Unmarshal(raw, &topic); err != nil {
+				return query, fmt.Errorf("invalid topic %d: %v", i, err)
+			}
+			topics = []common.Hash{topic}
+		}
+		query.Topics = append(query.Topics, topics)
+	}
+	return query, nil
+}
+
+// matchTopics reports whether the topics of a log match the filter positions, an empty position matches anything
+func matchTopics(filter [][]common.Hash, topics []common.Hash) bool {
+	for i, alternatives := range filter {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if i >= len(topics) {
+			return false
+		}
+		matched := false
+		for _, topic := range alternatives {
+			if topic == topics[i] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// eventLog converts a stored event back to the log it was built from
+func eventLog(event *BlockchainEvent) (types.Log, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(event.RawData, "0x"))
+	if err != nil {
+		return types.Log{}, fmt.Errorf("invalid raw data of %s:%d: %w", event.TxHash, event.LogIndex, err)
+	}
+
+	topics := make([]common.Hash, 0, 1+len(event.OtherTopics))
+	if event.EventSignature != "" {
+		topics = append(topics, common.HexToHash(event.EventSignature))
+	}
+	for _, topic := range event.OtherTopics {
+		topics = append(topics, common.HexToHash(topic))
+	}
+
+	return types.Log{
+		Address:     common.HexToAddress(event.ContractAddress),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: event.BlockNumber,
+		TxHash:      common.HexToHash(event.TxHash),
+		TxIndex:     event.TxIndex,
+		BlockHash:   common.HexToHash(event.BlockHash),
+		Index:       event.LogIndex,
+		Removed:     event.Removed,
+	}, nil
+}
+
+// coveredRange returns the blocks of [fromBlock, toBlock] answered from the database.
+// Blocks before StartBlock and after the cursor are not indexed; ok is false when the range is not covered at all
+// or archived or stripped events leave holes in it.
+func (s *IndexerService) coveredRange(db *gorm.DB, fromBlock, toBlock, head uint64) (uint64, uint64, bool, error) {
+	fromBlock = max(fromBlock, uint64(s.config.StartBlock))
+	toBlock = min(toBlock, head)
+	if fromBlock > toBlock {
+		return 0, 0, false, nil
+	}
+
+	var archived int64
+	err := db.Model(&ArchivedRange{}).
+		Where("restored_at IS NULL AND from_block <= ? AND to_block >= ?", toBlock, fromBlock).
+		Count(&archived).Error
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to query archived ranges: %w", err)
+	}
+
+	var stripped int64
+	err = db.Model(&BlockchainEvent{}).
+		Where("block_number BETWEEN ? AND ? AND raw_data IS NULL", fromBlock, toBlock).
+		Count(&stripped).Error
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to query stripped events: %w", err)
+	}
+	return fromBlock, toBlock, archived == 0 && stripped == 0, nil
+}
+
+// storedLogs returns the stored logs of query in [fromBlock, toBlock], or of its block hash
+func (s *IndexerService) storedLogs(db *gorm.DB, query ethereum.FilterQuery, fromBlock, toBlock uint64) ([]types.Log, error) {
+	binary := s.config.BinaryStorage
+	base := db.Model(&BlockchainEvent{})
+	if query.BlockHash != nil {
+		base = base.Where("block_hash = ?", hexParam(binary, strings.ToLower(query.BlockHash.Hex())))
+	} else {
+		base = base.Where("block_number BETWEEN ? AND ?", fromBlock, toBlock)
+	}
+	if len(query.Addresses) > 0 {
+		addresses := make([]string, 0, len(query.Addresses))
+		for _, address := range query.Addresses {
+			addresses = append(addresses, address.Hex())
+		}
+		base = base.Where("contract_address IN ?", hexParams(binary, addresses))
+	}
+	if len(query.Topics) > 0 && len(query.Topics[0]) > 0 {
+		signatures := make([]string, 0, len(query.Topics[0]))
+		for _, topic := range query.Topics[0] {
+			signatures = append(signatures, strings.ToLower(topic.Hex()))
+		}
+		base = base.Where("event_signature IN ?", hexParams(binary, signatures))
+	}
+
+	// Later topics are matched in Go, pages are read in block and log order
+	logs := []types.Log{}
+	var last *BlockchainEvent
+	for {
+		page := base.Session(&gorm.Session{})
+		if last != nil {
+			page = page.Where("block_number > ? OR (block_number = ? AND log_index > ?)", last.BlockNumber, last.BlockNumber, last.LogIndex)
+		}
+		var events []BlockchainEvent
+		if err := page.Order("block_number, log_index").Limit(MaxEventPageSize).Find(&events).Error; err != nil {
+			return nil, fmt.Errorf("failed to query events: %w", err)
+		}
+
+		for i := range events {
+			log, err := eventLog(&events[i])
+			if err != nil {
+				return nil, err
+			}
+			if !matchTopics(query.Topics, log.Topics) {
+				continue
+			}
+			if len(logs) == MaxRPCLogs {
+				return nil, &rpcError{Code: rpcLimitExceeded, Message: fmt.Sprintf("query returned more than %d results", MaxRPCLogs)}
+			}
+			logs = append(logs, log)
+		}
+
+		if len(events) < MaxEventPageSize {
+			break
+		}
+		last = &events[len(events)-1]
+	}
+
+	if s.config.Rollups {
+		if err := fillLogTimes(db, logs); err != nil {
+			return nil, err
+		}
+	}
+	return logs, nil
+}
+
+// fillLogTimes sets the block timestamps of logs from event_counts_block
+func fillLogTimes(db *gorm.DB, logs []types.Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	var counts []BlockEventCount
+	err := db.Select("block_number, block_time").
+		Where("block_number BETWEEN ? AND ?", logs[0].BlockNumber, logs[len(logs)-1].BlockNumber).
+		Find(&counts).Error
+	if err != nil {
+		return fmt.Errorf("failed to look up block times: %w", err)
+	}
+
+	times := make(map[uint64]uint64, len(counts))
+	for _, count := range counts {
+		times[count.BlockNumber] = uint64(count.BlockTime.Unix())
+	}
+	for i := range logs {
+		logs[i].BlockTimestamp = times[logs[i].BlockNumber]
+	}
+	return nil
+}
+
+// upstreamLogs fetches the logs of query in [fromBlock, toBlock] (or of its block hash) from RPC_URL
+func (s *IndexerService) upstreamLogs(ctx context.Context, query ethereum.FilterQuery, fromBlock, toBlock uint64) ([]types.Log, error) {
+	client, err := s.upstreamClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if query.BlockHash == nil {
+		query.FromBlock = new(big.Int).SetUint64(fromBlock)
+		query.ToBlock = new(big.Int).SetUint64(toBlock)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+	defer cancel()
+	logs, err := client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("upstream eth_getLogs failed: %w", err)
+	}
+	return logs, nil
+}
+
+// getLogs answers eth_getLogs. Only the indexed contract is stored, filters on other addresses are not covered.
+// Without RPCProxy an uncovered range fails, with it the blocks before and after the covered range are fetched upstream.
+func (s *IndexerService) getLogs(ctx context.Context, filter logFilter) ([]types.Log, error) {
+	db := s.db.WithContext(ctx)
+	cursor, indexed, err := readCursor(db)
+	if err != nil {
+		return nil, err
+	}
+	head := uint64(max(cursor.Count, 0))
+
+	query, err := filter.filterQuery(head)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	contract := common.HexToAddress(s.config.ContractAddr)
+	coveredAddress := len(query.Addresses) > 0
+	for _, address := range query.Addresses {
+		coveredAddress = coveredAddress && address == contract
+	}
+
+	if query.BlockHash != nil {
+		// A block hash is covered once an event of the block is stored
+		var count int64
+		err := db.Model(&BlockchainEvent{}).Where("block_hash = ?", hexParam(s.config.BinaryStorage, strings.ToLower(query.BlockHash.Hex()))).Count(&count).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to query events: %w", err)
+		}
+		if coveredAddress && count > 0 {
+			return s.storedLogs(db, query, 0, 0)
+		}
+		if s.config.RPCProxy {
+			return s.upstreamLogs(ctx, query, 0, 0)
+		}
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("block %s of this filter is not indexed", query.BlockHash.Hex())}
+	}
+
+	fromBlock, toBlock := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	coveredFrom, coveredTo, covered := uint64(0), uint64(0), false
+	if indexed && coveredAddress {
+		if coveredFrom, coveredTo, covered, err = s.coveredRange(db, fromBlock, toBlock, head); err != nil {
+			return nil, err
+		}
+	}
+
+	if !covered {
+		if !s.config.RPCProxy {
+			return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("blocks %d to %d of this filter are not indexed", fromBlock, toBlock)}
+		}
+		return s.upstreamLogs(ctx, query, fromBlock, toBlock)
+	}
+	if !s.config.RPCProxy && (coveredFrom > fromBlock || coveredTo < toBlock) {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("only blocks %d to %d of this filter are indexed", coveredFrom, coveredTo)}
+	}
+
+	logs := []types.Log{}
+	if coveredFrom > fromBlock {
+		before, err := s.upstreamLogs(ctx, query, fromBlock, coveredFrom-1)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, before...)
+	}
+	stored, err := s.storedLogs(db, query, coveredFrom, coveredTo)
+	if err != nil {
+		return nil, err
+	}
+	logs = append(logs, stored...)
+	if coveredTo < toBlock {
+		after, err := s.upstreamLogs(ctx, query, coveredTo+1, toBlock)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, after...)
+	}
+	if len(logs) > MaxRPCLogs {
+		return nil, &rpcError{Code: rpcLimitExceeded, Message: fmt.Sprintf("query returned more than %d results", MaxRPCLogs)}
+	}
+	return logs, nil
+}
+
+// callRPC runs one JSON-RPC request
+func (s *IndexerService) callRPC(ctx context.Context, req rpcRequest) rpcResponse {
+	response := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if response.ID == nil {
+		response.ID = json.RawMessage("null")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		response.Error = &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}
+		return response
+	}
+
+	var result any
+	var err error
+	switch req.Method {
+	case "eth_chainId":
+		var chainID *big.Int
+		if chainID, err = s.rpcChainID(ctx); err == nil {
+			result = (*hexutil.Big)(chainID)
+		}
+	case "eth_blockNumber":
+		var cursor Cursor
+		if cursor, _, err = readCursor(s.db.WithContext(ctx)); err == nil {
+			result = hexutil.Uint64(max(cursor.Count, 0))
+		}
+	case "eth_getLogs":
+		var filter logFilter
+		if len(req.Params) != 1 {
+			err = &rpcError{Code: rpcInvalidParams, Message: "eth_getLogs takes one filter object"}
+		} else if err = json.Unmarshal(req.Params[0], &filter); err != nil {
+			err = &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid filter: %v", err)}
+		} else {
+			result, err = s.getLogs(ctx, filter)
+		}
+	default:
+		err = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+	}
+
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		response.Error = rpcErr
+		return response
+	}
+	response.Result = result
+	return response
+}
+
+// handleRPC serves POST /rpc, single and batch JSON-RPC requests
+func (s *IndexerService) handleRPC(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRPCRequestSize))
+	if err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var requests []rpcRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			return
+		}
+		if len(requests) == 0 || len(requests) > maxRPCBatchSize {
+			writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcInvalidRequest, Message: fmt.Sprintf("batch must hold 1 to %d requests", maxRPCBatchSize)}})
+			return
+		}
+		responses := make([]rpcResponse, 0, len(requests))
+		for _, req := range requests {
+			responses = append(responses, s.callRPC(r.Context(), req))
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.callRPC(r.Context(), req))
+}
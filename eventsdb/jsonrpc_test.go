@@ -0,0 +1,111 @@
+package eventsdb
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// openTestRPC serves the JSON-RPC facade of s and returns a client of it
+func openTestRPC(t *testing.T, s *IndexerService) *ethclient.Client {
+	t.Helper()
+	server := httptest.NewServer(s.httpHandler())
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL + "/rpc")
+	if err != nil {
+		t.Fatalf("failed to dial the JSON-RPC facade: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// assertRPCError fails the test unless err is a JSON-RPC error with code whose message contains message
+func assertRPCError(t *testing.T, err error, code int, message string) {
+	t.Helper()
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != code || !strings.Contains(rpcErr.Error(), message) {
+		t.Fatalf("got error %v, want code %d with %q", err, code, message)
+	}
+}
+
+func TestJSONRPCGetLogs(t *testing.T) {
+	sigHash, sig := testTransferSignature(t)
+	config := testSQLiteConfig(t)
+	config.StartBlock = 100
+	config.ChainID = 5
+	s := openTestService(t, config)
+	writeTestRange(t, s.sink, 100, 149, testTransfers(t, &sig, 100, 149, 2))
+	client := openTestRPC(t, s)
+	ctx := context.Background()
+
+	if head, err := client.BlockNumber(ctx); err != nil || head != 149 {
+		t.Fatalf("eth_blockNumber returned %d (error %v), want 149", head, err)
+	}
+	if chainID, err := client.ChainID(ctx); err != nil || chainID.Int64() != 5 {
+		t.Fatalf("eth_chainId returned %v (error %v), want 5", chainID, err)
+	}
+
+	contract := common.HexToAddress(testContract)
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(110), ToBlock: big.NewInt(119), Addresses: []common.Address{contract}})
+	if err != nil {
+		t.Fatalf("eth_getLogs failed: %v", err)
+	}
+	var want []BlockchainEvent
+	for _, event := range storedEvents(t, s.db) {
+		if event.BlockNumber >= 110 && event.BlockNumber <= 119 {
+			want = append(want, event)
+		}
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("eth_getLogs returned %d logs, want %d", len(logs), len(want))
+	}
+	for i := range want {
+		wantLog, err := eventLog(&want[i])
+		if err != nil {
+			t.Fatalf("failed to convert event: %v", err)
+		}
+		if !reflect.DeepEqual(logs[i], wantLog) {
+			t.Fatalf("log %d is %+v, want %+v", i, logs[i], wantLog)
+		}
+	}
+
+	// Later topics are matched too, the second log of every block up to latest has topic 2 in its first indexed position
+	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: big.NewInt(100),
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{{common.HexToHash(sigHash)}, {common.BigToHash(big.NewInt(2))}},
+	})
+	if err != nil || len(logs) != 50 || logs[0].Index != 1 {
+		t.Fatalf("topic filter returned %d logs (error %v), want the 50 second logs", len(logs), err)
+	}
+
+	blockHash := testBlockHash(120)
+	logs, err = client.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &blockHash, Addresses: []common.Address{contract}})
+	if err != nil || len(logs) != 2 || logs[0].BlockNumber != 120 {
+		t.Fatalf("block hash filter returned %d logs (error %v), want the 2 of block 120", len(logs), err)
+	}
+
+	// Without RPC_PROXY uncovered filters fail instead of returning nothing
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(50), ToBlock: big.NewInt(60), Addresses: []common.Address{contract}})
+	assertRPCError(t, err, rpcServerError, "blocks 50 to 60 of this filter are not indexed")
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(90), ToBlock: big.NewInt(110), Addresses: []common.Address{contract}})
+	assertRPCError(t, err, rpcServerError, "only blocks 100 to 110 of this filter are indexed")
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(110), ToBlock: big.NewInt(119)})
+	assertRPCError(t, err, rpcServerError, "not indexed")
+	unknown := testBlockHash(200)
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &unknown, Addresses: []common.Address{contract}})
+	assertRPCError(t, err, rpcServerError, "block "+unknown.Hex()+" of this filter is not indexed")
+
+	_, err = client.FilterLogs(ctx, ethereum.FilterQuery{FromBlock: big.NewInt(120), ToBlock: big.NewInt(110)})
+	assertRPCError(t, err, rpcInvalidParams, "invalid block range params")
+}
@@ -0,0 +1,249 @@
+package eventsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// AllEvents registers a handler for every event, including events no ABI matched
+const AllEvents = "*"
+
+// Option configures an IndexerService created with New
+type Option func(*IndexerService)
+
+// WithConfig replaces the whole configuration, options after it change single settings
+func WithConfig(config Config) Option {
+	return func(s *IndexerService) { s.config = config }
+}
+
+// WithRPC sets the RPC endpoint logs and headers are fetched from
+func WithRPC(rpcURL string) Option {
+	return func(s *IndexerService) { s.config.RPC = rpcURL }
+}
+
+// WithContract sets the contract whose events are indexed
+func WithContract(address string) Option {
+	return func(s *IndexerService) { s.config.ContractAddr = address }
+}
+
+// WithABIDir sets the directory the ABIs used for decoding are loaded from
+func WithABIDir(dir string) Option {
+	return func(s *IndexerService) {
+		s.config.AbiDir = dir
+		s.config.ABIBindingsFile = filepath.Join(dir, "bindings.json")
+	}
+}
+
+// WithStartBlock sets the first block indexed when no cursor is stored
+func WithStartBlock(block int64) Option {
+	return func(s *IndexerService) { s.config.StartBlock = max(block, 1) }
+}
+
+// WithFinality sets how many blocks behind the head indexing stays
+func WithFinality(blocks int64) Option {
+	return func(s *IndexerService) { s.config.FinalityBlock = blocks }
+}
+
+// WithSQLite stores events in the SQLite file at path
+func WithSQLite(path string) Option {
+	return func(s *IndexerService) {
+		s.config.Storage = StorageSQLite
+		s.config.SQLitePath = path
+	}
+}
+
+// WithPostgres stores events in a PostgreSQL database
+func WithPostgres(host, port, user, password, dbName string) Option {
+	return func(s *IndexerService) {
+		s.config.Storage = StoragePostgres
+		s.config.PgHost = host
+		s.config.PgPort = port
+		s.config.PgUser = user
+		s.config.PgPassword = password
+		s.config.PgDbName = dbName
+	}
+}
+
+// WithPublisher publishes every stored event to publisher, see SetPublisher
+func WithPublisher(publisher Publisher) Option {
+	return func(s *IndexerService) { s.publisher = publisher }
+}
+
+// WithHook runs hook in the transaction of every write and rollback
+func WithHook(hook WriteHook) Option {
+	return func(s *IndexerService) { s.hooks = append(s.hooks, hook) }
+}
+
+// New creates an indexer to embed in another program. It starts from LoadConfig,
+// so environment variables apply unless an option overrides them.
+// Register handlers with OnEvent, then call Start and cancel its context to stop it.
+func New(opts ...Option) (*IndexerService, error) {
+	s := NewIndexerService(LoadConfig())
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if !common.IsHexAddress(s.config.ContractAddr) {
+		return nil, fmt.Errorf("invalid contract address %q", s.config.ContractAddr)
+	}
+	if s.config.Storage != StoragePostgres && s.config.Storage != StorageSQLite {
+		return nil, fmt.Errorf("unsupported storage %q", s.config.Storage)
+	}
+	return s, nil
+}
+
+// DecodedEvent is a stored event handed to the handlers registered with OnEvent
+type DecodedEvent struct {
+	Name        string // Event name, empty when no ABI matched the signature
+	Signature   string // Full signature like Transfer(address,address,uint256), empty when no ABI matched
+	Contract    common.Address
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time // Zero unless the node returns block times with logs or Rollups is on
+	TxHash      common.Hash
+	TxIndex     uint
+	LogIndex    uint
+	Topics      []common.Hash          // Every topic, the signature hash first
+	Data        []byte                 // Unindexed log data
+	Params      map[string]interface{} // Decoded parameters, numbers are json.Number to keep uint256 exact
+	params      json.RawMessage
+}
+
+// DecodeParams unmarshals the decoded parameters into v, a struct with json tags named after the ABI inputs
+func (e *DecodedEvent) DecodeParams(v interface{}) error {
+	return json.Unmarshal(e.params, v)
+}
+
+func newDecodedEvent(event *BlockchainEvent) (DecodedEvent, error) {
+	decoded := DecodedEvent{
+		Contract:    common.HexToAddress(event.ContractAddress),
+		BlockNumber: event.BlockNumber,
+		BlockHash:   common.HexToHash(event.BlockHash),
+		BlockTime:   event.BlockTime,
+		TxHash:      common.HexToHash(event.TxHash),
+		TxIndex:     event.TxIndex,
+		LogIndex:    event.LogIndex,
+		params:      event.DecodedParams,
+	}
+	if event.EventName != nil {
+		decoded.Name = *event.EventName
+	}
+	if event.EventFullSignature != nil {
+		decoded.Signature = *event.EventFullSignature
+	}
+	if event.EventSignature != "" {
+		decoded.Topics = append(decoded.Topics, common.HexToHash(event.EventSignature))
+	}
+	for _, topic := range event.OtherTopics {
+		decoded.Topics = append(decoded.Topics, common.HexToHash(topic))
+	}
+
+	var err error
+	if decoded.Data, err = hex.DecodeString(strings.TrimPrefix(event.RawData, "0x")); err != nil {
+		return decoded, fmt.Errorf("invalid data of event %s:%d: %w", event.TxHash, event.LogIndex, err)
+	}
+	if len(decoded.params) == 0 {
+		decoded.params = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(decoded.params))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded.Params); err != nil {
+		return decoded, fmt.Errorf("invalid parameters of event %s:%d: %w", event.TxHash, event.LogIndex, err)
+	}
+	return decoded, nil
+}
+
+// EventHandler handles a stored event inside its write transaction, an error rolls the whole write back
+type EventHandler func(ctx context.Context, event DecodedEvent) error
+
+// RollbackHandler handles a reorg inside the transaction deleting the events from fromBlock on
+type RollbackHandler func(ctx context.Context, fromBlock uint64) error
+
+// txKey carries the write transaction in the context of a handler
+type txKey struct{}
+
+// Tx returns the write transaction a handler runs in, writes through it commit or roll back with the events.
+// It returns nil outside of a handler.
+func Tx(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txKey{}).(*gorm.DB)
+	return tx
+}
+
+// eventHandler is a handler registered for an event name
+type eventHandler struct {
+	name    string
+	handler EventHandler
+}
+
+// eventHandlers calls the registered handlers in the write transaction, in event and then registration order
+type eventHandlers struct {
+	ctx      context.Context // Context of Start, without its cancellation so a write in flight completes
+	events   []eventHandler
+	rollback []RollbackHandler
+}
+
+func (h *eventHandlers) context(tx *gorm.DB) context.Context {
+	ctx := h.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func (h *eventHandlers) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	if len(h.events) == 0 {
+		return nil
+	}
+
+	ctx := h.context(tx)
+	for i := range events {
+		var decoded *DecodedEvent
+		for _, registered := range h.events {
+			if registered.name != AllEvents && (events[i].EventName == nil || *events[i].EventName != registered.name) {
+				continue
+			}
+			if decoded == nil {
+				event, err := newDecodedEvent(&events[i])
+				if err != nil {
+					return err
+				}
+				decoded = &event
+			}
+			if err := registered.handler(ctx, *decoded); err != nil {
+				return fmt.Errorf("handler of %s failed on %s:%d: %w", registered.name, events[i].TxHash, events[i].LogIndex, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *eventHandlers) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	ctx := h.context(tx)
+	for _, handler := range h.rollback {
+		if err := handler(ctx, fromBlock); err != nil {
+			return fmt.Errorf("rollback handler failed from block %d: %w", fromBlock, err)
+		}
+	}
+	return nil
+}
+
+// OnEvent calls handler for every stored event called name, AllEvents matches every event.
+// Handlers run inside the write transaction, Tx(ctx) returns it. Register them before Start.
+func (s *IndexerService) OnEvent(name string, handler EventHandler) {
+	s.handlers.events = append(s.handlers.events, eventHandler{name: name, handler: handler})
+}
+
+// OnRollback calls handler when a reorg removes the events from a block on, inside the same transaction.
+// Handlers keeping state derived from events undo it here. Register them before Start.
+func (s *IndexerService) OnRollback(handler RollbackHandler) {
+	s.handlers.rollback = append(s.handlers.rollback, handler)
+}
@@ -0,0 +1,91 @@
+package eventsdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// countRows returns the rows of table and fails the test on an error
+func countRows(t *testing.T, s *IndexerService, table string) int64 {
+	t.Helper()
+	var count int64
+	if err := s.db.Table(table).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestEventHandlersTransaction(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	s, err := New(WithConfig(testSQLiteConfig(t)))
+	if err != nil {
+		t.Fatalf("failed to create the indexer: %v", err)
+	}
+	if err := s.ensureDatabase(); err != nil {
+		t.Fatalf("failed to open the database: %v", err)
+	}
+	t.Cleanup(func() { s.sink.Close() })
+	if err := s.db.Exec("CREATE TABLE transfer_values (block_number integer, value text)").Error; err != nil {
+		t.Fatalf("failed to create the handler table: %v", err)
+	}
+
+	// The handler keeps a table of transfer values through the write transaction
+	failAt := uint64(0)
+	s.OnEvent("Transfer", func(ctx context.Context, event DecodedEvent) error {
+		if event.BlockNumber == failAt {
+			return errors.New("handler failed")
+		}
+		var transfer struct {
+			Value string `json:"value"`
+		}
+		if err := event.DecodeParams(&transfer); err != nil {
+			return err
+		}
+		return Tx(ctx).Exec("INSERT INTO transfer_values VALUES (?, ?)", event.BlockNumber, transfer.Value).Error
+	})
+	failRollback := false
+	s.OnRollback(func(ctx context.Context, fromBlock uint64) error {
+		if err := Tx(ctx).Exec("DELETE FROM transfer_values WHERE block_number >= ?", fromBlock).Error; err != nil {
+			return err
+		}
+		if failRollback {
+			return errors.New("rollback handler failed")
+		}
+		return nil
+	})
+	if Tx(context.Background()) != nil {
+		t.Fatal("Tx returned a transaction outside of a handler")
+	}
+
+	writeTestRange(t, s.sink, 100, 104, testTransfers(t, &sig, 100, 104, 2))
+	if rows := countRows(t, s, "transfer_values"); rows != 10 {
+		t.Fatalf("handler stored %d values, want 10", rows)
+	}
+
+	// A handler error rolls back the events, the cursor and what earlier handler calls wrote
+	failAt = 107
+	if err := s.sink.WriteRange(105, 109, testBlockHash(109).Hex(), testTransfers(t, &sig, 105, 109, 2)); err == nil {
+		t.Fatal("write succeeded although the handler failed")
+	}
+	if events, rows := len(storedEvents(t, s.db)), countRows(t, s, "transfer_values"); events != 10 || rows != 10 {
+		t.Fatalf("failed write left %d events and %d handler values, want 10 of each", events, rows)
+	}
+	assertCursor(t, s.sink, 104, testBlockHash(104).Hex())
+
+	// Rollback handlers run in the rollback transaction, their error keeps the events too
+	failRollback = true
+	if err := s.sink.Rollback(103); err == nil {
+		t.Fatal("rollback succeeded although the handler failed")
+	}
+	if events, rows := len(storedEvents(t, s.db)), countRows(t, s, "transfer_values"); events != 10 || rows != 10 {
+		t.Fatalf("failed rollback left %d events and %d handler values, want 10 of each", events, rows)
+	}
+	failRollback = false
+	if err := s.sink.Rollback(103); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if events, rows := len(storedEvents(t, s.db)), countRows(t, s, "transfer_values"); events != 6 || rows != 6 {
+		t.Fatalf("rollback left %d events and %d handler values, want 6 of each", events, rows)
+	}
+}
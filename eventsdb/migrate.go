@@ -0,0 +1,286 @@
+package eventsdb
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the versioned schema migrations, one directory per storage backend
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFilePattern matches migration scripts like 0001_baseline.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// schemaMigrationsDDL creates the table recording the applied migrations
+const schemaMigrationsDDL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at timestamp NOT NULL
+)`
+
+// migration is one schema version with the SQL that applies and reverts it
+type migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrationSettings are the configuration values migration scripts are rendered with
+type migrationSettings struct {
+	Partitioned bool // blockchain_events is partitioned by block_number range
+}
+
+// MigrationStatus reports whether a known migration is applied
+type MigrationStatus struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// loadMigrations renders the migrations of a storage backend ordered by version
+func loadMigrations(storage string, settings migrationSettings) ([]migration, error) {
+	dir := path.Join("migrations", storage)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for storage %q: %w", storage, err)
+	}
+
+	byVersion := make(map[uint]*migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.ParseUint(match[1], 10, 64)
+
+		script, err := renderMigration(path.Join(dir, entry.Name()), settings)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if match[3] == "up" {
+			m.Up = script
+		} else {
+			m.Down = script
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// renderMigration executes a migration script as a text template
+func renderMigration(name string, settings migrationSettings) (string, error) {
+	data, err := migrationFiles.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration %s: %w", name, err)
+	}
+
+	tmpl, err := template.New(path.Base(name)).Parse(string(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse migration %s: %w", name, err)
+	}
+
+	var script bytes.Buffer
+	if err := tmpl.Execute(&script, settings); err != nil {
+		return "", fmt.Errorf("failed to render migration %s: %w", name, err)
+	}
+	return script.String(), nil
+}
+
+// appliedMigrations returns the recorded migrations by version, creating schema_migrations if needed
+func appliedMigrations(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := db.Exec(schemaMigrationsDDL).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	applied := make(map[uint]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// pendingMigrations returns the migrations not applied yet.
+// It fails when the database has a migration this binary does not know, since its schema is ahead.
+func pendingMigrations(db *gorm.DB, migrations []migration) ([]migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[uint]bool, len(migrations))
+	var pending []migration
+	for _, m := range migrations {
+		known[m.Version] = true
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+
+	for version, record := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database schema has migration %04d_%s which this binary does not know, upgrade eventsdb", version, record.Name)
+		}
+	}
+
+	return pending, nil
+}
+
+// migrateUp applies the pending migrations in version order, each in its own transaction
+func migrateUp(db *gorm.DB, migrations []migration) (int, error) {
+	pending, err := pendingMigrations(db, migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied migration %04d_%s\n", m.Version, m.Name)
+	}
+
+	return len(pending), nil
+}
+
+// migrateDown reverts the latest steps applied migrations, each in its own transaction
+func migrateDown(db *gorm.DB, migrations []migration, steps int) (int, error) {
+	if _, err := pendingMigrations(db, migrations); err != nil {
+		return 0, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return reverted, fmt.Errorf("migration %04d_%s cannot be reverted", m.Version, m.Name)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("failed to revert migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Reverted migration %04d_%s\n", m.Version, m.Name)
+		reverted++
+	}
+
+	return reverted, nil
+}
+
+// migrationStatus lists every known migration with the time it was applied
+func migrationStatus(db *gorm.DB, migrations []migration) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// openMigrations opens the database without migrating it and loads the migrations of the storage backend
+func (s *IndexerService) openMigrations() (*gorm.DB, []migration, error) {
+	db, err := openDB(s.config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrations, err := loadMigrations(s.config.Storage, migrationSettings{Partitioned: s.config.PartitionSize > 0})
+	if err != nil {
+		closeDB(db)
+		return nil, nil, err
+	}
+	return db, migrations, nil
+}
+
+// MigrateUp applies every pending schema migration
+func (s *IndexerService) MigrateUp() (int, error) {
+	db, migrations, err := s.openMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer closeDB(db)
+
+	return migrateUp(db, migrations)
+}
+
+// MigrateDown reverts the latest steps applied schema migrations
+func (s *IndexerService) MigrateDown(steps int) (int, error) {
+	db, migrations, err := s.openMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer closeDB(db)
+
+	return migrateDown(db, migrations, steps)
+}
+
+// MigrationStatus lists the schema migrations known to this binary and when they were applied
+func (s *IndexerService) MigrationStatus() ([]MigrationStatus, error) {
+	db, migrations, err := s.openMigrations()
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB(db)
+
+	return migrationStatus(db, migrations)
+}
@@ -0,0 +1,76 @@
+-- Schema of the AutoMigrate releases. IF NOT EXISTS lets databases created by AutoMigrate adopt the migrations.
+{{- if .Partitioned}}
+
+-- Unique keys of a partitioned table must contain the partition key, so idx_tx_log also covers block_number
+CREATE TABLE IF NOT EXISTS blockchain_events (
+	id bigserial NOT NULL,
+	tx_hash varchar(66) NOT NULL,
+	tx_index bigint NOT NULL,
+	block_number bigint NOT NULL,
+	block_hash varchar(66) NOT NULL,
+	log_index bigint NOT NULL,
+	removed boolean NOT NULL DEFAULT false,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	event_name varchar(255) DEFAULT NULL,
+	event_full_signature text DEFAULT NULL,
+	other_topics text[],
+	raw_data text,
+	decoded_params jsonb,
+	global_abi_match boolean NOT NULL DEFAULT false,
+	insert_time timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id, block_number),
+	CONSTRAINT idx_tx_log UNIQUE (tx_hash, log_index, block_number)
+) PARTITION BY RANGE (block_number);
+{{- else}}
+
+CREATE TABLE IF NOT EXISTS blockchain_events (
+	id bigserial PRIMARY KEY,
+	tx_hash varchar(66) NOT NULL,
+	tx_index bigint NOT NULL,
+	block_number bigint NOT NULL,
+	block_hash varchar(66) NOT NULL,
+	log_index bigint NOT NULL,
+	removed boolean NOT NULL DEFAULT false,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	event_name varchar(255) DEFAULT NULL,
+	event_full_signature text DEFAULT NULL,
+	other_topics text[],
+	raw_data text,
+	decoded_params jsonb,
+	global_abi_match boolean NOT NULL DEFAULT false,
+	insert_time timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tx_log ON blockchain_events (tx_hash, log_index);
+{{- end}}
+
+CREATE TABLE IF NOT EXISTS abi_event_records (
+	id bigserial PRIMARY KEY,
+	event_signature_hash text,
+	event_name text,
+	abi_event_json text,
+	source_file text
+);
+
+CREATE TABLE IF NOT EXISTS cursors (
+	id bigserial PRIMARY KEY,
+	count bigint,
+	block_hash varchar(66)
+);
+
+-- Columns added after the first AutoMigrate release
+ALTER TABLE blockchain_events ADD COLUMN IF NOT EXISTS global_abi_match boolean NOT NULL DEFAULT false;
+ALTER TABLE abi_event_records ADD COLUMN IF NOT EXISTS source_file text;
+ALTER TABLE cursors ADD COLUMN IF NOT EXISTS block_hash varchar(66);
+
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_block_number ON blockchain_events (block_number);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_block_hash ON blockchain_events (block_hash);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_contract_address ON blockchain_events (contract_address);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_event_signature ON blockchain_events (event_signature);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_event_name ON blockchain_events (event_name);
+
+-- ABI events used to be unique per hash, they are now unique per hash and source file
+DROP INDEX IF EXISTS idx_abi_event_records_event_signature_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_abi_sig_source ON abi_event_records (event_signature_hash, source_file);
@@ -0,0 +1,6 @@
+-- Position of resumable exports, see eventsdb export -cursor
+CREATE TABLE export_cursors (
+	name varchar(255) PRIMARY KEY,
+	block_number bigint NOT NULL,
+	updated_at timestamp NOT NULL
+);
@@ -0,0 +1,28 @@
+-- Webhooks and their delivery outbox, rows are queued in the write transaction, see eventsdb/webhook.go.
+CREATE TABLE webhooks (
+	id bigserial PRIMARY KEY,
+	name varchar(255) NOT NULL UNIQUE,
+	url text NOT NULL,
+	secret text NOT NULL,
+	contract_address varchar(42) NOT NULL DEFAULT '',
+	event_names text NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+	id bigserial PRIMARY KEY,
+	webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+	kind varchar(16) NOT NULL,
+	block_number bigint NOT NULL,
+	log_index bigint NOT NULL DEFAULT 0,
+	payload text NOT NULL,
+	status varchar(16) NOT NULL,
+	attempts integer NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz NOT NULL,
+	last_error text NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL,
+	delivered_at timestamptz
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, status, block_number);
@@ -0,0 +1 @@
+DROP TABLE broker_messages;
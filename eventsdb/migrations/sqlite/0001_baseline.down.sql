@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS cursors;
+DROP TABLE IF EXISTS abi_event_records;
+DROP TABLE IF EXISTS blockchain_events;
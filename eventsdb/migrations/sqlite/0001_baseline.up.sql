@@ -0,0 +1,43 @@
+-- Schema of the AutoMigrate releases. IF NOT EXISTS lets databases created by AutoMigrate adopt the migrations.
+
+CREATE TABLE IF NOT EXISTS blockchain_events (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	tx_hash varchar(66) NOT NULL,
+	tx_index integer NOT NULL,
+	block_number integer NOT NULL,
+	block_hash varchar(66) NOT NULL,
+	log_index integer NOT NULL,
+	removed numeric NOT NULL DEFAULT false,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	event_name varchar(255) DEFAULT NULL,
+	event_full_signature text DEFAULT NULL,
+	other_topics text[],
+	raw_data text,
+	decoded_params jsonb,
+	global_abi_match numeric NOT NULL DEFAULT false,
+	insert_time datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tx_log ON blockchain_events (tx_hash, log_index);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_block_number ON blockchain_events (block_number);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_block_hash ON blockchain_events (block_hash);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_contract_address ON blockchain_events (contract_address);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_event_signature ON blockchain_events (event_signature);
+CREATE INDEX IF NOT EXISTS idx_blockchain_events_event_name ON blockchain_events (event_name);
+
+CREATE TABLE IF NOT EXISTS abi_event_records (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	event_signature_hash text,
+	event_name text,
+	abi_event_json text,
+	source_file text
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_abi_sig_source ON abi_event_records (event_signature_hash, source_file);
+
+CREATE TABLE IF NOT EXISTS cursors (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	count integer,
+	block_hash varchar(66)
+);
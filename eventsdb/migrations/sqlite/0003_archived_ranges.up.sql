@@ -0,0 +1,15 @@
+-- Event ranges moved to archive files by retention rules, see eventsdb retention
+CREATE TABLE archived_ranges (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	rule varchar(255) NOT NULL,
+	contract_address varchar(42) NOT NULL DEFAULT '',
+	event_name varchar(255) NOT NULL DEFAULT '',
+	from_block bigint NOT NULL,
+	to_block bigint NOT NULL,
+	events bigint NOT NULL,
+	file text NOT NULL,
+	archived_at timestamp NOT NULL,
+	restored_at timestamp
+);
+
+CREATE INDEX idx_archived_ranges_blocks ON archived_ranges (from_block, to_block);
@@ -0,0 +1,3 @@
+DROP TABLE event_counts_daily;
+DROP TABLE event_counts_hourly;
+DROP TABLE event_counts_block;
@@ -0,0 +1,32 @@
+-- Event counts kept by the indexer in the write transaction, see eventsdb/rollup.go.
+-- Hashes and addresses are hex text whatever BINARY_STORAGE says, names come from abi_event_records.
+CREATE TABLE event_counts_block (
+	block_number bigint NOT NULL,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	block_time timestamp NOT NULL,
+	events bigint NOT NULL,
+	PRIMARY KEY (block_number, contract_address, event_signature)
+);
+
+CREATE INDEX idx_event_counts_block_time ON event_counts_block (block_time);
+
+CREATE TABLE event_counts_hourly (
+	bucket timestamp NOT NULL,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	events bigint NOT NULL,
+	first_block bigint NOT NULL,
+	last_block bigint NOT NULL,
+	PRIMARY KEY (bucket, contract_address, event_signature)
+);
+
+CREATE TABLE event_counts_daily (
+	bucket timestamp NOT NULL,
+	contract_address varchar(42) NOT NULL,
+	event_signature varchar(66) NOT NULL,
+	events bigint NOT NULL,
+	first_block bigint NOT NULL,
+	last_block bigint NOT NULL,
+	PRIMARY KEY (bucket, contract_address, event_signature)
+);
@@ -0,0 +1,15 @@
+-- Message broker outbox, rows are queued in the write transaction, see eventsdb/broker.go.
+-- Published events are kept until their block is below the reorg depth so a rollback can retract them.
+CREATE TABLE broker_messages (
+	id integer PRIMARY KEY AUTOINCREMENT,
+	kind varchar(16) NOT NULL,
+	message_key varchar(255) NOT NULL,
+	event_id varchar(100) NOT NULL,
+	block_number bigint NOT NULL,
+	payload text NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL,
+	published_at timestamp
+);
+
+CREATE INDEX idx_broker_messages_pending ON broker_messages (published_at, id);
+CREATE INDEX idx_broker_messages_block ON broker_messages (block_number);
@@ -1,51 +1,78 @@
 package eventsdb
 
 import (
+	"bytes"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// BlockchainEvent model stores all blockchain event data
+// BlockchainEvent model stores all blockchain event data.
+// Nullable columns have no gorm default, a batch mixing NULL and values would insert DEFAULT, which SQLite rejects.
 type BlockchainEvent struct {
 	ID                 uint            `gorm:"primaryKey"`
-	TxHash             string          `gorm:"not null;type:varchar(66);uniqueIndex:idx_tx_log"` // Keccak hash of the transaction
-	TxIndex            uint            `gorm:"not null"`                                         // Transaction index in the block
-	BlockNumber        uint64          `gorm:"not null;index"`                                   // Block number
-	BlockHash          string          `gorm:"not null;type:varchar(66);index"`                  // Hash of the block
-	LogIndex           uint            `gorm:"not null;uniqueIndex:idx_tx_log"`                  // Index in the block's log array
-	Removed            bool            `gorm:"not null;default:false"`                           // True if log was removed due to chain reorg
-	ContractAddress    string          `gorm:"not null;type:varchar(42);index"`                  // Address of the contract
-	EventSignature     string          `gorm:"not null;type:varchar(66);index"`                  // Keccak of the event signature
-	EventName          *string         `gorm:"type:varchar(255);index;default:NULL"`             // Human-readable event name (NULL if unknown)
-	EventFullSignature *string         `gorm:"type:text;default:NULL"`                           // Full event signature (NULL if unknown)
-	OtherTopics        StringArray     `gorm:"type:text[]"`                                      // Additional event topics
-	RawData            string          `gorm:"type:text"`                                        // Hex-encoded unindexed log data
-	DecodedParams      json.RawMessage `gorm:"type:jsonb"`                                       // Decoded event parameters
-	InsertTime         time.Time       `gorm:"not null;default:now()"`                           // When this record was inserted
+	TxHash             string          `gorm:"not null;type:varchar(66);uniqueIndex:idx_tx_log;serializer:hash"` // Keccak hash of the transaction
+	TxIndex            uint            `gorm:"not null"`                                                         // Transaction index in the block
+	BlockNumber        uint64          `gorm:"not null;index"`                                                   // Block number
+	BlockHash          string          `gorm:"not null;type:varchar(66);index;serializer:hash"`                  // Hash of the block
+	LogIndex           uint            `gorm:"not null;uniqueIndex:idx_tx_log"`                                  // Index in the block's log array
+	Removed            bool            `gorm:"not null;default:false"`                                           // True if log was removed due to chain reorg
+	ContractAddress    string          `gorm:"not null;type:varchar(42);index;serializer:address"`               // Address of the contract
+	EventSignature     string          `gorm:"not null;type:varchar(66);index;serializer:hash"`                  // Keccak of the event signature
+	EventName          *string         `gorm:"type:varchar(255);index"`                                          // Human-readable event name (NULL if unknown)
+	EventFullSignature *string         `gorm:"type:text"`                                                        // Full event signature (NULL if unknown)
+	OtherTopics        StringArray     `gorm:"type:text[]"`                                                      // Additional event topics
+	RawData            string          `gorm:"type:text;serializer:hexdata"`                                     // Hex-encoded unindexed log data
+	DecodedParams      json.RawMessage `gorm:"type:jsonb"`                                                       // Decoded event parameters
+	GlobalABIMatch     bool            `gorm:"not null;default:false"`                                           // True if decoded with the global ABI fallback instead of an ABI bound to the contract
+	InsertTime         time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP"`                               // When this record was inserted
+	BlockTime          time.Time       `gorm:"-"`                                                                // Timestamp of the block, only set on events fetched from the chain
 }
 
-// StringArray handles PostgreSQL string arrays
+// StringArray handles PostgreSQL string arrays, bytea arrays are scanned as 0x prefixed hex.
+// SQLite stores the same array literal as text.
 type StringArray []string
 
+// pgTypes encodes and parses array literals, arrays are read and written in the text format
+var pgTypes = pgtype.NewMap()
+
 func (sa *StringArray) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("scan source is not []byte")
+	var src []byte
+	switch v := value.(type) {
+	case nil:
+		*sa = nil
+		return nil
+	case []byte:
+		src = v
+	case string:
+		src = []byte(v)
+	default:
+		return errors.New("scan source is not []byte or string")
 	}
 
-	str := string(bytes)
-	str = strings.Trim(str, "{}")
-
-	if str == "" {
-		*sa = []string{}
+	// Every element of a bytea array is written as "\\x0a", the hex text of topics never is
+	if bytes.HasPrefix(src, []byte(`{"\\x`)) {
+		var elements [][]byte
+		if err := pgTypes.Scan(pgtype.ByteaArrayOID, pgtype.TextFormatCode, src, &elements); err != nil {
+			return fmt.Errorf("failed to parse bytea array: %w", err)
+		}
+		*sa = make(StringArray, 0, len(elements))
+		for _, element := range elements {
+			*sa = append(*sa, hexutil.Encode(element))
+		}
 		return nil
 	}
 
-	*sa = strings.Split(str, ",")
+	var elements []string
+	if err := pgTypes.Scan(pgtype.TextArrayOID, pgtype.TextFormatCode, src, &elements); err != nil {
+		return fmt.Errorf("failed to parse text array: %w", err)
+	}
+	*sa = elements
 	return nil
 }
 
@@ -53,19 +80,115 @@ func (sa StringArray) Value() (driver.Value, error) {
 	if sa == nil {
 		return "{}", nil
 	}
-	return fmt.Sprintf("{%s}", strings.Join(sa, ",")), nil
+	literal, err := pgTypes.Encode(pgtype.TextArrayOID, pgtype.TextFormatCode, []string(sa), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode text array: %w", err)
+	}
+	return string(literal), nil
 }
 
 // ABIEventRecord model stores ABI events json format
 type ABIEventRecord struct {
 	ID                 uint   `gorm:"primaryKey"`
-	EventSignatureHash string `gorm:"uniqueIndex"`
+	EventSignatureHash string `gorm:"uniqueIndex:idx_abi_sig_source"`
 	EventName          string
 	ABIEventJSON       string
+	SourceFile         string `gorm:"uniqueIndex:idx_abi_sig_source"` // ABI file, relative to AbiDir, or upload the event was loaded from
 }
 
 // Coursor count Number of processed block
 type Cursor struct {
-	ID    uint `gorm:"primaryKey"`
-	Count int
+	ID        uint `gorm:"primaryKey"`
+	Count     int
+	BlockHash string `gorm:"type:varchar(66)"` // Hash of the processed block, empty after a rollback
+}
+
+// SchemaMigration records a schema migration applied to the database
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// ExportCursor is the last block written by a resumable export
+type ExportCursor struct {
+	Name        string `gorm:"primaryKey"`
+	BlockNumber uint64
+	UpdatedAt   time.Time
+}
+
+// ArchivedRange is a block range of events moved to an archive file by a retention rule
+type ArchivedRange struct {
+	ID              uint   `gorm:"primaryKey"`
+	Rule            string `gorm:"not null"`
+	ContractAddress string // Contract of the rule, empty for every contract
+	EventName       string // Event of the rule, empty for every event
+	FromBlock       uint64 `gorm:"not null"`
+	ToBlock         uint64 `gorm:"not null"`
+	Events          int64  `gorm:"not null"`
+	File            string `gorm:"not null"` // gzip JSON Lines file holding the events
+	ArchivedAt      time.Time
+	RestoredAt      *time.Time // Set once the events were imported back
+}
+
+// BlockEventCount is the number of events of a contract and signature in one block
+type BlockEventCount struct {
+	BlockNumber     uint64 `gorm:"primaryKey;autoIncrement:false"`
+	ContractAddress string `gorm:"primaryKey"`
+	EventSignature  string `gorm:"primaryKey"`
+	BlockTime       time.Time
+	Events          int64
+}
+
+func (BlockEventCount) TableName() string {
+	return "event_counts_block"
+}
+
+// PeriodEventCount is the number of events of a contract and signature in one hour or day, see event_counts_hourly and event_counts_daily
+type PeriodEventCount struct {
+	Bucket          time.Time `gorm:"primaryKey"` // Start of the hour or day in UTC
+	ContractAddress string    `gorm:"primaryKey"`
+	EventSignature  string    `gorm:"primaryKey"`
+	Events          int64
+	FirstBlock      uint64
+	LastBlock       uint64
+}
+
+// Webhook posts the events it matches to URL, see eventsdb webhooks
+type Webhook struct {
+	ID              uint   `gorm:"primaryKey"`
+	Name            string `gorm:"not null;uniqueIndex"`
+	URL             string `gorm:"not null"`
+	Secret          string `gorm:"not null" json:"-"` // HMAC-SHA256 key of the X-Eventsdb-Signature header
+	ContractAddress string // Checksummed contract, empty for every contract
+	EventNames      string // Comma separated event names, empty for every event
+	CreatedAt       time.Time
+}
+
+// WebhookDelivery is a payload queued for a webhook in the write transaction of its event
+type WebhookDelivery struct {
+	ID            uint   `gorm:"primaryKey"`
+	WebhookID     uint   `gorm:"not null;index"`
+	Kind          string `gorm:"not null"` // event or rollback
+	BlockNumber   uint64 `gorm:"not null"`
+	LogIndex      uint   `gorm:"not null"`
+	Payload       string `gorm:"not null"` // Posted as is, the signature covers these bytes
+	Status        string `gorm:"not null"` // pending, delivered or dead
+	Attempts      int    `gorm:"not null"`
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+}
+
+// BrokerMessage is an event or tombstone queued for the message broker in the write transaction of its event
+type BrokerMessage struct {
+	ID          uint   `gorm:"primaryKey"`
+	Kind        string `gorm:"not null"` // event or tombstone
+	MessageKey  string `gorm:"not null"` // Contract address or event name, see BrokerKey
+	EventID     string `gorm:"not null"` // Tx hash and log index of the event, joined by a colon
+	BlockNumber uint64 `gorm:"not null"`
+	Payload     string // EventRecord JSON, a tombstone has the record of its event with removed set
+	CreatedAt   time.Time
+	PublishedAt *time.Time
 }
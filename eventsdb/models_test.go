@@ -0,0 +1,45 @@
+package eventsdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringArrayScan(t *testing.T) {
+	tests := []struct {
+		src  string
+		want StringArray
+	}{
+		{`{}`, StringArray{}},
+		{`{0xab,0xcd}`, StringArray{"0xab", "0xcd"}},
+		{`{"a,b","c\"d"}`, StringArray{"a,b", `c"d`}},
+		// bytea[] columns of binary storage
+		{`{"\\x0a0b","\\x"}`, StringArray{"0x0a0b", "0x"}},
+	}
+	for _, test := range tests {
+		var got StringArray
+		if err := got.Scan([]byte(test.src)); err != nil {
+			t.Fatalf("scanning %s failed: %v", test.src, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s scanned as %#v, want %#v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestStringArrayRoundTrip(t *testing.T) {
+	for _, array := range []StringArray{nil, {}, {"0xab"}, {"a,b", `c"d`, `e\f`, "{g}", ""}} {
+		value, err := array.Value()
+		if err != nil {
+			t.Fatalf("encoding %#v failed: %v", array, err)
+		}
+
+		var got StringArray
+		if err := got.Scan(value); err != nil {
+			t.Fatalf("scanning %v failed: %v", value, err)
+		}
+		if len(got) != len(array) || (len(array) > 0 && !reflect.DeepEqual(got, array)) {
+			t.Fatalf("%#v came back as %#v through %v", array, got, value)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// maxNotifyPayload is the largest NOTIFY payload PostgreSQL accepts, less a margin
+const maxNotifyPayload = 7_900
+
+// Notification types
+const (
+	NotifyWrite    = "write"
+	NotifyRollback = "rollback"
+)
+
+// Notification is the payload NOTIFY sends on NotifyChannel once a write or rollback committed
+type Notification struct {
+	Type       string         `json:"type"` // write or rollback
+	FromBlock  uint64         `json:"fromBlock"`
+	ToBlock    uint64         `json:"toBlock,omitempty"`    // Last block of a write, the cursor moved to it
+	Events     int            `json:"events,omitempty"`     // Events stored by a write
+	EventNames map[string]int `json:"eventNames,omitempty"` // Events per name, undecoded events under their signature
+	Truncated  bool           `json:"truncated,omitempty"`  // EventNames was left out to fit the payload limit
+}
+
+// notifier publishes every write and rollback with NOTIFY, PostgreSQL only delivers it when the transaction commits
+type notifier struct {
+	channel string
+}
+
+func (n notifier) notify(tx *gorm.DB, notification Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		notification.EventNames = nil
+		notification.Truncated = true
+		if payload, err = json.Marshal(notification); err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+	}
+
+	if err := tx.Exec("SELECT pg_notify(?, ?)", n.channel, string(payload)).Error; err != nil {
+		return fmt.Errorf("failed to notify %s: %w", n.channel, err)
+	}
+	return nil
+}
+
+func (n notifier) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	notification := Notification{Type: NotifyWrite, FromBlock: fromBlock, ToBlock: toBlock, Events: len(events)}
+	for i := range events {
+		name := events[i].EventSignature
+		if events[i].EventName != nil {
+			name = *events[i].EventName
+		}
+		if notification.EventNames == nil {
+			notification.EventNames = make(map[string]int)
+		}
+		notification.EventNames[name]++
+	}
+	return n.notify(tx, notification)
+}
+
+func (n notifier) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	return n.notify(tx, Notification{Type: NotifyRollback, FromBlock: fromBlock})
+}
+
+// NotificationListener receives the notifications of an indexer, see ListenNotifications
+type NotificationListener struct {
+	C    <-chan Notification // Closed when the context is done or the connection fails
+	err  error
+	done chan struct{}
+}
+
+// ListenNotifications runs LISTEN on channel over its own connection and delivers the notifications on C.
+// Notifications sent while no listener is connected are lost, read the cursor after connecting to catch up.
+func ListenNotifications(ctx context.Context, connString, channel string) (*NotificationListener, error) {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	notifications := make(chan Notification, 64)
+	listener := &NotificationListener{C: notifications, done: make(chan struct{})}
+	go func() {
+		defer close(listener.done)
+		defer close(notifications)
+		defer conn.Close(context.Background())
+
+		for {
+			received, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					listener.err = fmt.Errorf("failed to wait for notification: %w", err)
+				}
+				return
+			}
+
+			var notification Notification
+			if err := json.Unmarshal([]byte(received.Payload), &notification); err != nil {
+				log.Printf("Skipping notification on %s: %v\n", channel, err)
+				continue
+			}
+			select {
+			case notifications <- notification:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return listener, nil
+}
+
+// Err returns why C was closed, nil when the context was done. It waits for C to be closed.
+func (l *NotificationListener) Err() error {
+	<-l.done
+	return l.err
+}
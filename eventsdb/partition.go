@@ -0,0 +1,186 @@
+package eventsdb
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// partitionBoundPattern extracts the bounds of a range partition from pg_get_expr
+var partitionBoundPattern = regexp.MustCompile(`FROM \('?(\d+)'?\) TO \('?(\d+)'?\)`)
+
+// EventPartition is one block range partition of blockchain_events
+type EventPartition struct {
+	Name      string `json:"name"`
+	FromBlock uint64 `json:"fromBlock"` // Inclusive
+	ToBlock   uint64 `json:"toBlock"`   // Exclusive
+}
+
+// checkEventsPartitioning verifies blockchain_events is partitioned exactly when PARTITION_SIZE is set.
+// The migrations only create the table, converting an existing one means rewriting every row.
+func checkEventsPartitioning(db *gorm.DB, partitioned bool) error {
+	var relkind string
+	err := db.Raw("SELECT relkind FROM pg_class WHERE relname = 'blockchain_events' AND relnamespace = 'public'::regnamespace").Scan(&relkind).Error
+	if err != nil {
+		return fmt.Errorf("failed to inspect blockchain_events: %w", err)
+	}
+
+	switch {
+	case partitioned && relkind != "p":
+		return fmt.Errorf("blockchain_events already exists without partitions, unset PARTITION_SIZE or migrate the table first")
+	case !partitioned && relkind == "p":
+		return fmt.Errorf("blockchain_events is partitioned, set PARTITION_SIZE to its partition size")
+	}
+	return nil
+}
+
+// partitionName names the partition starting at fromBlock, zero padded so names sort by block
+func partitionName(fromBlock uint64) string {
+	return fmt.Sprintf("blockchain_events_p%012d", fromBlock)
+}
+
+// detachedPartitionSuffix is appended to the name of a detached partition,
+// so its table is not mistaken for the partition of its blocks
+const detachedPartitionSuffix = "_detached"
+
+// ensurePartitions creates the partitions covering [fromBlock, toBlock] that are not in created yet.
+// Attached partitions are looked up in pg_inherits, blocks whose partition was detached are refused.
+func ensurePartitions(db *gorm.DB, partitionSize, fromBlock, toBlock uint64, created map[uint64]bool) error {
+	var attached map[uint64]bool
+	for start := fromBlock - fromBlock%partitionSize; start <= toBlock; start += partitionSize {
+		if created[start] {
+			continue
+		}
+
+		if attached == nil {
+			partitions, err := listPartitions(db)
+			if err != nil {
+				return err
+			}
+			attached = make(map[uint64]bool, len(partitions))
+			for _, partition := range partitions {
+				attached[partition.FromBlock] = true
+			}
+		}
+		if attached[start] {
+			created[start] = true
+			continue
+		}
+
+		if err := checkDetachedPartition(db, start, start+partitionSize); err != nil {
+			return err
+		}
+		err := db.Exec(fmt.Sprintf("CREATE TABLE %s PARTITION OF blockchain_events FOR VALUES FROM (%d) TO (%d)",
+			partitionName(start), start, start+partitionSize)).Error
+		if err != nil {
+			return fmt.Errorf("failed to create partition for blocks %d to %d: %w", start, start+partitionSize-1, err)
+		}
+		created[start] = true
+	}
+
+	return nil
+}
+
+// checkDetachedPartition fails when the partition of [fromBlock, toBlock) was detached and its table still exists.
+// Writing to those blocks would split their events between the detached table and a new partition.
+func checkDetachedPartition(db *gorm.DB, fromBlock, toBlock uint64) error {
+	var tables []string
+	err := db.Raw("SELECT relname FROM pg_class WHERE relnamespace = 'public'::regnamespace AND relname IN ?",
+		[]string{partitionName(fromBlock), partitionName(fromBlock) + detachedPartitionSuffix}).Scan(&tables).Error
+	if err != nil {
+		return fmt.Errorf("failed to look up detached partitions: %w", err)
+	}
+	if len(tables) > 0 {
+		return fmt.Errorf("blocks %d to %d were detached to table %s, attach it again or drop it before writing to them",
+			fromBlock, toBlock-1, tables[0])
+	}
+	return nil
+}
+
+// listPartitions returns the attached partitions of blockchain_events ordered by block range
+func listPartitions(db *gorm.DB) ([]EventPartition, error) {
+	var rows []struct {
+		Name  string
+		Bound string
+	}
+	err := db.Raw(`SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'blockchain_events'
+		ORDER BY c.relname`).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	partitions := make([]EventPartition, 0, len(rows))
+	for _, row := range rows {
+		match := partitionBoundPattern.FindStringSubmatch(row.Bound)
+		if match == nil {
+			continue
+		}
+		from, _ := strconv.ParseUint(match[1], 10, 64)
+		to, _ := strconv.ParseUint(match[2], 10, 64)
+		partitions = append(partitions, EventPartition{Name: row.Name, FromBlock: from, ToBlock: to})
+	}
+
+	return partitions, nil
+}
+
+// detachPartitionsBefore detaches every partition that only holds blocks below block.
+// Detached partitions are renamed with detachedPartitionSuffix and stay as standalone tables that can be dumped and dropped.
+func detachPartitionsBefore(db *gorm.DB, block uint64) ([]EventPartition, error) {
+	partitions, err := listPartitions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var detached []EventPartition
+	for _, partition := range partitions {
+		if partition.ToBlock > block {
+			continue
+		}
+
+		name := partition.Name + detachedPartitionSuffix
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE blockchain_events DETACH PARTITION " + partition.Name).Error; err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE " + partition.Name + " RENAME TO " + name).Error
+		})
+		if err != nil {
+			return detached, fmt.Errorf("failed to detach partition %s: %w", partition.Name, err)
+		}
+		log.Printf("Detached partition %s to %s (blocks %d to %d)\n", partition.Name, name, partition.FromBlock, partition.ToBlock-1)
+		partition.Name = name
+		detached = append(detached, partition)
+	}
+
+	return detached, nil
+}
+
+// ListPartitions returns the attached block range partitions of blockchain_events
+func (s *IndexerService) ListPartitions() ([]EventPartition, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	if s.config.PartitionSize == 0 {
+		return nil, fmt.Errorf("blockchain_events is not partitioned, set PARTITION_SIZE")
+	}
+	return listPartitions(s.db)
+}
+
+// DetachPartitionsBefore detaches the partitions that only hold blocks below block
+func (s *IndexerService) DetachPartitionsBefore(block uint64) ([]EventPartition, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	sink, ok := s.sink.(*PostgresSink)
+	if !ok || s.config.PartitionSize == 0 {
+		return nil, fmt.Errorf("blockchain_events is not partitioned, set PARTITION_SIZE")
+	}
+	return sink.detachPartitionsBefore(block)
+}
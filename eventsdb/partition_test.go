@@ -0,0 +1,42 @@
+package eventsdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetachedPartitionsRefuseWrites(t *testing.T) {
+	config := testPostgresConfig(t)
+	config.PartitionSize = 25
+	db, sink := openTestSink(t, config)
+	writeTestRange(t, sink, 0, 99, testTransfers(t, nil, 10, 99, 1))
+
+	detached, err := sink.(*PostgresSink).detachPartitionsBefore(50)
+	if err != nil {
+		t.Fatalf("detach failed: %v", err)
+	}
+	if len(detached) != 2 || detached[0].Name != partitionName(0)+detachedPartitionSuffix {
+		t.Fatalf("detached %+v, want the partitions of blocks 0 and 25 renamed", detached)
+	}
+	partitions, err := listPartitions(db)
+	if err != nil || len(partitions) != 2 || partitions[0].FromBlock != 50 {
+		t.Fatalf("attached partitions %+v (error %v), want blocks 50 and 75", partitions, err)
+	}
+
+	// The cached partition of block 10 is gone, the import reports the detached table instead of a missing partition
+	err = sink.Import(testTransfers(t, nil, 10, 10, 1))
+	if err == nil || !strings.Contains(err.Error(), "detached to table "+partitionName(0)+detachedPartitionSuffix) {
+		t.Fatalf("import into a detached partition returned %v", err)
+	}
+
+	// Once the detached table is dropped its blocks can be written again
+	if err := db.Exec("DROP TABLE " + partitionName(0) + detachedPartitionSuffix).Error; err != nil {
+		t.Fatalf("failed to drop the detached partition: %v", err)
+	}
+	if err := sink.Import(testTransfers(t, nil, 10, 10, 1)); err != nil {
+		t.Fatalf("import after dropping the detached partition failed: %v", err)
+	}
+	if stored := storedEvents(t, db); len(stored) != 51 {
+		t.Fatalf("%d events stored, want the 50 attached ones and the import", len(stored))
+	}
+}
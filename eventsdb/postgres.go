@@ -0,0 +1,226 @@
+package eventsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// eventStagingTable receives COPY rows before they are merged into blockchain_events
+const eventStagingTable = "blockchain_events_staging"
+
+// eventCopyColumns are the columns written by COPY, in the order of copyRow
+var eventCopyColumns = []string{"tx_hash", "tx_index", "block_number", "block_hash", "log_index", "removed", "contract_address", "event_signature", "event_name", "event_full_signature", "other_topics", "raw_data", "decoded_params", "global_abi_match"}
+
+// PostgresSink stores events in PostgreSQL through GORM
+type PostgresSink struct {
+	db        *gorm.DB
+	writeMode string
+	batchSize int
+
+	// Range partitioning of blockchain_events, partitionSize 0 means a plain table
+	partitionSize uint64
+	partitionsMu  sync.Mutex
+	partitions    map[uint64]bool // Start blocks of the partitions known to exist
+
+	binary bool // Hashes, addresses, topics and data are stored as bytea
+
+	writeHooks
+}
+
+// NewPostgresSink creates a sink on an open PostgreSQL connection with the write mode, partitioning and storage of config
+func NewPostgresSink(db *gorm.DB, config Config) *PostgresSink {
+	return &PostgresSink{
+		db:            db,
+		writeMode:     config.WriteMode,
+		batchSize:     config.WriteBatchSize,
+		partitionSize: config.PartitionSize,
+		partitions:    make(map[uint64]bool),
+		binary:        config.BinaryStorage,
+	}
+}
+
+func (p *PostgresSink) WriteRange(fromBlock, toBlock uint64, toBlockHash string, events []BlockchainEvent) error {
+	if p.partitionSize > 0 {
+		// Partitions are created in their own statements so a failed write does not forget them
+		p.partitionsMu.Lock()
+		err := ensurePartitions(p.db, p.partitionSize, fromBlock, toBlock, p.partitions)
+		p.partitionsMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.writeMode != WriteModeCopy {
+		return writeRange(p.db, &p.writeHooks, fromBlock, toBlock, toBlockHash, events, p.storeEvents)
+	}
+
+	// COPY needs the pgx connection behind the transaction, so pin one connection for both
+	return p.db.Connection(func(conn *gorm.DB) error {
+		sqlConn, ok := conn.Statement.ConnPool.(*sql.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected connection type %T", conn.Statement.ConnPool)
+		}
+
+		return writeRange(conn, &p.writeHooks, fromBlock, toBlock, toBlockHash, events, func(tx *gorm.DB, events []BlockchainEvent) error {
+			return copyEvents(tx, sqlConn, events, p.partitionSize > 0, p.binary)
+		})
+	})
+}
+
+func (p *PostgresSink) Import(events []BlockchainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if p.partitionSize > 0 {
+		// Imports write to old blocks another process may have detached, so pg_inherits is asked instead of the cache
+		p.partitionsMu.Lock()
+		err := ensurePartitions(p.db, p.partitionSize, events[0].BlockNumber, events[len(events)-1].BlockNumber, make(map[uint64]bool))
+		p.partitionsMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if p.binary {
+			return storeBinaryEvents(tx, toBinaryEvents(events), WriteModeBatch, p.batchSize, p.partitionSize > 0)
+		}
+		return storeEventsInBatches(tx, events, p.batchSize, p.partitionSize > 0)
+	})
+}
+
+// detachPartitionsBefore detaches the partitions below block and forgets them, later writes to their blocks are refused
+func (p *PostgresSink) detachPartitionsBefore(block uint64) ([]EventPartition, error) {
+	p.partitionsMu.Lock()
+	defer p.partitionsMu.Unlock()
+
+	detached, err := detachPartitionsBefore(p.db, block)
+	for _, partition := range detached {
+		delete(p.partitions, partition.FromBlock)
+	}
+	return detached, err
+}
+
+func (p *PostgresSink) Rollback(fromBlock uint64) error {
+	return rollbackRange(p.db, &p.writeHooks, fromBlock)
+}
+
+func (p *PostgresSink) Cursor() (Cursor, bool, error) {
+	return readCursor(p.db)
+}
+
+func (p *PostgresSink) Close() error {
+	return closeDB(p.db)
+}
+
+// storeEvents upserts events with the row or batch write mode
+func (p *PostgresSink) storeEvents(tx *gorm.DB, events []BlockchainEvent) error {
+	if p.binary {
+		return storeBinaryEvents(tx, toBinaryEvents(events), p.writeMode, p.batchSize, p.partitionSize > 0)
+	}
+	if p.writeMode == WriteModeRow {
+		return storeEvents(tx, events, p.partitionSize > 0)
+	}
+	return storeEventsInBatches(tx, events, p.batchSize, p.partitionSize > 0)
+}
+
+// copyEvents streams events into a staging table with COPY and merges them into blockchain_events.
+// tx must be a transaction on sqlConn so the staged rows and the merge share it.
+func copyEvents(tx *gorm.DB, sqlConn *sql.Conn, events []BlockchainEvent, partitioned, binary bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := tx.Exec("CREATE TEMP TABLE IF NOT EXISTS " + eventStagingTable + " (LIKE blockchain_events INCLUDING DEFAULTS) ON COMMIT DELETE ROWS").Error
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	rows := make([][]any, 0, len(events))
+	for i := range events {
+		if binary {
+			rows = append(rows, copyBinaryRow(toBinaryEvent(&events[i])))
+		} else {
+			rows = append(rows, copyRow(&events[i]))
+		}
+	}
+
+	err = sqlConn.Raw(func(driverConn any) error {
+		conn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY requires the pgx driver, got %T", driverConn)
+		}
+
+		_, err := conn.Conn().CopyFrom(context.Background(), pgx.Identifier{eventStagingTable}, eventCopyColumns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy events: %w", err)
+	}
+
+	assignments := make([]string, 0, len(eventUpsertColumns))
+	for _, column := range eventUpsertColumns {
+		assignments = append(assignments, column+" = EXCLUDED."+column)
+	}
+	columns := strings.Join(eventCopyColumns, ", ")
+
+	err = tx.Exec("INSERT INTO blockchain_events (" + columns + ") SELECT " + columns + " FROM " + eventStagingTable +
+		" ON CONFLICT (" + strings.Join(eventConflictColumns(partitioned), ", ") + ") DO UPDATE SET " + strings.Join(assignments, ", ")).Error
+	if err != nil {
+		return fmt.Errorf("failed to merge staged events: %w", err)
+	}
+
+	return nil
+}
+
+// copyRow returns the values of an event in the order of eventCopyColumns
+func copyRow(event *BlockchainEvent) []any {
+	otherTopics := []string(event.OtherTopics)
+	if otherTopics == nil {
+		otherTopics = []string{}
+	}
+
+	return []any{
+		event.TxHash,
+		int64(event.TxIndex),
+		int64(event.BlockNumber),
+		event.BlockHash,
+		int64(event.LogIndex),
+		event.Removed,
+		event.ContractAddress,
+		event.EventSignature,
+		event.EventName,
+		event.EventFullSignature,
+		otherTopics,
+		event.RawData,
+		string(event.DecodedParams),
+		event.GlobalABIMatch,
+	}
+}
+
+// copyBinaryRow returns the values of a binary event in the order of eventCopyColumns
+func copyBinaryRow(event binaryEvent) []any {
+	return []any{
+		event.TxHash,
+		int64(event.TxIndex),
+		int64(event.BlockNumber),
+		event.BlockHash,
+		int64(event.LogIndex),
+		event.Removed,
+		event.ContractAddress,
+		event.EventSignature,
+		event.EventName,
+		event.EventFullSignature,
+		event.OtherTopics,
+		event.RawData,
+		string(event.DecodedParams),
+		event.GlobalABIMatch,
+	}
+}
@@ -0,0 +1,46 @@
+package eventsdb
+
+import (
+	"os"
+	"testing"
+)
+
+// testPostgresConfig returns the configuration of the PostgreSQL database named by EVENTSDB_TEST_PG_DBNAME,
+// connected with the PG_* variables. Every table of that database is dropped, the test skips when it is unset.
+func testPostgresConfig(t testing.TB) Config {
+	t.Helper()
+	dbName := os.Getenv("EVENTSDB_TEST_PG_DBNAME")
+	if dbName == "" {
+		t.Skip("set EVENTSDB_TEST_PG_DBNAME to a database the tests may wipe to run them on PostgreSQL")
+	}
+
+	config := testSQLiteConfig(t)
+	config.Storage = StoragePostgres
+	config.PgDbName = dbName
+
+	db, err := openDB(config)
+	if err != nil {
+		t.Fatalf("failed to connect to PostgreSQL: %v", err)
+	}
+	defer closeDB(db)
+	for _, statement := range []string{"DROP SCHEMA public CASCADE", "CREATE SCHEMA public"} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("failed to reset %s: %v", dbName, err)
+		}
+	}
+	return config
+}
+
+func TestPostgresSinkWriteRangeAndRollback(t *testing.T) {
+	db, sink := openTestSink(t, testPostgresConfig(t))
+	writeTestRange(t, sink, 100, 109, testTransfers(t, nil, 100, 109, 2))
+	writeTestRange(t, sink, 110, 119, testTransfers(t, nil, 110, 119, 2))
+
+	if err := sink.Rollback(105); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if stored := storedEvents(t, db); len(stored) != 10 {
+		t.Fatalf("%d events left after rolling back from block 105, want 10", len(stored))
+	}
+	assertCursor(t, sink, 104, "")
+}
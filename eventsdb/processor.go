@@ -13,16 +13,16 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
-func processBlockRange(client *ethclient.Client, db *gorm.DB, contractAddress common.Address, fromBlock, toBlock *big.Int, eventSigs map[string]EventSignatureInfo, maxRetries int, retryDelay time.Duration) error {
+// processBlockRange fetches, decodes and writes the logs of [fromBlock, toBlock].
+// ctx only cancels fetching, a write that started is completed.
+func processBlockRange(ctx context.Context, client *ethclient.Client, sink Sink, contractAddress common.Address, fromBlock, toBlock *big.Int, sigs *signatureSet, blockTimes bool, maxRetries int, retryDelay time.Duration) error {
 	if client == nil {
 		return fmt.Errorf("client is nil")
 	}
-	if db == nil {
-		return fmt.Errorf("database is nil")
+	if sink == nil {
+		return fmt.Errorf("sink is nil")
 	}
 	if fromBlock == nil || toBlock == nil {
 		return fmt.Errorf("block numbers cannot be nil")
@@ -38,8 +38,8 @@ func processBlockRange(client *ethclient.Client, db *gorm.DB, contractAddress co
 	var err error
 
 	for i := 0; i < maxRetries; i++ {
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectionTimeout)
-		logs, err = client.FilterLogs(ctx, query)
+		callCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		logs, err = client.FilterLogs(callCtx, query)
 		cancel()
 
 		if err == nil {
@@ -48,7 +48,9 @@ func processBlockRange(client *ethclient.Client, db *gorm.DB, contractAddress co
 
 		if i < maxRetries-1 {
 			logger.Printf("Failed to filter logs (attempt %d): %v. Retrying...\n", i+1, err)
-			time.Sleep(retryDelay)
+			if !sleepContext(ctx, retryDelay) {
+				return ctx.Err()
+			}
 		}
 	}
 
@@ -56,55 +58,42 @@ func processBlockRange(client *ethclient.Client, db *gorm.DB, contractAddress co
 		return fmt.Errorf("failed to filter logs after %d attempts: %v", maxRetries, err)
 	}
 
-	tx := db.Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("failed to start transaction: %v", tx.Error)
+	// The hash of the last block lets the monitor detect reorgs below the cursor
+	toHeader, err := fetchHeader(ctx, client, toBlock, maxRetries, retryDelay)
+	if err != nil {
+		return err
 	}
 
 	if len(logs) == 0 {
 		logger.Println("No event found")
-		err = storeCursor(tx, toBlock)
-		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to store Cursor: %v", err)
-		}
-		if err := tx.Commit().Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to commit transaction: %v", err)
-		}
-		return nil
+	} else {
+		logger.Printf("Found %d events\n", len(logs))
 	}
 
-	logger.Printf("Found %d events\n", len(logs))
+	events := make([]BlockchainEvent, 0, len(logs))
 	for _, log := range logs {
 		var eventSig *EventSignatureInfo
+		var globalMatch bool
 		if len(log.Topics) > 0 {
-			topicHex := log.Topics[0].Hex()
-			if sig, exists := eventSigs[topicHex]; exists {
-				eventSig = &sig
-			}
+			eventSig, globalMatch = sigs.lookup(log.Address, log.BlockNumber, log.Topics[0].Hex())
 		}
 
-		err = storeEvent(tx, log, eventSig)
+		event, err := buildEvent(log, eventSig, globalMatch)
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to store event: %v", err)
+			return err
 		}
-
-		logger.Printf("Event stored in database successfully (BlockNumber: %d, TxHash: %s, LogIndex: %d)\n",
-			log.BlockNumber, log.TxHash.Hex(), log.Index)
+		events = append(events, event)
 	}
 
-	err = storeCursor(tx, toBlock)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to store Cursor: %v", err)
+	// Rollups bucket events by block time, which logs only carry on recent nodes
+	if blockTimes {
+		if err := fillBlockTimes(ctx, client, events, maxRetries, retryDelay); err != nil {
+			return err
+		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to commit transaction: %v", err)
+	if err := sink.WriteRange(fromBlock.Uint64(), toBlock.Uint64(), toHeader.Hash().Hex(), events); err != nil {
+		return fmt.Errorf("failed to write block range: %v", err)
 	}
 
 	return nil
@@ -196,8 +185,8 @@ func printEventLog(log types.Log, eventSigs map[string]EventSignatureInfo) {
 	}
 }
 
-// Modified storeEvent function with upsert and transaction support
-func storeEvent(tx *gorm.DB, log types.Log, eventSig *EventSignatureInfo) error {
+// buildEvent converts a log and its decoded parameters into the stored event model
+func buildEvent(log types.Log, eventSig *EventSignatureInfo, globalMatch bool) (BlockchainEvent, error) {
 	var eventName *string
 	var fullSignature *string
 
@@ -206,87 +195,14 @@ func storeEvent(tx *gorm.DB, log types.Log, eventSig *EventSignatureInfo) error
 		fullSignature = &eventSig.Signature
 	}
 
-	otherTopics := make([]string, 0, len(log.Topics)-1)
+	otherTopics := make([]string, 0, len(log.Topics))
 	for i := 1; i < len(log.Topics); i++ {
 		otherTopics = append(otherTopics, log.Topics[i].Hex())
 	}
 
-	decodedParams := make(map[string]interface{})
-	if eventSig != nil && eventSig.OriginalABI != nil {
-		var indexedInputs []abi.Argument
-		var nonIndexedInputs []abi.Argument
-		var originalIndexedInputs []ABIInput
-		var originalNonIndexedInputs []ABIInput
-
-		// Separate indexed and non-indexed inputs
-		for i, input := range eventSig.Inputs {
-			if input.Indexed {
-				indexedInputs = append(indexedInputs, input)
-				if i < len(eventSig.OriginalABI.Inputs) {
-					originalIndexedInputs = append(originalIndexedInputs, eventSig.OriginalABI.Inputs[i])
-				}
-			} else {
-				nonIndexedInputs = append(nonIndexedInputs, input)
-				if i < len(eventSig.OriginalABI.Inputs) {
-					originalNonIndexedInputs = append(originalNonIndexedInputs, eventSig.OriginalABI.Inputs[i])
-				}
-			}
-		}
-
-		// Process indexed parameters (topics)
-		for i, input := range indexedInputs {
-			topicIndex := i + 1
-			if topicIndex < len(log.Topics) {
-				topic := log.Topics[topicIndex]
-
-				var decodedValue interface{}
-				switch input.Type.T {
-				case abi.AddressTy:
-					decodedValue = common.HexToAddress(topic.Hex())
-				case abi.IntTy, abi.UintTy:
-					decodedValue = big.NewInt(0).SetBytes(topic.Bytes())
-				case abi.BoolTy:
-					decodedValue = topic.Bytes()[31] == 1
-				case abi.StringTy:
-					decodedValue = string(topic.Bytes())
-				case abi.FixedBytesTy, abi.BytesTy:
-					decodedValue = fmt.Sprintf("%x", topic.Bytes())
-				default:
-					decodedValue = topic.Bytes()
-				}
-
-				// Use simplified decoding
-				if i < len(originalIndexedInputs) {
-					decodedParams[input.Name] = decodeParameterWithComponents(decodedValue, originalIndexedInputs[i], input)
-				} else {
-					decodedParams[input.Name] = decodedValue
-				}
-			}
-		}
-
-		// Process non-indexed parameters from data
-		if len(log.Data) > 0 && len(nonIndexedInputs) > 0 {
-			method := abi.NewMethod(eventSig.Name, eventSig.Name, abi.Function, "", false, false, nonIndexedInputs, nil)
-
-			v, err := method.Inputs.UnpackValues(log.Data)
-			if err == nil {
-				for i, input := range nonIndexedInputs {
-					if i < len(v) {
-						// Use simplified decoding
-						if i < len(originalNonIndexedInputs) {
-							decodedParams[input.Name] = decodeParameterWithComponents(v[i], originalNonIndexedInputs[i], input)
-						} else {
-							decodedParams[input.Name] = v[i]
-						}
-					}
-				}
-			}
-		}
-	}
-
-	decodedParamsJSON, err := json.Marshal(decodedParams)
+	decodedParamsJSON, err := json.Marshal(decodeLogParams(log, eventSig))
 	if err != nil {
-		return fmt.Errorf("failed to marshal decoded parameters: %w", err)
+		return BlockchainEvent{}, fmt.Errorf("failed to marshal decoded parameters: %w", err)
 	}
 
 	rawData := fmt.Sprintf("%x", log.Data)
@@ -296,7 +212,12 @@ func storeEvent(tx *gorm.DB, log types.Log, eventSig *EventSignatureInfo) error
 		logTopic = log.Topics[0].Hex()
 	}
 
-	event := BlockchainEvent{
+	var blockTime time.Time
+	if log.BlockTimestamp != 0 {
+		blockTime = time.Unix(int64(log.BlockTimestamp), 0).UTC()
+	}
+
+	return BlockchainEvent{
 		TxHash:             log.TxHash.Hex(),
 		TxIndex:            uint(log.TxIndex),
 		BlockNumber:        log.BlockNumber,
@@ -310,47 +231,7 @@ func storeEvent(tx *gorm.DB, log types.Log, eventSig *EventSignatureInfo) error
 		OtherTopics:        otherTopics,
 		RawData:            rawData,
 		DecodedParams:      decodedParamsJSON,
-	}
-
-	// Use upsert (OnConflict) to avoid duplicate key errors
-	result := tx.Clauses(
-		clause.OnConflict{
-			Columns:   []clause.Column{{Name: "tx_hash"}, {Name: "log_index"}},
-			DoUpdates: clause.AssignmentColumns([]string{"tx_index", "block_number", "block_hash", "removed", "contract_address", "event_signature", "event_name", "event_full_signature", "other_topics", "raw_data", "decoded_params"}),
-		},
-	).Create(&event)
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to store event: %w", result.Error)
-	}
-
-	return nil
-}
-
-// Update storeCursor to use transaction
-func storeCursor(tx *gorm.DB, c *big.Int) error {
-	var counter Cursor
-	if err := tx.First(&counter, 1).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Create the counter if not exists
-			counter = Cursor{
-				ID:    1,
-				Count: int(c.Int64()),
-			}
-			if err := tx.Create(&counter).Error; err != nil {
-				return fmt.Errorf("failed to create counter: %w", err)
-			}
-		} else {
-			return fmt.Errorf("failed to query counter: %w", err)
-		}
-	} else {
-		// Update the existing counter
-		if err := tx.Model(&Cursor{}).
-			Where("id = ?", 1).
-			Update("count", int(c.Int64())).Error; err != nil {
-			return fmt.Errorf("failed to update counter: %w", err)
-		}
-	}
-
-	return nil
+		GlobalABIMatch:     globalMatch,
+		BlockTime:          blockTime,
+	}, nil
 }
@@ -0,0 +1,558 @@
+package eventsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// projectionBatchSize is the number of rows upserted per statement into a projection table
+const projectionBatchSize = 200
+
+// projectionBaseColumns are copied from blockchain_events into every projection table
+var projectionBaseColumns = []string{"tx_hash", "log_index", "block_number", "contract_address"}
+
+// Kinds of projection column values, they decide how a decoded parameter is converted
+const (
+	valueNumber = iota
+	valueAddress
+	valueBytes
+	valueBool
+	valueText
+	valueJSON
+)
+
+// projectionColumn is a typed column of a projection table filled from one decoded parameter
+type projectionColumn struct {
+	Name    string   // Column name, the parameter name in snake case
+	Params  []string // Keys of the parameter in decoded_params, one per distinct name among the definitions
+	SQLType string
+	Kind    int
+	Indexed bool // Indexed event parameters get a database index
+}
+
+// projection is the typed table of one event signature
+type projection struct {
+	Table         string
+	SignatureHash string
+	Signature     string
+	Columns       []projectionColumn
+}
+
+// ProjectionSummary describes the projection table of an event signature
+type ProjectionSummary struct {
+	Table         string   `json:"table"`
+	SignatureHash string   `json:"signatureHash"`
+	Signature     string   `json:"signature"`
+	Columns       []string `json:"columns"`
+}
+
+// buildProjection derives the projection table of an event from the ABI inputs of its definitions.
+// The first definition names the table and columns, inputs other definitions name differently are read by position.
+func buildProjection(signatureHash string, sigs []EventSignatureInfo, storage string, binary bool) *projection {
+	sig := &sigs[0]
+	p := &projection{
+		Table:         projectionTableName(sig.Name, signatureHash),
+		SignatureHash: signatureHash,
+		Signature:     sig.Signature,
+	}
+
+	used := make(map[string]bool)
+	for _, column := range projectionBaseColumns {
+		used[column] = true
+	}
+
+	if sig.OriginalABI == nil {
+		return p
+	}
+	for i, input := range sig.OriginalABI.Inputs {
+		name := snakeCase(input.Name)
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		for used[name] {
+			name = "param_" + name
+		}
+		used[name] = true
+
+		sqlType, kind := projectionColumnType(input.Type, storage, binary)
+		column := projectionColumn{
+			Name:    name,
+			Params:  []string{input.Name},
+			SQLType: sqlType,
+			Kind:    kind,
+			Indexed: input.Indexed,
+		}
+		for _, other := range sigs[1:] {
+			if other.OriginalABI == nil || i >= len(other.OriginalABI.Inputs) {
+				continue
+			}
+			if param := other.OriginalABI.Inputs[i].Name; !slices.Contains(column.Params, param) {
+				column.Params = append(column.Params, param)
+			}
+		}
+		p.Columns = append(p.Columns, column)
+	}
+
+	return p
+}
+
+// value returns the decoded parameter of the column, under whichever name the event was decoded with
+func (c *projectionColumn) value(params map[string]interface{}) interface{} {
+	for _, param := range c.Params {
+		if value, ok := params[param]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// projectionTableName names the table of an event, the hash prefix keeps overloaded events apart
+func projectionTableName(eventName, signatureHash string) string {
+	name := snakeCase(eventName)
+	// PostgreSQL identifiers are limited to 63 bytes
+	if len(name) > 45 {
+		name = name[:45]
+	}
+	return fmt.Sprintf("event_%s_%s", name, strings.TrimPrefix(signatureHash, "0x")[:8])
+}
+
+// snakeCase turns a Solidity identifier like quoteId into quote_id
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) && runes[i-1] != '_' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// projectionColumnType maps a Solidity type to a column type of the storage backend
+func projectionColumnType(solidityType, storage string, binary bool) (string, int) {
+	sqlite := storage == StorageSQLite
+
+	switch {
+	case strings.HasSuffix(solidityType, "]") || strings.HasPrefix(solidityType, "tuple"):
+		if sqlite {
+			return "text", valueJSON
+		}
+		return "jsonb", valueJSON
+	case solidityType == "address":
+		if binary {
+			return "bytea", valueAddress
+		}
+		return "varchar(42)", valueAddress
+	case solidityType == "bool":
+		return "boolean", valueBool
+	case solidityType == "string":
+		return "text", valueText
+	case strings.HasPrefix(solidityType, "bytes"):
+		if sqlite {
+			return "blob", valueBytes
+		}
+		return "bytea", valueBytes
+	case strings.HasPrefix(solidityType, "uint"), strings.HasPrefix(solidityType, "int"):
+		if integerFitsBigint(solidityType) {
+			return "bigint", valueNumber
+		}
+		if sqlite {
+			// SQLite numbers are 64 bit, larger values keep their precision as text
+			return "text", valueNumber
+		}
+		return "numeric", valueNumber
+	default:
+		return "text", valueText
+	}
+}
+
+// integerFitsBigint reports whether every value of a Solidity integer type fits a signed 64 bit column
+func integerFitsBigint(solidityType string) bool {
+	bits := 256
+	digits := strings.TrimLeft(solidityType, "uint")
+	if digits != "" {
+		if n, err := strconv.Atoi(digits); err == nil {
+			bits = n
+		}
+	}
+	if strings.HasPrefix(solidityType, "uint") {
+		return bits < 64
+	}
+	return bits <= 64
+}
+
+// createStatements returns the DDL creating the table and its indexes if they do not exist
+func (p *projection) createStatements(binary bool) []string {
+	hashType, addressType := "varchar(66)", "varchar(42)"
+	if binary {
+		hashType, addressType = "bytea", "bytea"
+	}
+
+	columns := []string{
+		"tx_hash " + hashType + " NOT NULL",
+		"log_index bigint NOT NULL",
+		"block_number bigint NOT NULL",
+		"contract_address " + addressType + " NOT NULL",
+	}
+	for _, column := range p.Columns {
+		columns = append(columns, quoteIdentifier(column.Name)+" "+column.SQLType)
+	}
+	columns = append(columns, "PRIMARY KEY (tx_hash, log_index)")
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", p.Table, strings.Join(columns, ",\n\t")),
+		p.createIndex("block_number"),
+		p.createIndex("contract_address"),
+	}
+	for _, column := range p.Columns {
+		if column.Indexed {
+			statements = append(statements, p.createIndex(column.Name))
+		}
+	}
+	return statements
+}
+
+func (p *projection) createIndex(column string) string {
+	name := fmt.Sprintf("idx_%s_%s", strings.TrimPrefix(p.SignatureHash, "0x")[:8], column)
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", quoteIdentifier(name), p.Table, quoteIdentifier(column))
+}
+
+// row converts a stored event into the column values of the projection table.
+// Parameters that cannot be converted are stored as NULL and logged, they never fail the write.
+func (p *projection) row(event *BlockchainEvent, binary bool) map[string]interface{} {
+	params := make(map[string]interface{})
+	if len(event.DecodedParams) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(event.DecodedParams))
+		decoder.UseNumber()
+		if err := decoder.Decode(&params); err != nil {
+			log.Printf("Warning: Failed to parse decoded params of %s/%d for %s, storing NULL columns: %v\n", event.TxHash, event.LogIndex, p.Table, err)
+			params = make(map[string]interface{})
+		}
+	}
+
+	row := map[string]interface{}{
+		"tx_hash":          hexParam(binary, event.TxHash),
+		"log_index":        event.LogIndex,
+		"block_number":     event.BlockNumber,
+		"contract_address": hexParam(binary, event.ContractAddress),
+	}
+	for _, column := range p.Columns {
+		value, err := projectionValue(column.value(params), column.Kind, binary)
+		if err != nil {
+			log.Printf("Warning: Failed to convert %s of %s/%d for %s, storing NULL: %v\n", column.Name, event.TxHash, event.LogIndex, p.Table, err)
+		}
+		row[column.Name] = value
+	}
+	return row
+}
+
+// projectionValue converts a decoded parameter to the value of its column
+func projectionValue(value interface{}, kind int, binary bool) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case valueNumber:
+		switch v := value.(type) {
+		case json.Number:
+			return v.String(), nil
+		case string:
+			return v, nil
+		}
+	case valueAddress:
+		if v, ok := value.(string); ok {
+			return hexParam(binary, v), nil
+		}
+	case valueBytes:
+		if v, ok := value.(string); ok {
+			return common.FromHex(v), nil
+		}
+	case valueBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case valueText:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+		return fmt.Sprint(value), nil
+	case valueJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	return nil, fmt.Errorf("unexpected value %v", value)
+}
+
+// upsert stores the rows of events in the projection table inside a transaction
+func (p *projection) upsert(tx *gorm.DB, events []*BlockchainEvent, binary bool) error {
+	updates := []string{"block_number", "contract_address"}
+	for _, column := range p.Columns {
+		updates = append(updates, column.Name)
+	}
+	onConflict := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tx_hash"}, {Name: "log_index"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}
+
+	for start := 0; start < len(events); start += projectionBatchSize {
+		end := min(start+projectionBatchSize, len(events))
+
+		rows := make([]map[string]interface{}, 0, end-start)
+		for _, event := range events[start:end] {
+			rows = append(rows, p.row(event, binary))
+		}
+
+		if err := tx.Table(p.Table).Clauses(onConflict).Create(rows).Error; err != nil {
+			return fmt.Errorf("failed to store %s rows: %w", p.Table, err)
+		}
+	}
+
+	return nil
+}
+
+// quoteIdentifier quotes a column or index name for both PostgreSQL and SQLite
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// projector keeps one typed table per known event signature in step with blockchain_events.
+// It is registered as a WriteHook so projection rows are written in the same transaction as the events.
+type projector struct {
+	storage     string
+	binary      bool
+	projections atomic.Pointer[map[string]*projection] // By signature hash
+}
+
+func newProjector(config Config) *projector {
+	p := &projector{storage: config.Storage, binary: config.BinaryStorage}
+	p.projections.Store(&map[string]*projection{})
+	return p
+}
+
+// update builds the projections of every signature in set and creates their tables
+func (p *projector) update(db *gorm.DB, set *signatureSet) error {
+	projections := make(map[string]*projection, len(set.global))
+	for hash, sigs := range signatureDefinitions(set) {
+		projection := buildProjection(hash, sigs, p.storage, p.binary)
+		for _, statement := range projection.createStatements(p.binary) {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to create projection table %s: %w", projection.Table, err)
+			}
+		}
+		projections[hash] = projection
+	}
+
+	p.projections.Store(&projections)
+	return nil
+}
+
+// signatureDefinitions returns the distinct definitions of every signature hash in set, global and
+// contract-bound ones alike, the global definition first and the others in source order
+func signatureDefinitions(set *signatureSet) map[string][]EventSignatureInfo {
+	definitions := make(map[string][]EventSignatureInfo, len(set.global))
+	for hash, sig := range set.global {
+		definitions[hash] = []EventSignatureInfo{sig}
+	}
+
+	sources := make([]string, 0, len(set.bySource))
+	for source := range set.bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		for hash, sig := range set.bySource[source] {
+			known := slices.ContainsFunc(definitions[hash], func(other EventSignatureInfo) bool {
+				return reflect.DeepEqual(other.OriginalABI, sig.OriginalABI)
+			})
+			if !known {
+				definitions[hash] = append(definitions[hash], sig)
+			}
+		}
+	}
+	return definitions
+}
+
+// sorted returns the projections ordered by table name
+func (p *projector) sorted() []*projection {
+	projections := *p.projections.Load()
+	sorted := make([]*projection, 0, len(projections))
+	for _, projection := range projections {
+		sorted = append(sorted, projection)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Table < sorted[j].Table })
+	return sorted
+}
+
+func (p *projector) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	projections := *p.projections.Load()
+
+	bySignature := make(map[string][]*BlockchainEvent)
+	for i := range events {
+		if events[i].EventName == nil {
+			continue
+		}
+		if _, ok := projections[events[i].EventSignature]; ok {
+			bySignature[events[i].EventSignature] = append(bySignature[events[i].EventSignature], &events[i])
+		}
+	}
+
+	for hash, signatureEvents := range bySignature {
+		if err := projections[hash].upsert(tx, signatureEvents, p.binary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *projector) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	for _, projection := range p.sorted() {
+		if err := tx.Exec("DELETE FROM "+projection.Table+" WHERE block_number >= ?", fromBlock).Error; err != nil {
+			return fmt.Errorf("failed to roll back %s: %w", projection.Table, err)
+		}
+	}
+	return nil
+}
+
+// rebuild recreates the projection tables of signatures from blockchain_events, every table if signatures is empty.
+// Each table is dropped and refilled in one transaction so readers never see it half filled.
+func (p *projector) rebuild(db *gorm.DB, signatures []string, batchSize int) (int, error) {
+	projections := *p.projections.Load()
+	if len(signatures) == 0 {
+		for hash := range projections {
+			signatures = append(signatures, hash)
+		}
+	}
+
+	rebuilt := 0
+	for _, hash := range signatures {
+		projection, ok := projections[hash]
+		if !ok {
+			continue
+		}
+
+		var rows int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP TABLE IF EXISTS " + projection.Table).Error; err != nil {
+				return err
+			}
+			for _, statement := range projection.createStatements(p.binary) {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+
+			var lastID uint
+			for {
+				var events []BlockchainEvent
+				err := tx.Where("id > ? AND event_signature = ? AND event_name IS NOT NULL", lastID, hexParam(p.binary, hash)).
+					Order("id").Limit(batchSize).Find(&events).Error
+				if err != nil {
+					return err
+				}
+				if len(events) == 0 {
+					return nil
+				}
+
+				batch := make([]*BlockchainEvent, 0, len(events))
+				for i := range events {
+					batch = append(batch, &events[i])
+				}
+				if err := projection.upsert(tx, batch, p.binary); err != nil {
+					return err
+				}
+
+				rows += len(events)
+				lastID = events[len(events)-1].ID
+			}
+		})
+		if err != nil {
+			return rebuilt, fmt.Errorf("failed to rebuild %s: %w", projection.Table, err)
+		}
+
+		log.Printf("Rebuilt %s with %d rows\n", projection.Table, rows)
+		rebuilt++
+	}
+
+	return rebuilt, nil
+}
+
+// ListProjections returns the projection table of every known event signature
+func (s *IndexerService) ListProjections() ([]ProjectionSummary, error) {
+	if err := s.prepareProjections(); err != nil {
+		return nil, err
+	}
+
+	var summaries []ProjectionSummary
+	for _, projection := range s.projector.sorted() {
+		summary := ProjectionSummary{
+			Table:         projection.Table,
+			SignatureHash: projection.SignatureHash,
+			Signature:     projection.Signature,
+			Columns:       append([]string{}, projectionBaseColumns...),
+		}
+		for _, column := range projection.Columns {
+			summary.Columns = append(summary.Columns, column.Name)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// RebuildProjections refills the projection tables of the events called name, or of every event if name is empty
+func (s *IndexerService) RebuildProjections(name string, batchSize int) (int, error) {
+	if err := s.prepareProjections(); err != nil {
+		return 0, err
+	}
+	if batchSize < 1 {
+		batchSize = DefaultRedecodeBatchSize
+	}
+
+	var signatures []string
+	if name != "" {
+		for hash, sig := range s.sigs.Snapshot().global {
+			if sig.Name == name || strings.EqualFold(hash, name) {
+				signatures = append(signatures, hash)
+			}
+		}
+		if len(signatures) == 0 {
+			return 0, fmt.Errorf("no ABI event registered for %s", name)
+		}
+	}
+
+	return s.projector.rebuild(s.db, signatures, batchSize)
+}
+
+// prepareProjections loads the signatures and creates the projection tables for the projections commands
+func (s *IndexerService) prepareProjections() error {
+	if !s.config.Projections {
+		return fmt.Errorf("projection tables are disabled, set PROJECTIONS=true")
+	}
+	if err := s.ensureDatabase(); err != nil {
+		return err
+	}
+	return s.loadEventSignatures()
+}
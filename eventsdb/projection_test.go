@@ -0,0 +1,197 @@
+package eventsdb
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testEverythingABI has an input of every Solidity type family, indexed and not
+const testEverythingABI = `{"type":"event","name":"Everything","anonymous":false,"inputs":[` +
+	`{"name":"owner","type":"address","indexed":true},` +
+	`{"name":"id","type":"uint256","indexed":true},` +
+	`{"name":"topicTag","type":"bytes32","indexed":true},` +
+	`{"name":"flagged","type":"bool","indexed":true},` +
+	`{"name":"small","type":"uint8","indexed":false},` +
+	`{"name":"signed","type":"int64","indexed":false},` +
+	`{"name":"amount","type":"uint256","indexed":false},` +
+	`{"name":"delta","type":"int256","indexed":false},` +
+	`{"name":"recipient","type":"address","indexed":false},` +
+	`{"name":"active","type":"bool","indexed":false},` +
+	`{"name":"note","type":"string","indexed":false},` +
+	`{"name":"payload","type":"bytes","indexed":false},` +
+	`{"name":"selector","type":"bytes4","indexed":false},` +
+	`{"name":"tag","type":"bytes8","indexed":false},` +
+	`{"name":"digest","type":"bytes32","indexed":false},` +
+	`{"name":"amounts","type":"uint256[]","indexed":false},` +
+	`{"name":"tags","type":"bytes8[]","indexed":false},` +
+	`{"name":"pair","type":"tuple","indexed":false,"components":[{"name":"a","type":"address"},{"name":"b","type":"uint256"}]},` +
+	`{"name":"pairs","type":"tuple[]","indexed":false,"components":[{"name":"a","type":"address"},{"name":"b","type":"uint256"}]}]}`
+
+// testPair is the Go value of the pair tuple of testEverythingABI
+type testPair struct {
+	A common.Address
+	B *big.Int
+}
+
+// testEverythingEvent builds an Everything event of block decoded with sig
+func testEverythingEvent(t *testing.T, sigHash string, sig *EventSignatureInfo, block uint64) BlockchainEvent {
+	t.Helper()
+	var nonIndexed abi.Arguments
+	for _, input := range sig.Inputs {
+		if !input.Indexed {
+			nonIndexed = append(nonIndexed, input)
+		}
+	}
+	recipient := common.HexToAddress("0x00000000000000000000000000000000000000b0")
+	data, err := nonIndexed.Pack(
+		uint8(7), int64(-42), new(big.Int).Lsh(big.NewInt(1), 100), big.NewInt(-5), recipient, true, "hello",
+		[]byte{0xde, 0xad}, [4]byte{0xa9, 0x05, 0x9c, 0xbb}, [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, [32]byte{31: 0xff},
+		[]*big.Int{big.NewInt(1), big.NewInt(2)}, [][8]byte{{1}, {2}},
+		testPair{A: recipient, B: big.NewInt(3)}, []testPair{{A: recipient, B: big.NewInt(4)}, {A: recipient, B: big.NewInt(5)}},
+	)
+	if err != nil {
+		t.Fatalf("failed to pack the Everything data: %v", err)
+	}
+
+	eventLog := types.Log{
+		Address: common.HexToAddress(testContract),
+		Topics: []common.Hash{
+			common.HexToHash(sigHash),
+			common.BytesToHash(common.HexToAddress("0x00000000000000000000000000000000000000a0").Bytes()),
+			common.BigToHash(big.NewInt(9)),
+			common.HexToHash("0xabcd"),
+			common.BigToHash(big.NewInt(1)),
+		},
+		Data:        data,
+		BlockNumber: block,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(block)),
+		BlockHash:   testBlockHash(block),
+	}
+	event, err := buildEvent(eventLog, sig, false)
+	if err != nil {
+		t.Fatalf("failed to build event: %v", err)
+	}
+	return event
+}
+
+func TestProjectionTypes(t *testing.T) {
+	sigHash, sig, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Everything", ABIEventJSON: testEverythingABI})
+	if err != nil {
+		t.Fatalf("failed to parse the Everything ABI: %v", err)
+	}
+	config := testSQLiteConfig(t)
+	config.Projections = true
+	db, sink := openTestSink(t, config)
+
+	set := newSignatureSet()
+	set.global[sigHash] = sig
+	set.bySource["everything.json"] = map[string]EventSignatureInfo{sigHash: sig}
+	projector := newProjector(config)
+	if err := projector.update(db, set); err != nil {
+		t.Fatalf("failed to create the projection table: %v", err)
+	}
+	sink.AddHook(projector)
+
+	event := testEverythingEvent(t, sigHash, &sig, 100)
+	// Events decoded before bytesN were stored as hex keep a number array, it cannot be converted
+	stale := testEverythingEvent(t, sigHash, &sig, 101)
+	stale.DecodedParams = bytes.Replace(stale.DecodedParams, []byte(`"tag":"0x0102030405060708"`), []byte(`"tag":[1,2,3,4,5,6,7,8]`), 1)
+	writeTestRange(t, sink, 100, 101, []BlockchainEvent{event, stale})
+
+	table := projector.sorted()[0].Table
+	var rows []map[string]interface{}
+	if err := db.Table(table).Order("block_number").Find(&rows).Error; err != nil {
+		t.Fatalf("failed to read %s: %v", table, err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("%s has %d rows, want 2", table, len(rows))
+	}
+
+	// SQLite returns booleans as integers and large integers as text
+	recipient := "0x00000000000000000000000000000000000000b0"
+	want := map[string]interface{}{
+		"tx_hash":          event.TxHash,
+		"log_index":        int64(0),
+		"block_number":     int64(100),
+		"contract_address": testContract,
+		"owner":            "0x00000000000000000000000000000000000000a0",
+		"id":               "9",
+		"topic_tag":        common.HexToHash("0xabcd").Bytes(),
+		"flagged":          int64(1),
+		"small":            int64(7),
+		"signed":           int64(-42),
+		"amount":           new(big.Int).Lsh(big.NewInt(1), 100).String(),
+		"delta":            "-5",
+		"recipient":        recipient,
+		"active":           int64(1),
+		"note":             "hello",
+		"payload":          []byte{0xde, 0xad},
+		"selector":         []byte{0xa9, 0x05, 0x9c, 0xbb},
+		"tag":              []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		"digest":           common.BytesToHash([]byte{0xff}).Bytes(),
+		"amounts":          `[1,2]`,
+		"tags":             `["0x0100000000000000","0x0200000000000000"]`,
+		"pair":             `{"a":"` + recipient + `","b":3}`,
+		"pairs":            `[{"a":"` + recipient + `","b":"4"},{"a":"` + recipient + `","b":"5"}]`,
+	}
+	if !reflect.DeepEqual(rows[0], want) {
+		for column := range want {
+			if !reflect.DeepEqual(rows[0][column], want[column]) {
+				t.Errorf("column %s is %#v, want %#v", column, rows[0][column], want[column])
+			}
+		}
+		t.FailNow()
+	}
+
+	// A value that cannot be converted is stored as NULL instead of failing the write
+	if rows[1]["tag"] != nil || rows[1]["note"] != "hello" {
+		t.Fatalf("stale row has tag %#v and note %#v, want NULL and hello", rows[1]["tag"], rows[1]["note"])
+	}
+}
+
+func TestProjectionDefinitions(t *testing.T) {
+	global := `{"type":"event","name":"Swap","inputs":[` +
+		`{"name":"txHash","type":"bytes32","indexed":true},{"name":"tx_hash","type":"uint256","indexed":false},{"name":"param_tx_hash","type":"address","indexed":false}]}`
+	bound := `{"type":"event","name":"Swap","inputs":[` +
+		`{"name":"hash","type":"bytes32","indexed":true},{"name":"amount","type":"uint256","indexed":false},{"name":"param_tx_hash","type":"address","indexed":false}]}`
+	sigHash, globalSig, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Swap", ABIEventJSON: global})
+	if err != nil {
+		t.Fatalf("failed to parse the global Swap ABI: %v", err)
+	}
+	boundHash, boundSig, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Swap", ABIEventJSON: bound})
+	if err != nil || boundHash != sigHash {
+		t.Fatalf("bound Swap ABI has hash %s (error %v), want %s", boundHash, err, sigHash)
+	}
+
+	set := newSignatureSet()
+	set.global[sigHash] = globalSig
+	set.bySource["dex.json"] = map[string]EventSignatureInfo{sigHash: globalSig}
+	set.bySource["bound.json"] = map[string]EventSignatureInfo{sigHash: boundSig}
+	definitions := signatureDefinitions(set)
+	if len(definitions[sigHash]) != 2 || definitions[sigHash][0].OriginalABI.Inputs[0].Name != "txHash" {
+		t.Fatalf("definitions %+v, want the global and the bound one", definitions[sigHash])
+	}
+
+	// Colliding names are renamed until they are unique
+	p := buildProjection(sigHash, definitions[sigHash], StorageSQLite, false)
+	var columns []string
+	for _, column := range p.Columns {
+		columns = append(columns, column.Name)
+	}
+	if want := []string{"param_tx_hash", "param_param_tx_hash", "param_param_param_tx_hash"}; !reflect.DeepEqual(columns, want) {
+		t.Fatalf("columns %v, want %v", columns, want)
+	}
+
+	// Events decoded with the bound ABI fill the columns named after the global one
+	event := BlockchainEvent{TxHash: "0x01", ContractAddress: testContract, DecodedParams: []byte(`{"hash":"0xabcd","amount":"12","param_tx_hash":"0x02"}`)}
+	row := p.row(&event, false)
+	if !bytes.Equal(row["param_tx_hash"].([]byte), []byte{0xab, 0xcd}) || row["param_param_tx_hash"] != "12" || row["param_param_param_tx_hash"] != "0x02" {
+		t.Fatalf("row %v, want the values decoded with the bound ABI", row)
+	}
+}
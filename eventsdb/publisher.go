@@ -0,0 +1,174 @@
+package eventsdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers of a published message, Kafka and NATS send them as headers and Redis as stream entry fields
+const (
+	brokerHeaderKind    = "eventsdb-kind"     // event or tombstone
+	brokerHeaderEventID = "eventsdb-event-id" // Tx hash and log index of the event
+	brokerHeaderBlock   = "eventsdb-block"
+)
+
+// brokerMessageID deduplicates redelivered messages where the broker supports it
+func brokerMessageID(message *BrokerMessage) string {
+	return message.Kind + ":" + message.EventID
+}
+
+// newPublisher connects to the broker of BrokerURL, the scheme selects Kafka, NATS or Redis Streams
+func newPublisher(config Config) (Publisher, error) {
+	scheme, address, ok := strings.Cut(config.BrokerURL, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid BROKER_URL %q, expected kafka://, nats:// or redis://", redactBrokerURL(config.BrokerURL))
+	}
+
+	switch strings.ToLower(scheme) {
+	case "kafka":
+		return newKafkaPublisher(strings.Split(address, ","), config.BrokerTopic), nil
+	case "nats":
+		return newNATSPublisher(config.BrokerURL, config.BrokerTopic)
+	case "redis", "rediss":
+		return newRedisPublisher(config.BrokerURL, config.BrokerTopic)
+	default:
+		return nil, fmt.Errorf("unsupported broker %q, expected kafka, nats or redis", scheme)
+	}
+}
+
+// redactBrokerURL hides the password of a broker URL for logging
+func redactBrokerURL(brokerURL string) string {
+	parsed, err := url.Parse(brokerURL)
+	if err != nil || parsed.User == nil {
+		return brokerURL
+	}
+	return parsed.Redacted()
+}
+
+// kafkaPublisher writes events to a Kafka topic, partitioned by the hash of the message key.
+// A tombstone has the key of its event and is told apart by its kind header, not by a null value.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisher(brokers []string, topic string) *kafkaPublisher {
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    brokerBatchSize,
+		BatchTimeout: 10 * time.Millisecond,
+	}}
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, messages []BrokerMessage) error {
+	records := make([]kafka.Message, 0, len(messages))
+	for i := range messages {
+		message := &messages[i]
+		record := kafka.Message{
+			Key: []byte(message.MessageKey),
+			Headers: []kafka.Header{
+				{Key: brokerHeaderKind, Value: []byte(message.Kind)},
+				{Key: brokerHeaderEventID, Value: []byte(message.EventID)},
+				{Key: brokerHeaderBlock, Value: []byte(strconv.FormatUint(message.BlockNumber, 10))},
+			},
+			Value: []byte(message.Payload),
+		}
+		records = append(records, record)
+	}
+	return p.writer.WriteMessages(ctx, records...)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// natsPublisher publishes events to JetStream on the subject <topic>.<key>, a stream must capture <topic>.>.
+// Nats-Msg-Id lets JetStream drop messages redelivered within its duplicate window.
+type natsPublisher struct {
+	conn   *nats.Conn
+	stream nats.JetStreamContext
+	topic  string
+}
+
+func newNATSPublisher(natsURL, topic string) (*natsPublisher, error) {
+	// Keep reconnecting, the outbox holds the messages while NATS is away
+	conn, err := nats.Connect(natsURL, nats.Name("eventsdb"), nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	stream, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	return &natsPublisher{conn: conn, stream: stream, topic: topic}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, messages []BrokerMessage) error {
+	for i := range messages {
+		message := &messages[i]
+		msg := nats.NewMsg(p.topic + "." + message.MessageKey)
+		msg.Header.Set(nats.MsgIdHdr, brokerMessageID(message))
+		msg.Header.Set(brokerHeaderKind, message.Kind)
+		msg.Header.Set(brokerHeaderEventID, message.EventID)
+		msg.Header.Set(brokerHeaderBlock, strconv.FormatUint(message.BlockNumber, 10))
+		msg.Data = []byte(message.Payload)
+		if _, err := p.stream.PublishMsg(msg, nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// redisPublisher appends events to a Redis stream, the fields of an entry are the headers plus key and payload.
+// A batch is added in one MULTI/EXEC so a failed call adds nothing.
+type redisPublisher struct {
+	client *redis.Client
+	stream string
+}
+
+func newRedisPublisher(redisURL, stream string) (*redisPublisher, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &redisPublisher{client: redis.NewClient(options), stream: stream}, nil
+}
+
+func (p *redisPublisher) Publish(ctx context.Context, messages []BrokerMessage) error {
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range messages {
+			message := &messages[i]
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: p.stream,
+				Values: []interface{}{
+					"key", message.MessageKey,
+					brokerHeaderKind, message.Kind,
+					brokerHeaderEventID, message.EventID,
+					brokerHeaderBlock, message.BlockNumber,
+					"payload", message.Payload,
+				},
+			})
+		}
+		return nil
+	})
+	return err
+}
+
+func (p *redisPublisher) Close() error {
+	return p.client.Close()
+}
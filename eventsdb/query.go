@@ -0,0 +1,148 @@
+package eventsdb
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Matltin/event-fetcher/query"
+	"gorm.io/gorm"
+)
+
+// Report is one of the shipped queries in query/, named after its file
+type Report struct {
+	Name        string `json:"name"`
+	Description string `json:"description"` // First comment line of the file
+}
+
+// QueryResult holds the columns and rows returned by a report or an ad hoc query
+type QueryResult struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// reportDir returns the directory of the report files written for storage
+func reportDir(storage string) string {
+	if storage == StorageSQLite {
+		return "sqlite"
+	}
+	return "."
+}
+
+// loadReports returns the reports available for storage ordered by name
+func loadReports(storage string) ([]Report, error) {
+	files, err := fs.Glob(query.Files, path.Join(reportDir(storage), "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	reports := make([]Report, 0, len(files))
+	for _, file := range files {
+		script, err := query.Files.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		report := Report{Name: strings.TrimSuffix(path.Base(file), ".sql")}
+		if line, _, _ := strings.Cut(string(script), "\n"); strings.HasPrefix(line, "--") {
+			report.Description = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// runReadOnly runs statement in a transaction that cannot write and is rolled back afterwards
+func runReadOnly(db *gorm.DB, storage, statement string) (*QueryResult, error) {
+	result := &QueryResult{}
+	err := db.Connection(func(conn *gorm.DB) error {
+		if storage == StorageSQLite {
+			if err := conn.Exec("PRAGMA query_only = ON").Error; err != nil {
+				return err
+			}
+			defer conn.Exec("PRAGMA query_only = OFF")
+		}
+
+		tx := conn.Begin()
+		if tx.Error != nil {
+			return tx.Error
+		}
+		defer tx.Rollback()
+
+		if storage == StoragePostgres {
+			if err := tx.Exec("SET TRANSACTION READ ONLY").Error; err != nil {
+				return err
+			}
+		}
+
+		// The driver rejects a script that ends in an empty statement
+		statement = strings.TrimRight(strings.TrimSpace(statement), ";")
+		rows, err := tx.Raw(statement).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if result.Columns, err = rows.Columns(); err != nil {
+			return err
+		}
+		for rows.Next() {
+			values := make([]interface{}, len(result.Columns))
+			pointers := make([]interface{}, len(values))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				return err
+			}
+			for i, value := range values {
+				switch v := value.(type) {
+				case []byte:
+					values[i] = string(v)
+				case time.Time:
+					values[i] = v.UTC().Format(time.RFC3339)
+				}
+			}
+			result.Rows = append(result.Rows, values)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return result, nil
+}
+
+// ListReports returns the shipped queries that run on the configured storage
+func (s *IndexerService) ListReports() ([]Report, error) {
+	return loadReports(s.config.Storage)
+}
+
+// RunReport runs the shipped query called name against the configured storage.
+// The reports read the rollup tables, without ROLLUPS they would be empty so it fails instead.
+func (s *IndexerService) RunReport(name string) (*QueryResult, error) {
+	if !s.config.Rollups {
+		return nil, fmt.Errorf("reports read the rollup tables, set ROLLUPS=true and run eventsdb rollups rebuild")
+	}
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	script, err := query.Files.ReadFile(path.Join(reportDir(s.config.Storage), name+".sql"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown report %q, see eventsdb query list", name)
+	}
+	return runReadOnly(s.db, s.config.Storage, string(script))
+}
+
+// Query runs a read only SQL statement against the configured storage
+func (s *IndexerService) Query(statement string) (*QueryResult, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	return runReadOnly(s.db, s.config.Storage, statement)
+}
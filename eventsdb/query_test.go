@@ -0,0 +1,31 @@
+package eventsdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunReport(t *testing.T) {
+	config := testSQLiteConfig(t)
+	s := openTestService(t, config)
+	writeTestRange(t, s.sink, 0, 59, timedTransfers(t, 0, 59, 2))
+
+	// Without rollups the reports would read empty tables
+	if _, err := s.RunReport("query3"); err == nil || !strings.Contains(err.Error(), "ROLLUPS=true") {
+		t.Fatalf("report without rollups returned error %v, want one asking for ROLLUPS=true", err)
+	}
+
+	config.Rollups = true
+	s = openTestService(t, config)
+	writeTestRange(t, s.sink, 60, 69, timedTransfers(t, 60, 69, 2))
+	result, err := s.RunReport("query6")
+	if err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][1] != int64(20) {
+		t.Fatalf("report returned %v, want the 20 events of one day", result.Rows)
+	}
+	if _, err := s.RunReport("query4"); err == nil || !strings.Contains(err.Error(), "unknown report") {
+		t.Fatalf("unknown report returned error %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package eventsdb
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// eventToLog rebuilds the original log of a stored event from its topics and raw data
+func eventToLog(event BlockchainEvent) (types.Log, error) {
+	data, err := hex.DecodeString(event.RawData)
+	if err != nil {
+		return types.Log{}, fmt.Errorf("invalid raw data for event %d: %w", event.ID, err)
+	}
+
+	topics := make([]common.Hash, 0, len(event.OtherTopics)+1)
+	if event.EventSignature != "" {
+		topics = append(topics, common.HexToHash(event.EventSignature))
+	}
+	for _, topic := range event.OtherTopics {
+		topics = append(topics, common.HexToHash(topic))
+	}
+
+	return types.Log{
+		Address:     common.HexToAddress(event.ContractAddress),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: event.BlockNumber,
+		TxHash:      common.HexToHash(event.TxHash),
+		TxIndex:     event.TxIndex,
+		BlockHash:   common.HexToHash(event.BlockHash),
+		Index:       event.LogIndex,
+		Removed:     event.Removed,
+	}, nil
+}
+
+// redecodeEvents re-decodes stored events with the given signature hashes from raw_data and topics.
+// Only events with a NULL event_name are touched unless all is set, binary tells how the hashes are stored.
+// Events stripped by a retention rule have no raw_data left and are skipped.
+func redecodeEvents(db *gorm.DB, sigs *signatureSet, signatures []string, batchSize int, all, binary bool) (int, error) {
+	if len(signatures) == 0 {
+		return 0, nil
+	}
+	if batchSize < 1 {
+		batchSize = DefaultRedecodeBatchSize
+	}
+
+	var lastID uint
+	var updated int
+	for {
+		query := db.Where("id > ? AND event_signature IN ? AND raw_data IS NOT NULL", lastID, hexParams(binary, signatures))
+		if !all {
+			query = query.Where("event_name IS NULL")
+		}
+
+		var events []BlockchainEvent
+		if err := query.Order("id").Limit(batchSize).Find(&events).Error; err != nil {
+			return updated, fmt.Errorf("failed to load events after id %d: %w", lastID, err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		var batchUpdated int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, event := range events {
+				eventLog, err := eventToLog(event)
+				if err != nil {
+					return err
+				}
+
+				sig, globalMatch := sigs.lookup(eventLog.Address, eventLog.BlockNumber, event.EventSignature)
+				if sig == nil {
+					continue
+				}
+
+				decodedParamsJSON, err := json.Marshal(decodeLogParams(eventLog, sig))
+				if err != nil {
+					return fmt.Errorf("failed to marshal decoded parameters: %w", err)
+				}
+
+				err = tx.Model(&BlockchainEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+					"event_name":           sig.Name,
+					"event_full_signature": sig.Signature,
+					"decoded_params":       decodedParamsJSON,
+					"global_abi_match":     globalMatch,
+				}).Error
+				if err != nil {
+					return fmt.Errorf("failed to update event %d: %w", event.ID, err)
+				}
+				batchUpdated++
+			}
+			return nil
+		})
+		if err != nil {
+			return updated, err
+		}
+
+		// Events no signature matched at their contract and block are left as they are and not counted
+		updated += batchUpdated
+		lastID = events[len(events)-1].ID
+		log.Printf("Re-decoded %d events (last id %d)\n", updated, lastID)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,46 @@
+package eventsdb
+
+import "testing"
+
+func TestRedecodeEvents(t *testing.T) {
+	sigHash, sig := testTransferSignature(t)
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 100, 109, testTransfers(t, nil, 100, 109, 2))
+
+	// Without a matching ABI nothing is updated or counted
+	updated, err := redecodeEvents(db, newSignatureSet(), []string{sigHash}, 3, false, false)
+	if err != nil {
+		t.Fatalf("redecode failed: %v", err)
+	}
+	if updated != 0 {
+		t.Fatalf("redecode without a matching ABI reported %d events, want 0", updated)
+	}
+
+	sigs := newSignatureSet()
+	sigs.global[sigHash] = sig
+	updated, err = redecodeEvents(db, sigs, []string{sigHash}, 3, false, false)
+	if err != nil {
+		t.Fatalf("redecode failed: %v", err)
+	}
+	if updated != 20 {
+		t.Fatalf("redecode reported %d events, want 20", updated)
+	}
+
+	want := testTransfers(t, &sig, 100, 109, 2)
+	for i, event := range storedEvents(t, db) {
+		if event.EventName == nil || *event.EventName != "Transfer" || !event.GlobalABIMatch {
+			t.Fatalf("event %d was not re-decoded as Transfer: %+v", i, event)
+		}
+		if string(event.DecodedParams) != string(want[i].DecodedParams) {
+			t.Fatalf("event %d decoded to %s, want %s", i, event.DecodedParams, want[i].DecodedParams)
+		}
+	}
+
+	// Decoded events are only touched again when all is set
+	if updated, err := redecodeEvents(db, sigs, []string{sigHash}, 3, false, false); err != nil || updated != 0 {
+		t.Fatalf("second redecode reported %d events (error %v), want 0", updated, err)
+	}
+	if updated, err := redecodeEvents(db, sigs, []string{sigHash}, 3, true, false); err != nil || updated != 20 {
+		t.Fatalf("redecode of all events reported %d events (error %v), want 20", updated, err)
+	}
+}
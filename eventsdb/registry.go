@@ -0,0 +1,106 @@
+package eventsdb
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// signatureSet is an immutable view of the known event signatures and contract bindings
+type signatureSet struct {
+	global   map[string]EventSignatureInfo            // First definition of each signature hash
+	bySource map[string]map[string]EventSignatureInfo // Definitions per ABI source file
+	bindings map[common.Address][]abiBinding          // ABI sources bound to specific contracts
+}
+
+func newSignatureSet() *signatureSet {
+	return &signatureSet{
+		global:   make(map[string]EventSignatureInfo),
+		bySource: make(map[string]map[string]EventSignatureInfo),
+		bindings: make(map[common.Address][]abiBinding),
+	}
+}
+
+// lookup finds the signature of a log emitted by contract at blockNumber.
+// ABIs bound to the contract win, the global map is the fallback and is reported by globalMatch.
+func (set *signatureSet) lookup(contract common.Address, blockNumber uint64, topicHex string) (eventSig *EventSignatureInfo, globalMatch bool) {
+	for _, binding := range set.bindings[contract] {
+		if !binding.covers(blockNumber) {
+			continue
+		}
+		if sig, exists := set.bySource[binding.SourceFile][topicHex]; exists {
+			return &sig, false
+		}
+	}
+
+	if sig, exists := set.global[topicHex]; exists {
+		return &sig, true
+	}
+
+	return nil, false
+}
+
+// signatureHashes returns every signature hash known to the set
+func (set *signatureSet) signatureHashes() []string {
+	hashes := make([]string, 0, len(set.global))
+	for sigHash := range set.global {
+		hashes = append(hashes, sigHash)
+	}
+	return hashes
+}
+
+// signatureRegistry holds the event signatures used for decoding.
+// Readers take an immutable snapshot, reloads publish a new set atomically.
+type signatureRegistry struct {
+	set atomic.Pointer[signatureSet]
+}
+
+func newSignatureRegistry() *signatureRegistry {
+	r := &signatureRegistry{}
+	r.Replace(newSignatureSet())
+	return r
+}
+
+// Snapshot returns the current signature set, callers must not modify it
+func (r *signatureRegistry) Snapshot() *signatureSet {
+	return r.set.Load()
+}
+
+// Replace publishes a new signature set and returns the hashes whose definitions are new or changed.
+// A change in contract bindings reports every hash since any stored event may decode differently.
+func (r *signatureRegistry) Replace(set *signatureSet) []string {
+	previous := r.set.Swap(set)
+	if previous == nil || !reflect.DeepEqual(previous.bindings, set.bindings) {
+		return set.signatureHashes()
+	}
+
+	var changed []string
+	for sigHash := range set.global {
+		if !sameDefinitions(previous, set, sigHash) {
+			changed = append(changed, sigHash)
+		}
+	}
+
+	return changed
+}
+
+// sameDefinitions reports whether a hash has identical definitions in every source of both sets
+func sameDefinitions(a, b *signatureSet, sigHash string) bool {
+	if !reflect.DeepEqual(a.global[sigHash].OriginalABI, b.global[sigHash].OriginalABI) {
+		return false
+	}
+
+	for source, sigs := range b.bySource {
+		sig, exists := sigs[sigHash]
+		if !exists {
+			continue
+		}
+		old, exists := a.bySource[source][sigHash]
+		if !exists || !reflect.DeepEqual(old.OriginalABI, sig.OriginalABI) {
+			return false
+		}
+	}
+
+	return true
+}
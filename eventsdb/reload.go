@@ -0,0 +1,123 @@
+package eventsdb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// reloadEventSignatures rebuilds the signature set from the database and publishes it.
+// Stored events of new or changed signatures are re-decoded when RedecodeOnReload is set.
+// The hashes of a reload that fails after publishing are kept and applied again by the next one.
+func (s *IndexerService) reloadEventSignatures() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	set, err := s.loadSignatureSet()
+	if err != nil {
+		return err
+	}
+
+	changed := s.sigs.Replace(set)
+	for _, sigHash := range s.unapplied {
+		if !slices.Contains(changed, sigHash) {
+			changed = append(changed, sigHash)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	log.Printf("Reloaded event signatures: %d new or changed\n", len(changed))
+	s.unapplied = changed
+
+	if s.projector != nil {
+		if err := s.projector.update(s.db, set); err != nil {
+			return err
+		}
+	}
+
+	if s.config.RedecodeOnReload {
+		updated, err := redecodeEvents(s.db, set, changed, DefaultRedecodeBatchSize, true, s.config.BinaryStorage)
+		if err != nil {
+			return fmt.Errorf("failed to re-decode events: %w", err)
+		}
+		log.Printf("Re-decoded %d stored events after reload\n", updated)
+
+		if s.projector != nil {
+			if _, err := s.projector.rebuild(s.db, changed, DefaultRedecodeBatchSize); err != nil {
+				return fmt.Errorf("failed to rebuild projections: %w", err)
+			}
+		}
+	}
+
+	s.unapplied = nil
+	return nil
+}
+
+// reloadABIDir stores the current content of AbiDir and reloads the signatures
+func (s *IndexerService) reloadABIDir() error {
+	if err := loadEventSignaturesOnDB(s.db, s.config.AbiDir); err != nil {
+		return fmt.Errorf("faild to store event on database: %w", err)
+	}
+
+	return s.reloadEventSignatures()
+}
+
+// watchABIDir polls AbiDir and reloads the signatures whenever a file is added, removed or modified, until ctx is done
+func (s *IndexerService) watchABIDir(ctx context.Context, interval time.Duration) {
+	lastState, err := abiDirState(s.config.AbiDir)
+	if err != nil {
+		log.Printf("Warning: Failed to read ABI directory %s: %v\n", s.config.AbiDir, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state, err := abiDirState(s.config.AbiDir)
+		if err != nil {
+			log.Printf("Warning: Failed to read ABI directory %s: %v\n", s.config.AbiDir, err)
+			continue
+		}
+		if state == lastState {
+			continue
+		}
+
+		log.Printf("ABI directory %s changed, reloading event signatures...\n", s.config.AbiDir)
+		if err := s.reloadABIDir(); err != nil {
+			log.Printf("Warning: Failed to reload ABI directory: %v\n", err)
+			continue
+		}
+		lastState = state
+	}
+}
+
+// abiDirState fingerprints the ABI files of a directory by path, size and modification time
+func abiDirState(abiDir string) (string, error) {
+	var state strings.Builder
+
+	err := filepath.Walk(abiDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		fmt.Fprintf(&state, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+
+	return state.String(), err
+}
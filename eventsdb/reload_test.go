@@ -0,0 +1,98 @@
+package eventsdb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testTransferDeclaration is testTransferABI as a human-readable declaration
+const testTransferDeclaration = "event Transfer(address indexed from, address indexed to, uint256 value)"
+
+// postTestABI uploads body to the admin ABI endpoint of s and returns the response
+func postTestABI(t *testing.T, s *IndexerService, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/admin/abi", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.config.AdminToken)
+	recorder := httptest.NewRecorder()
+	s.httpHandler().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestReloadRetriesFailedHashes(t *testing.T) {
+	sigHash, _ := testTransferSignature(t)
+	config := testSQLiteConfig(t)
+	config.Projections = true
+	config.RedecodeOnReload = true
+	s := openTestService(t, config)
+	writeTestRange(t, s.sink, 100, 109, testTransfers(t, nil, 100, 109, 1))
+
+	// A view in the way of the projection table makes the reload fail after the new signatures are published
+	table := projectionTableName("Transfer", sigHash)
+	if err := s.db.Exec("CREATE VIEW " + table + " AS SELECT 1 AS other").Error; err != nil {
+		t.Fatalf("failed to create %s: %v", table, err)
+	}
+	events, err := parseABIData([]byte(testTransferDeclaration))
+	if err != nil {
+		t.Fatalf("failed to parse the Transfer declaration: %v", err)
+	}
+	if _, err := storeABIEvents(s.db, events, "erc20.abi"); err != nil {
+		t.Fatalf("failed to store the Transfer ABI: %v", err)
+	}
+	if err := s.reloadEventSignatures(); err == nil {
+		t.Fatal("reload succeeded although the projection table could not be created")
+	}
+	if event := storedEvents(t, s.db)[0]; event.EventName != nil {
+		t.Fatalf("event was re-decoded as %s by the failed reload", *event.EventName)
+	}
+
+	// The next reload finds no new definition but applies the failed hashes again
+	if err := s.db.Exec("DROP VIEW " + table).Error; err != nil {
+		t.Fatalf("failed to drop %s: %v", table, err)
+	}
+	if err := s.reloadEventSignatures(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	for _, event := range storedEvents(t, s.db) {
+		if event.EventName == nil || *event.EventName != "Transfer" {
+			t.Fatalf("event of block %d was not re-decoded after the retry", event.BlockNumber)
+		}
+	}
+	var rows int64
+	if err := s.db.Table(table).Count(&rows).Error; err != nil || rows != 10 {
+		t.Fatalf("%s has %d rows (error %v), want 10", table, rows, err)
+	}
+	if len(s.unapplied) != 0 {
+		t.Fatalf("hashes %v are still unapplied after a successful reload", s.unapplied)
+	}
+}
+
+func TestABIUpload(t *testing.T) {
+	config := testSQLiteConfig(t)
+	config.AdminToken = "secret"
+	s := openTestService(t, config)
+
+	if res := postTestABI(t, s, testTransferDeclaration); res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"written":1`) {
+		t.Fatalf("upload answered %d %s, want 200 with one written event", res.Code, res.Body)
+	}
+
+	// An oversized body is refused instead of being cut and parsed in part
+	oversized := strings.Repeat(testTransferDeclaration+"\n", maxABIUploadSize/len(testTransferDeclaration)+1)
+	if res := postTestABI(t, s, oversized); res.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized upload answered %d %s, want 413", res.Code, res.Body)
+	}
+	if res := postTestABI(t, s, "event Transfer(address"); res.Code != http.StatusBadRequest {
+		t.Fatalf("malformed upload answered %d %s, want 400", res.Code, res.Body)
+	}
+
+	// Database errors fail the upload instead of reporting fewer written events
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		t.Fatalf("failed to get the database handle: %v", err)
+	}
+	sqlDB.Close()
+	if res := postTestABI(t, s, "event Approval(address indexed owner, address indexed spender, uint256 value)"); res.Code != http.StatusInternalServerError {
+		t.Fatalf("upload to a closed database answered %d %s, want 500", res.Code, res.Body)
+	}
+}
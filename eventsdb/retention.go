@@ -0,0 +1,463 @@
+package eventsdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+)
+
+// DefaultArchiveBlocks is the block span of one archive file
+const DefaultArchiveBlocks = 100_000
+
+// Retention actions
+const (
+	RetentionArchive = "archive" // Move the events to a gzip JSON Lines file and delete them
+	RetentionStrip   = "strip"   // Drop raw_data of decoded events and keep the decoded fields
+)
+
+// unsafeFileChars are replaced in rule names used as archive file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// RetentionRule moves or strips the events of a contract or event once they are old enough.
+// Events older than AfterBlocks behind the indexer cursor or older than AfterDays are affected,
+// with both set the rule only applies to events that are old by both measures.
+type RetentionRule struct {
+	Name        string `json:"name"`
+	Contract    string `json:"contract,omitempty"` // Empty matches every contract
+	Event       string `json:"event,omitempty"`    // Empty matches every event
+	AfterBlocks uint64 `json:"afterBlocks,omitempty"`
+	AfterDays   int    `json:"afterDays,omitempty"`
+	Action      string `json:"action"`
+}
+
+// RetentionResult reports what one rule did in a retention run
+type RetentionResult struct {
+	Rule        string `json:"rule"`
+	CutoffBlock uint64 `json:"cutoffBlock"`
+	Archived    int64  `json:"archived"`
+	Stripped    int64  `json:"stripped"`
+	Files       int    `json:"files"`
+}
+
+// loadRetentionRules reads {"rules": [...]} from path, a missing file means no rules
+func loadRetentionRules(path string) ([]RetentionRule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention rules: %w", err)
+	}
+
+	var file struct {
+		Rules []RetentionRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse retention rules %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("retention rule without a name in %s", path)
+		case names[rule.Name]:
+			return nil, fmt.Errorf("duplicate retention rule %q", rule.Name)
+		case rule.Action != RetentionArchive && rule.Action != RetentionStrip:
+			return nil, fmt.Errorf("retention rule %q has unknown action %q (available: archive, strip)", rule.Name, rule.Action)
+		case rule.AfterBlocks == 0 && rule.AfterDays <= 0:
+			return nil, fmt.Errorf("retention rule %q needs afterBlocks or afterDays", rule.Name)
+		case rule.Contract != "" && !common.IsHexAddress(rule.Contract):
+			return nil, fmt.Errorf("retention rule %q has invalid contract address %q", rule.Name, rule.Contract)
+		}
+		if rule.Contract != "" {
+			// Addresses are stored checksummed
+			rule.Contract = common.HexToAddress(rule.Contract).Hex()
+		}
+		names[rule.Name] = true
+	}
+
+	return file.Rules, nil
+}
+
+// retention applies the retention rules to the stored events
+type retention struct {
+	db            *gorm.DB
+	client        *ethclient.Client // Only needed by rules with AfterDays
+	binary        bool
+	archiveDir    string
+	archiveBlocks uint64
+	maxRetries    int
+	retryDelay    time.Duration
+}
+
+// cutoffBlock returns the last block a rule applies to, ok is false when no block is old enough
+func (r *retention) cutoffBlock(ctx context.Context, rule RetentionRule, indexed uint64) (uint64, bool, error) {
+	cutoff := indexed
+	if rule.AfterBlocks > 0 {
+		if rule.AfterBlocks >= indexed {
+			return 0, false, nil
+		}
+		cutoff = indexed - rule.AfterBlocks
+	}
+
+	if rule.AfterDays > 0 {
+		if r.client == nil {
+			return 0, false, fmt.Errorf("retention rule %q uses afterDays and needs an RPC connection", rule.Name)
+		}
+		block, err := blockBefore(ctx, r.client, time.Now().AddDate(0, 0, -rule.AfterDays), indexed, r.maxRetries, r.retryDelay)
+		if err != nil {
+			return 0, false, err
+		}
+		if block == 0 {
+			return 0, false, nil
+		}
+		cutoff = min(cutoff, block)
+	}
+
+	return cutoff, true, nil
+}
+
+// blockBefore returns the last block at or below latest with a timestamp before t, 0 if there is none
+func blockBefore(ctx context.Context, client *ethclient.Client, t time.Time, latest uint64, maxRetries int, retryDelay time.Duration) (uint64, error) {
+	low, high := uint64(0), latest
+	for low < high {
+		mid := low + (high-low+1)/2
+		header, err := fetchHeader(ctx, client, new(big.Int).SetUint64(mid), maxRetries, retryDelay)
+		if err != nil {
+			return 0, err
+		}
+		if int64(header.Time) < t.Unix() {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low, nil
+}
+
+// ruleQuery restricts a query to the events of a rule up to cutoff
+func (r *retention) ruleQuery(tx *gorm.DB, rule RetentionRule, cutoff uint64) *gorm.DB {
+	query := tx.Model(&BlockchainEvent{}).Where("block_number <= ?", cutoff)
+	if rule.Contract != "" {
+		query = query.Where("contract_address = ?", hexParam(r.binary, rule.Contract))
+	}
+	if rule.Event != "" {
+		query = query.Where("event_name = ?", rule.Event)
+	}
+	return query
+}
+
+// apply runs one rule up to the indexer cursor
+func (r *retention) apply(ctx context.Context, rule RetentionRule, indexed uint64) (*RetentionResult, error) {
+	result := &RetentionResult{Rule: rule.Name}
+
+	cutoff, ok, err := r.cutoffBlock(ctx, rule, indexed)
+	if err != nil || !ok {
+		return result, err
+	}
+	result.CutoffBlock = cutoff
+
+	if rule.Action == RetentionStrip {
+		// Raw data becomes NULL so redecode can tell stripped events from events without data
+		update := r.ruleQuery(r.db, rule, cutoff).
+			Where("event_name IS NOT NULL AND raw_data IS NOT NULL").
+			Update("raw_data", gorm.Expr("NULL"))
+		if update.Error != nil {
+			return result, fmt.Errorf("failed to strip raw data: %w", update.Error)
+		}
+		result.Stripped = update.RowsAffected
+		return result, nil
+	}
+
+	for {
+		var first *uint64
+		if err := r.ruleQuery(r.db, rule, cutoff).Select("MIN(block_number)").Scan(&first).Error; err != nil {
+			return result, fmt.Errorf("failed to find events to archive: %w", err)
+		}
+		if first == nil {
+			return result, nil
+		}
+
+		to := min(*first+r.archiveBlocks-1, cutoff)
+		archived, err := r.archiveRange(rule, *first, to)
+		if err != nil {
+			return result, err
+		}
+		result.Archived += archived
+		result.Files++
+	}
+}
+
+// archiveRange writes the events of a rule in [fromBlock, toBlock] to a gzip JSON Lines file, then deletes them
+// and records the file in archived_ranges in one transaction.
+func (r *retention) archiveRange(rule RetentionRule, fromBlock, toBlock uint64) (int64, error) {
+	name := fmt.Sprintf("%s-%012d-%012d.jsonl.gz", unsafeFileChars.ReplaceAllString(rule.Name, "_"), fromBlock, toBlock)
+	path := filepath.Join(r.archiveDir, name)
+
+	count, err := r.writeArchive(path, rule, fromBlock, toBlock)
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		deleted := r.ruleQuery(tx, rule, toBlock).Where("block_number >= ?", fromBlock).Delete(&BlockchainEvent{})
+		if deleted.Error != nil {
+			return fmt.Errorf("failed to delete archived events: %w", deleted.Error)
+		}
+		if deleted.RowsAffected != count {
+			return fmt.Errorf("archived %d events of blocks %d to %d but %d matched on delete", count, fromBlock, toBlock, deleted.RowsAffected)
+		}
+
+		return tx.Create(&ArchivedRange{
+			Rule:            rule.Name,
+			ContractAddress: rule.Contract,
+			EventName:       rule.Event,
+			FromBlock:       fromBlock,
+			ToBlock:         toBlock,
+			Events:          count,
+			File:            path,
+			ArchivedAt:      time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Archived %d events of blocks %d to %d to %s\n", count, fromBlock, toBlock, path)
+	return count, nil
+}
+
+// writeArchive streams the events of a rule in [fromBlock, toBlock] to a gzip JSON Lines file and syncs it
+func (r *retention) writeArchive(path string, rule RetentionRule, fromBlock, toBlock uint64) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer file.Close()
+
+	compressed := gzip.NewWriter(file)
+	encoder := json.NewEncoder(compressed)
+
+	var count int64
+	var lastID uint
+	for {
+		var events []BlockchainEvent
+		err := r.ruleQuery(r.db, rule, toBlock).
+			Where("block_number >= ? AND id > ?", fromBlock, lastID).
+			Order("id").Limit(DefaultExportBatchSize).Find(&events).Error
+		if err != nil {
+			return count, fmt.Errorf("failed to load events to archive: %w", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for i := range events {
+			record := newEventRecord(&events[i])
+			if err := encoder.Encode(&record); err != nil {
+				return count, fmt.Errorf("failed to write archive file: %w", err)
+			}
+		}
+		count += int64(len(events))
+		lastID = events[len(events)-1].ID
+	}
+
+	if err := compressed.Close(); err != nil {
+		return count, fmt.Errorf("failed to write archive file: %w", err)
+	}
+	// The events are deleted next, the archive must be on disk first
+	if err := file.Sync(); err != nil {
+		return count, fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	return count, nil
+}
+
+// readArchive loads the events of an archive file
+func readArchive(path string) ([]BlockchainEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file %s: %w", path, err)
+	}
+
+	var events []BlockchainEvent
+	decoder := json.NewDecoder(bufio.NewReader(compressed))
+	for decoder.More() {
+		var record EventRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("failed to parse archive file %s: %w", path, err)
+		}
+		events = append(events, record.event())
+	}
+	return events, nil
+}
+
+// event turns an exported record back into a stored event
+func (r *EventRecord) event() BlockchainEvent {
+	return BlockchainEvent{
+		TxHash:             r.TxHash,
+		TxIndex:            uint(r.TxIndex),
+		BlockNumber:        r.BlockNumber,
+		BlockHash:          r.BlockHash,
+		LogIndex:           uint(r.LogIndex),
+		Removed:            r.Removed,
+		ContractAddress:    r.ContractAddress,
+		EventSignature:     r.EventSignature,
+		EventName:          r.EventName,
+		EventFullSignature: r.EventFullSignature,
+		OtherTopics:        StringArray(r.OtherTopics),
+		RawData:            r.RawData,
+		DecodedParams:      r.DecodedParams,
+		GlobalABIMatch:     r.GlobalABIMatch,
+		InsertTime:         r.InsertTime,
+	}
+}
+
+// newRetention prepares a retention run, connecting to the RPC endpoint only when a rule needs block times
+func (s *IndexerService) newRetention(ctx context.Context, rules []RetentionRule) (*retention, error) {
+	if err := os.MkdirAll(s.config.ArchiveDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	r := &retention{
+		db:            s.db,
+		client:        s.client,
+		binary:        s.config.BinaryStorage,
+		archiveDir:    s.config.ArchiveDir,
+		archiveBlocks: DefaultArchiveBlocks,
+		maxRetries:    s.config.MaxRetries,
+		retryDelay:    s.config.RetryDelay,
+	}
+
+	for _, rule := range rules {
+		if rule.AfterDays > 0 && r.client == nil {
+			if err := s.connectToBlockchain(ctx); err != nil {
+				return nil, fmt.Errorf("failed to connect to blockchain: %w", err)
+			}
+			r.client = s.client
+			break
+		}
+	}
+	return r, nil
+}
+
+// ApplyRetention archives or strips the events matched by the rules in RetentionFile, it stops between rules once ctx is done
+func (s *IndexerService) ApplyRetention(ctx context.Context) ([]RetentionResult, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	rules, err := loadRetentionRules(s.config.RetentionFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	cursor, ok, err := s.sink.Cursor()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	r, err := s.newRetention(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []RetentionResult
+	for _, rule := range rules {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, err := r.apply(ctx, rule, uint64(cursor.Count))
+		if err != nil {
+			return results, fmt.Errorf("retention rule %q failed: %w", rule.Name, err)
+		}
+		if result.Archived > 0 || result.Stripped > 0 {
+			log.Printf("Retention rule %s: archived %d and stripped %d events up to block %d\n", rule.Name, result.Archived, result.Stripped, result.CutoffBlock)
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// ListArchivedRanges returns the archive files written by retention rules
+func (s *IndexerService) ListArchivedRanges() ([]ArchivedRange, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	var ranges []ArchivedRange
+	if err := s.db.Order("from_block, id").Find(&ranges).Error; err != nil {
+		return nil, fmt.Errorf("failed to query archived ranges: %w", err)
+	}
+	return ranges, nil
+}
+
+// RestoreArchivedRange imports the events of an archive file back into blockchain_events
+func (s *IndexerService) RestoreArchivedRange(id uint) (int, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return 0, err
+	}
+
+	var archived ArchivedRange
+	if err := s.db.First(&archived, id).Error; err != nil {
+		return 0, fmt.Errorf("archived range %d: %w", id, err)
+	}
+
+	events, err := readArchive(archived.File)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.sink.Import(events); err != nil {
+		return 0, fmt.Errorf("failed to restore events: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if err := s.db.Model(&archived).Update("restored_at", now).Error; err != nil {
+		return 0, fmt.Errorf("failed to mark archived range %d restored: %w", id, err)
+	}
+
+	log.Printf("Restored %d events of blocks %d to %d from %s\n", len(events), archived.FromBlock, archived.ToBlock, archived.File)
+	return len(events), nil
+}
+
+// runRetention applies the retention rules every interval until ctx is done
+func (s *IndexerService) runRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.ApplyRetention(ctx); err != nil {
+			log.Printf("Warning: Failed to apply retention rules: %v\n", err)
+		}
+	}
+}
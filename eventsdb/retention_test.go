@@ -0,0 +1,121 @@
+package eventsdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestService connects a service with config to its database, the sink is closed when the test ends
+func openTestService(t *testing.T, config Config) *IndexerService {
+	t.Helper()
+	s := NewIndexerService(config)
+	if err := s.ensureDatabase(); err != nil {
+		t.Fatalf("failed to open %s database: %v", config.Storage, err)
+	}
+	t.Cleanup(func() { s.sink.Close() })
+	return s
+}
+
+// writeRetentionRules writes rules JSON to a file in the test directory and returns its path
+func writeRetentionRules(t *testing.T, rules string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "retention.json")
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatalf("failed to write retention rules: %v", err)
+	}
+	return path
+}
+
+func TestLoadRetentionRules(t *testing.T) {
+	rules, err := loadRetentionRules(writeRetentionRules(t, `{"rules": [{"name": "old", "contract": "`+strings.ToLower(testContract)+`", "afterBlocks": 10, "action": "archive"}]}`))
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Contract != testContract {
+		t.Fatalf("loaded %+v, want the contract checksummed", rules)
+	}
+
+	invalid := []string{
+		`{"rules": [{"afterBlocks": 10, "action": "archive"}]}`,
+		`{"rules": [{"name": "old", "afterBlocks": 10, "action": "delete"}]}`,
+		`{"rules": [{"name": "old", "action": "archive"}]}`,
+		`{"rules": [{"name": "old", "contract": "0x1234", "afterBlocks": 10, "action": "archive"}]}`,
+		`{"rules": [{"name": "old", "afterBlocks": 10, "action": "archive"}, {"name": "old", "afterDays": 1, "action": "strip"}]}`,
+	}
+	for _, rules := range invalid {
+		if _, err := loadRetentionRules(writeRetentionRules(t, rules)); err == nil {
+			t.Fatalf("loaded invalid rules %s", rules)
+		}
+	}
+
+	if rules, err := loadRetentionRules(filepath.Join(t.TempDir(), "missing.json")); err != nil || rules != nil {
+		t.Fatalf("missing file loaded as %+v (error %v), want no rules", rules, err)
+	}
+}
+
+func TestRetentionArchiveAndRestore(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	config := testSQLiteConfig(t)
+	config.ArchiveDir = filepath.Join(t.TempDir(), "archive")
+	contract := strings.ToLower(testContract)
+	config.RetentionFile = writeRetentionRules(t, `{"rules": [
+		{"name": "old transfers", "contract": "`+contract+`", "event": "Transfer", "afterBlocks": 50, "action": "archive"},
+		{"name": "recent transfers", "contract": "`+contract+`", "afterBlocks": 20, "action": "strip"}
+	]}`)
+	s := openTestService(t, config)
+
+	writeTestRange(t, s.sink, 100, 199, testTransfers(t, &sig, 100, 199, 2))
+	before := storedEvents(t, s.db)
+
+	results, err := s.ApplyRetention(context.Background())
+	if err != nil {
+		t.Fatalf("retention failed: %v", err)
+	}
+	if len(results) != 2 || results[0].CutoffBlock != 149 || results[0].Archived != 100 || results[0].Files != 1 ||
+		results[1].CutoffBlock != 179 || results[1].Stripped != 60 {
+		t.Fatalf("retention results %+v, want 100 events of blocks 100 to 149 archived and 60 up to block 179 stripped", results)
+	}
+
+	stored := storedEvents(t, s.db)
+	if len(stored) != 100 || stored[0].BlockNumber != 150 {
+		t.Fatalf("%d events left starting at block %d, want 100 from block 150", len(stored), stored[0].BlockNumber)
+	}
+	for _, event := range stored {
+		if stripped := event.BlockNumber <= 179; stripped != (event.RawData == "") || len(event.DecodedParams) == 0 {
+			t.Fatalf("event of block %d has raw data %q and parameters %s", event.BlockNumber, event.RawData, event.DecodedParams)
+		}
+	}
+
+	ranges, err := s.ListArchivedRanges()
+	if err != nil {
+		t.Fatalf("failed to list archived ranges: %v", err)
+	}
+	if len(ranges) != 1 || ranges[0].ContractAddress != testContract || ranges[0].FromBlock != 100 || ranges[0].ToBlock != 149 || ranges[0].Events != 100 {
+		t.Fatalf("archived ranges %+v, want blocks 100 to 149 of the checksummed contract", ranges)
+	}
+
+	// A second run finds nothing left to archive
+	if results, err := s.ApplyRetention(context.Background()); err != nil || results[0].Archived != 0 || results[1].Stripped != 0 {
+		t.Fatalf("second run returned %+v (error %v), want nothing archived or stripped", results, err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ApplyRetention(cancelled); err != context.Canceled {
+		t.Fatalf("run with a cancelled context returned %v", err)
+	}
+
+	restored, err := s.RestoreArchivedRange(ranges[0].ID)
+	if err != nil || restored != 100 {
+		t.Fatalf("restored %d events (error %v), want 100", restored, err)
+	}
+	after := storedEvents(t, s.db)
+	if len(after) != len(before) {
+		t.Fatalf("%d events stored after the restore, want %d", len(after), len(before))
+	}
+	assertSameRecords(t, exportedRecords(t, after[:100]), exportedRecords(t, before[:100]))
+	assertCursor(t, s.sink, 199, testBlockHash(199).Hex())
+}
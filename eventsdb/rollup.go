@@ -0,0 +1,390 @@
+package eventsdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rollupBatchSize is the number of rows inserted per statement into a rollup table
+const rollupBatchSize = 500
+
+// rollupRebuildBlocks is the block span counted and timed in one step of a rollup rebuild
+const rollupRebuildBlocks = 10_000
+
+// Rollup tables of hourly and daily event counts
+const (
+	rollupHourlyTable = "event_counts_hourly"
+	rollupDailyTable  = "event_counts_daily"
+)
+
+// rollupKey identifies the events of one contract and signature in a block or period
+type rollupKey struct {
+	Contract  string
+	Signature string
+}
+
+// rollups keeps event_counts_block, event_counts_hourly and event_counts_daily in step with blockchain_events.
+// Block counts are written from the events of every range, hourly counts are recomputed from the block counts
+// of the touched hours and daily counts from the hourly counts, so rewrites and rollbacks never double count.
+type rollups struct{}
+
+func (rollups) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	counts := make(map[uint64]map[rollupKey]*BlockEventCount)
+	since := events[0].BlockTime
+	for i := range events {
+		event := &events[i]
+		if event.BlockTime.IsZero() {
+			return fmt.Errorf("event %s:%d has no block time", event.TxHash, event.LogIndex)
+		}
+		if event.BlockTime.Before(since) {
+			since = event.BlockTime
+		}
+
+		blockCounts, ok := counts[event.BlockNumber]
+		if !ok {
+			blockCounts = make(map[rollupKey]*BlockEventCount)
+			counts[event.BlockNumber] = blockCounts
+		}
+		key := rollupKey{Contract: event.ContractAddress, Signature: event.EventSignature}
+		if count, ok := blockCounts[key]; ok {
+			count.Events++
+			continue
+		}
+		blockCounts[key] = &BlockEventCount{
+			BlockNumber:     event.BlockNumber,
+			ContractAddress: key.Contract,
+			EventSignature:  key.Signature,
+			BlockTime:       event.BlockTime.UTC(),
+			Events:          1,
+		}
+	}
+
+	rows := make([]*BlockEventCount, 0, len(counts))
+	for _, blockCounts := range counts {
+		for _, count := range blockCounts {
+			rows = append(rows, count)
+		}
+	}
+
+	// A block is always written by a single range, so its counts replace what a previous write of it stored
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "block_number"}, {Name: "contract_address"}, {Name: "event_signature"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_time", "events"}),
+	}).CreateInBatches(rows, rollupBatchSize).Error
+	if err != nil {
+		return fmt.Errorf("failed to store block event counts: %w", err)
+	}
+
+	return refreshRollups(tx, since)
+}
+
+func (rollups) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	// The earliest row is loaded instead of MIN(block_time), SQLite returns aggregated timestamps as text
+	var first []BlockEventCount
+	if err := tx.Where("block_number >= ?", fromBlock).Order("block_time").Limit(1).Find(&first).Error; err != nil {
+		return fmt.Errorf("failed to query rolled back block event counts: %w", err)
+	}
+	if len(first) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("block_number >= ?", fromBlock).Delete(&BlockEventCount{}).Error; err != nil {
+		return fmt.Errorf("failed to roll back block event counts: %w", err)
+	}
+	return refreshRollups(tx, first[0].BlockTime)
+}
+
+// refreshRollups recomputes the hourly counts from the hour of since and the daily counts from its day on
+func refreshRollups(tx *gorm.DB, since time.Time) error {
+	return refreshRollupsUntil(tx, since, time.Time{})
+}
+
+// refreshRollupsUntil recomputes the hourly and daily counts from since up to until, a day boundary.
+// A zero until recomputes them up to the last block.
+func refreshRollupsUntil(tx *gorm.DB, since, until time.Time) error {
+	hour := since.UTC().Truncate(time.Hour)
+	day := since.UTC().Truncate(24 * time.Hour)
+
+	var blocks []BlockEventCount
+	query := tx.Where("block_time >= ?", hour)
+	if !until.IsZero() {
+		query = query.Where("block_time < ?", until)
+	}
+	if err := query.Find(&blocks).Error; err != nil {
+		return fmt.Errorf("failed to load block event counts: %w", err)
+	}
+	hourly := make([]PeriodEventCount, 0, len(blocks))
+	for _, block := range blocks {
+		hourly = append(hourly, PeriodEventCount{
+			Bucket:          block.BlockTime.UTC().Truncate(time.Hour),
+			ContractAddress: block.ContractAddress,
+			EventSignature:  block.EventSignature,
+			Events:          block.Events,
+			FirstBlock:      block.BlockNumber,
+			LastBlock:       block.BlockNumber,
+		})
+	}
+	if err := replacePeriodCounts(tx, rollupHourlyTable, hour, until, mergePeriodCounts(hourly, time.Hour)); err != nil {
+		return err
+	}
+
+	var hours []PeriodEventCount
+	query = tx.Table(rollupHourlyTable).Where("bucket >= ?", day)
+	if !until.IsZero() {
+		query = query.Where("bucket < ?", until)
+	}
+	if err := query.Find(&hours).Error; err != nil {
+		return fmt.Errorf("failed to load hourly event counts: %w", err)
+	}
+	return replacePeriodCounts(tx, rollupDailyTable, day, until, mergePeriodCounts(hours, 24*time.Hour))
+}
+
+// mergePeriodCounts sums counts into buckets of period, ordered by bucket, contract and signature
+func mergePeriodCounts(counts []PeriodEventCount, period time.Duration) []PeriodEventCount {
+	type periodKey struct {
+		Bucket time.Time
+		rollupKey
+	}
+
+	merged := make(map[periodKey]*PeriodEventCount)
+	for _, count := range counts {
+		bucket := count.Bucket.UTC().Truncate(period)
+		key := periodKey{Bucket: bucket, rollupKey: rollupKey{Contract: count.ContractAddress, Signature: count.EventSignature}}
+
+		total, ok := merged[key]
+		if !ok {
+			count.Bucket = bucket
+			merged[key] = &count
+			continue
+		}
+		total.Events += count.Events
+		total.FirstBlock = min(total.FirstBlock, count.FirstBlock)
+		total.LastBlock = max(total.LastBlock, count.LastBlock)
+	}
+
+	result := make([]PeriodEventCount, 0, len(merged))
+	for _, count := range merged {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Bucket.Equal(result[j].Bucket) {
+			return result[i].Bucket.Before(result[j].Bucket)
+		}
+		if result[i].ContractAddress != result[j].ContractAddress {
+			return result[i].ContractAddress < result[j].ContractAddress
+		}
+		return result[i].EventSignature < result[j].EventSignature
+	})
+	return result
+}
+
+// replacePeriodCounts replaces the rows of table from bucket since on, and before until unless it is zero, with counts
+func replacePeriodCounts(tx *gorm.DB, table string, since, until time.Time, counts []PeriodEventCount) error {
+	query := tx.Table(table).Where("bucket >= ?", since)
+	if !until.IsZero() {
+		query = query.Where("bucket < ?", until)
+	}
+	if err := query.Delete(&PeriodEventCount{}).Error; err != nil {
+		return fmt.Errorf("failed to clear %s: %w", table, err)
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := tx.Table(table).CreateInBatches(counts, rollupBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to store %s: %w", table, err)
+	}
+	return nil
+}
+
+// fillBlockTimes sets the block time of events whose log did not carry one, fetching the blocks in batched calls
+func fillBlockTimes(ctx context.Context, client *ethclient.Client, events []BlockchainEvent, maxRetries int, retryDelay time.Duration) error {
+	var blocks []uint64
+	for i := range events {
+		if events[i].BlockTime.IsZero() && (len(blocks) == 0 || blocks[len(blocks)-1] != events[i].BlockNumber) {
+			blocks = append(blocks, events[i].BlockNumber)
+		}
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	times, err := fetchBlockTimes(ctx, client, blocks, maxRetries, retryDelay)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		if events[i].BlockTime.IsZero() {
+			events[i].BlockTime = times[events[i].BlockNumber]
+		}
+	}
+	return nil
+}
+
+// rebuildRollups recomputes every rollup table from blockchain_events, block times are fetched from the chain.
+// Block counts are replaced rollupRebuildBlocks blocks at a time, then hourly and daily counts a day at a time,
+// so readers see old or new counts of a range but never none. Events already removed by a retention rule
+// are no longer counted afterwards.
+func rebuildRollups(ctx context.Context, db *gorm.DB, client *ethclient.Client, maxRetries int, retryDelay time.Duration) (int, error) {
+	db = db.WithContext(ctx)
+	var bounds struct {
+		FirstBlock *uint64
+		LastBlock  *uint64
+	}
+	if err := db.Model(&BlockchainEvent{}).Select("MIN(block_number) AS first_block, MAX(block_number) AS last_block").Scan(&bounds).Error; err != nil {
+		return 0, fmt.Errorf("failed to query stored blocks: %w", err)
+	}
+
+	rows := 0
+	if bounds.FirstBlock != nil {
+		for from := *bounds.FirstBlock; from <= *bounds.LastBlock; from += rollupRebuildBlocks {
+			to := min(from+rollupRebuildBlocks-1, *bounds.LastBlock)
+			written, err := rebuildBlockCounts(ctx, db, client, from, to, maxRetries, retryDelay)
+			if err != nil {
+				return rows, err
+			}
+			rows += written
+			log.Printf("Rebuilt block event counts up to block %d of %d\n", to, *bounds.LastBlock)
+		}
+	}
+
+	// Counts of blocks outside the stored range have no events left
+	stale := db.Where("1 = 1")
+	if bounds.FirstBlock != nil {
+		stale = db.Where("block_number < ? OR block_number > ?", *bounds.FirstBlock, *bounds.LastBlock)
+	}
+	if err := stale.Delete(&BlockEventCount{}).Error; err != nil {
+		return rows, fmt.Errorf("failed to clear block event counts: %w", err)
+	}
+
+	return rows, rebuildPeriodCounts(ctx, db)
+}
+
+// rebuildBlockCounts replaces the block counts of [fromBlock, toBlock] with counts of the stored events
+func rebuildBlockCounts(ctx context.Context, db *gorm.DB, client *ethclient.Client, fromBlock, toBlock uint64, maxRetries int, retryDelay time.Duration) (int, error) {
+	type blockCount struct {
+		BlockNumber     uint64
+		ContractAddress string `gorm:"serializer:address"`
+		EventSignature  string `gorm:"serializer:hash"`
+		Events          int64
+	}
+
+	var counts []blockCount
+	err := db.WithContext(ctx).Model(&BlockchainEvent{}).
+		Select("block_number, contract_address, event_signature, COUNT(*) AS events").
+		Where("block_number BETWEEN ? AND ?", fromBlock, toBlock).
+		Group("block_number, contract_address, event_signature").
+		Order("block_number").
+		Scan(&counts).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count stored events: %w", err)
+	}
+
+	var blocks []uint64
+	for i, count := range counts {
+		if i == 0 || count.BlockNumber != counts[i-1].BlockNumber {
+			blocks = append(blocks, count.BlockNumber)
+		}
+	}
+	times, err := fetchBlockTimes(ctx, client, blocks, maxRetries, retryDelay)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([]BlockEventCount, 0, len(counts))
+	for _, count := range counts {
+		rows = append(rows, BlockEventCount{
+			BlockNumber:     count.BlockNumber,
+			ContractAddress: count.ContractAddress,
+			EventSignature:  count.EventSignature,
+			BlockTime:       times[count.BlockNumber],
+			Events:          count.Events,
+		})
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("block_number BETWEEN ? AND ?", fromBlock, toBlock).Delete(&BlockEventCount{}).Error; err != nil {
+			return fmt.Errorf("failed to clear block event counts: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(rows, rollupBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to store block event counts: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// rebuildPeriodCounts recomputes the hourly and daily counts from the block counts one day at a time
+func rebuildPeriodCounts(ctx context.Context, db *gorm.DB) error {
+	// The earliest and latest rows are loaded instead of MIN and MAX, SQLite returns aggregated timestamps as text
+	var first, last []BlockEventCount
+	if err := db.Order("block_time").Limit(1).Find(&first).Error; err != nil {
+		return fmt.Errorf("failed to query block event counts: %w", err)
+	}
+	if err := db.Order("block_time DESC").Limit(1).Find(&last).Error; err != nil {
+		return fmt.Errorf("failed to query block event counts: %w", err)
+	}
+
+	for _, table := range []string{rollupHourlyTable, rollupDailyTable} {
+		stale := db.Table(table).Where("1 = 1")
+		if len(first) > 0 {
+			stale = db.Table(table).Where("bucket < ? OR bucket >= ?",
+				first[0].BlockTime.UTC().Truncate(24*time.Hour), last[0].BlockTime.UTC().Truncate(24*time.Hour).Add(24*time.Hour))
+		}
+		if err := stale.Delete(&PeriodEventCount{}).Error; err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	if len(first) == 0 {
+		return nil
+	}
+
+	for day := first[0].BlockTime.UTC().Truncate(24 * time.Hour); !day.After(last[0].BlockTime); day = day.Add(24 * time.Hour) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return refreshRollupsUntil(tx, day, day.Add(24*time.Hour))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildRollups recomputes the event count tables from the stored events, it stops between steps once ctx is done
+func (s *IndexerService) RebuildRollups(ctx context.Context) (int, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return 0, err
+	}
+	if s.client == nil {
+		if err := s.connectToBlockchain(ctx); err != nil {
+			return 0, fmt.Errorf("failed to connect to blockchain: %w", err)
+		}
+	}
+
+	rows, err := rebuildRollups(ctx, s.db, s.client, s.config.MaxRetries, s.config.RetryDelay)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Rebuilt event count rollups from %d block counts\n", rows)
+	return rows, nil
+}
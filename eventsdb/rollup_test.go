@@ -0,0 +1,192 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+)
+
+// testChainStart is the time of block 0 in the test chain, blocks follow every 30 minutes
+var testChainStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testBlockTime(block uint64) time.Time {
+	return testChainStart.Add(time.Duration(block) * 30 * time.Minute)
+}
+
+// openTestChain serves eth_getBlockByNumber with the test block times and counts the HTTP requests it gets
+func openTestChain(t *testing.T) (*ethclient.Client, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var batch []rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		responses := make([]rpcResponse, 0, len(batch))
+		for _, req := range batch {
+			var number hexutil.Uint64
+			if err := json.Unmarshal(req.Params[0], &number); err != nil {
+				responses = append(responses, rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: rpcInvalidParams, Message: err.Error()}})
+				continue
+			}
+			block := map[string]interface{}{"number": number, "timestamp": hexutil.Uint64(testBlockTime(uint64(number)).Unix())}
+			responses = append(responses, rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: block})
+		}
+		writeJSON(w, http.StatusOK, responses)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatalf("failed to dial the test chain: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client, &requests
+}
+
+// timedTransfers are test transfers carrying the times of their blocks, as logs of recent nodes do
+func timedTransfers(t *testing.T, fromBlock, toBlock uint64, perBlock int) []BlockchainEvent {
+	t.Helper()
+	_, sig := testTransferSignature(t)
+	events := testTransfers(t, &sig, fromBlock, toBlock, perBlock)
+	for i := range events {
+		events[i].BlockTime = testBlockTime(events[i].BlockNumber)
+	}
+	return events
+}
+
+// rollupRows returns the block, hourly and daily counts in a comparable form
+func rollupRows(t *testing.T, db *gorm.DB) ([]BlockEventCount, []PeriodEventCount, []PeriodEventCount) {
+	t.Helper()
+	var blocks []BlockEventCount
+	var hourly, daily []PeriodEventCount
+	if err := db.Order("block_number").Find(&blocks).Error; err != nil {
+		t.Fatalf("failed to load block event counts: %v", err)
+	}
+	if err := db.Table(rollupHourlyTable).Order("bucket").Find(&hourly).Error; err != nil {
+		t.Fatalf("failed to load hourly event counts: %v", err)
+	}
+	if err := db.Table(rollupDailyTable).Order("bucket").Find(&daily).Error; err != nil {
+		t.Fatalf("failed to load daily event counts: %v", err)
+	}
+	for i := range blocks {
+		blocks[i].BlockTime = blocks[i].BlockTime.UTC()
+	}
+	for _, counts := range [][]PeriodEventCount{hourly, daily} {
+		for i := range counts {
+			counts[i].Bucket = counts[i].Bucket.UTC()
+		}
+	}
+	return blocks, hourly, daily
+}
+
+func TestFillBlockTimes(t *testing.T) {
+	client, requests := openTestChain(t)
+	events := timedTransfers(t, 0, 149, 2)
+	for i := range events {
+		events[i].BlockTime = time.Time{}
+	}
+
+	if err := fillBlockTimes(context.Background(), client, events, 3, time.Millisecond); err != nil {
+		t.Fatalf("failed to fill block times: %v", err)
+	}
+	for _, event := range events {
+		if !event.BlockTime.Equal(testBlockTime(event.BlockNumber)) {
+			t.Fatalf("block %d got time %v, want %v", event.BlockNumber, event.BlockTime, testBlockTime(event.BlockNumber))
+		}
+	}
+	// 150 blocks are asked for in two batched calls, not one call per block
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("filling 150 block times took %d requests, want 2", got)
+	}
+}
+
+func TestRollups(t *testing.T) {
+	config := testSQLiteConfig(t)
+	config.Rollups = true
+	db, sink := openTestSink(t, config)
+	sink.AddHook(rollups{})
+
+	// Blocks 0 to 59 span 30 hours, two blocks and four events an hour
+	writeTestRange(t, sink, 0, 59, timedTransfers(t, 0, 59, 2))
+	_, hourly, daily := rollupRows(t, db)
+	if len(hourly) != 30 || hourly[0].Events != 4 || hourly[0].FirstBlock != 0 || hourly[0].LastBlock != 1 {
+		t.Fatalf("hourly counts %+v, want 30 hours of 4 events", hourly)
+	}
+	if len(daily) != 2 || daily[0].Events != 96 || daily[1].Events != 24 || daily[1].FirstBlock != 48 {
+		t.Fatalf("daily counts %+v, want 96 events on the first day and 24 on the second", daily)
+	}
+
+	// A rollback recounts the hours and days of the removed blocks
+	if err := sink.Rollback(40); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	_, hourly, daily = rollupRows(t, db)
+	if len(hourly) != 20 || len(daily) != 1 || daily[0].Events != 80 || daily[0].LastBlock != 39 {
+		t.Fatalf("after the rollback %d hourly counts and daily counts %+v, want 20 hours and 80 events on the first day", len(hourly), daily)
+	}
+}
+
+func TestRebuildRollups(t *testing.T) {
+	config := testSQLiteConfig(t)
+	config.Rollups = true
+	db, sink := openTestSink(t, config)
+	sink.AddHook(rollups{})
+	writeTestRange(t, sink, 0, 299, timedTransfers(t, 0, 299, 1))
+
+	// Events removed behind the rollups back, and counts that went stale or missing
+	if err := db.Where("block_number < ?", 20).Delete(&BlockchainEvent{}).Error; err != nil {
+		t.Fatalf("failed to delete events: %v", err)
+	}
+	if err := db.Create(&BlockEventCount{BlockNumber: 5000, ContractAddress: testContract, EventSignature: "0x01", BlockTime: testBlockTime(5000), Events: 7}).Error; err != nil {
+		t.Fatalf("failed to add a stale block count: %v", err)
+	}
+	if err := db.Exec("DELETE FROM "+rollupHourlyTable+" WHERE bucket >= ?", testBlockTime(100)).Error; err != nil {
+		t.Fatalf("failed to delete hourly counts: %v", err)
+	}
+
+	client, _ := openTestChain(t)
+	rows, err := rebuildRollups(context.Background(), db, client, 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+	if rows != 280 {
+		t.Fatalf("rebuilt %d block counts, want 280", rows)
+	}
+
+	// The rebuilt tables match the ones kept while writing the remaining events
+	wantDB, wantSink := openTestSink(t, testSQLiteConfig(t))
+	wantSink.AddHook(rollups{})
+	writeTestRange(t, wantSink, 0, 299, timedTransfers(t, 20, 299, 1))
+
+	gotBlocks, gotHourly, gotDaily := rollupRows(t, db)
+	wantBlocks, wantHourly, wantDaily := rollupRows(t, wantDB)
+	if !reflect.DeepEqual(gotBlocks, wantBlocks) {
+		t.Fatalf("rebuilt %d block counts, want %d", len(gotBlocks), len(wantBlocks))
+	}
+	if !reflect.DeepEqual(gotHourly, wantHourly) {
+		t.Fatalf("rebuilt %d hourly counts, want %d", len(gotHourly), len(wantHourly))
+	}
+	if !reflect.DeepEqual(gotDaily, wantDaily) {
+		t.Fatalf("rebuilt daily counts %+v, want %+v", gotDaily, wantDaily)
+	}
+
+	// A cancelled rebuild stops before counting anything
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rebuildRollups(cancelled, db, client, 3, time.Millisecond); err == nil {
+		t.Fatal("rebuild with a cancelled context succeeded")
+	}
+}
@@ -5,32 +5,56 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
 // IndexerService handles the main application logic
 type IndexerService struct {
-	config    Config
-	db        *gorm.DB
-	client    *ethclient.Client
-	eventSigs map[string]EventSignatureInfo
+	config     Config
+	db         *gorm.DB
+	sink       Sink
+	client     *ethclient.Client
+	sigs       *signatureRegistry
+	reloadMu   sync.Mutex
+	unapplied  []string // Changed signature hashes a failed reload did not re-decode or project yet
+	httpServer *http.Server
+	grpcServer *grpc.Server
+	projector  *projector // Typed per event tables, nil when Projections is off
+	graphql    graphqlAPI
+	stream     *eventStream // Live events of /stream and /ws
+	rpc        rpcFacade
+	publisher  Publisher      // Broker the outbox is published to, nil when BROKER_URL is empty
+	hooks      []WriteHook    // Added with WithHook
+	handlers   *eventHandlers // Registered with OnEvent and OnRollback
+	workers    sync.WaitGroup // Background loops started by Start
 }
 
 // NewIndexerService creates a new indexer service
 func NewIndexerService(config Config) *IndexerService {
 	return &IndexerService{
-		config: config,
+		config:   config,
+		sigs:     newSignatureRegistry(),
+		handlers: &eventHandlers{},
 	}
 }
 
-func (s *IndexerService) Start() error {
+// Start indexes the contract until ctx is done, it returns nil once stopped by ctx.
+// A write in flight is completed, then the servers and background workers stop, queued webhooks and
+// broker messages get a last flush and the RPC client and database are closed.
+func (s *IndexerService) Start(ctx context.Context) error {
+	// Handlers see the values of ctx, a write in flight is completed when it is cancelled
+	s.handlers.ctx = context.WithoutCancel(ctx)
+
 	// Print confuguration
 	s.printConfiguration()
 
@@ -39,6 +63,9 @@ func (s *IndexerService) Start() error {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	workers, stopWorkers := context.WithCancel(ctx)
+	defer s.shutdown(stopWorkers)
+
 	// Load event on database
 	if err := s.loadEventSignaturesOnDB(); err != nil {
 		return fmt.Errorf("failed to store event on db : %w", err)
@@ -50,18 +77,67 @@ func (s *IndexerService) Start() error {
 		log.Println("Continuing without event signature decoding...")
 	}
 
-	if err := s.connectToBlockchain(); err != nil {
+	if s.config.ABIReloadInterval > 0 {
+		s.startWorker(func() { s.watchABIDir(workers, s.config.ABIReloadInterval) })
+	}
+
+	if s.config.HTTPAddr != "" {
+		s.startHTTPServer()
+	}
+	if s.config.GRPCAddr != "" {
+		if err := s.startGRPCServer(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.connectToBlockchain(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
 		return fmt.Errorf("failed to connect to blockchain: %w", err)
 	}
-	defer s.client.Close()
 
+	if s.config.RetentionInterval > 0 {
+		s.startWorker(func() { s.runRetention(workers, s.config.RetentionInterval) })
+	}
+	s.startWorker(func() { s.runWebhooks(workers) })
+	if s.publisher == nil && s.config.BrokerURL != "" {
+		publisher, err := newPublisher(s.config)
+		if err != nil {
+			return err
+		}
+		s.publisher = publisher
+	}
+	if s.publisher != nil {
+		s.startWorker(func() { s.runBroker(workers) })
+	}
+
+	err := s.index(ctx)
+	if ctx.Err() != nil {
+		// Calls cut off by the cancellation fail, that is how indexing stops
+		log.Println("Stopping indexer...")
+		return nil
+	}
+	return err
+}
+
+// index catches up with the chain from the cursor, then follows new blocks until ctx is done
+func (s *IndexerService) index(ctx context.Context) error {
 	// Get latest block and calculate starting block
-	latestBlock, err := s.getLatestBlock()
+	latestBlock, err := s.getLatestBlock(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get latest block: %w", err)
 	}
 
-	fromBlock, savedBlock := s.calculateStartingBlock(latestBlock)
+	// The chain may have reorganized below the cursor while the indexer was stopped
+	if _, err := s.rollbackReorg(ctx); err != nil {
+		return fmt.Errorf("failed to check for reorg: %w", err)
+	}
+
+	fromBlock, savedBlock, err := s.calculateStartingBlock(latestBlock)
+	if err != nil {
+		return fmt.Errorf("failed to read cursor: %w", err)
+	}
 	contractAddress := common.HexToAddress(s.config.ContractAddr)
 
 	if fromBlock != nil {
@@ -71,6 +147,10 @@ func (s *IndexerService) Start() error {
 		end := latestBlock.Int64()
 
 		for start <= end {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			// Calculate subrange end block
 			subEnd := start + s.config.MaxBlockRange - 1
 			if subEnd > end {
@@ -82,7 +162,7 @@ func (s *IndexerService) Start() error {
 			subToBlock := big.NewInt(subEnd)
 
 			fmt.Printf("Processing block range %d to %d\n", start, subEnd)
-			err = processBlockRange(s.client, s.db, contractAddress, subFromBlock, subToBlock, s.eventSigs, s.config.MaxRetries, s.config.RetryDelay)
+			err = processBlockRange(ctx, s.client, s.sink, contractAddress, subFromBlock, subToBlock, s.sigs.Snapshot(), s.config.Rollups, s.config.MaxRetries, s.config.RetryDelay)
 			if err != nil {
 				return fmt.Errorf("failed to process block range %d to %d: %w", start, subEnd, err)
 			}
@@ -95,7 +175,70 @@ func (s *IndexerService) Start() error {
 		latestBlock = savedBlock
 	}
 	// Start continuous monitoring
-	return s.startContinuousMonitoring(contractAddress, latestBlock)
+	return s.startContinuousMonitoring(ctx, contractAddress, latestBlock)
+}
+
+// startWorker runs fn in the background, shutdown waits for it to return
+func (s *IndexerService) startWorker(fn func()) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn()
+	}()
+}
+
+// shutdown stops what Start started: the servers first so no request sees a closing database,
+// then the background workers, which flush their queues once more, then the clients and the database
+func (s *IndexerService) shutdown(stopWorkers context.CancelFunc) {
+	s.stopServers()
+
+	stopWorkers()
+	s.workers.Wait()
+
+	if s.publisher != nil {
+		if err := s.publisher.Close(); err != nil {
+			log.Printf("Warning: Failed to close broker connection: %v\n", err)
+		}
+	}
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.rpc.close()
+	if err := s.sink.Close(); err != nil {
+		log.Printf("Warning: Failed to close database: %v\n", err)
+	}
+	log.Println("Indexer stopped")
+}
+
+// Redecode re-decodes stored events with the ABIs currently in AbiDir without refetching logs.
+// When all is false only events that were stored without a known signature are updated.
+func (s *IndexerService) Redecode(all bool, batchSize int) error {
+	if err := s.initializeDatabase(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	if err := s.loadEventSignaturesOnDB(); err != nil {
+		return fmt.Errorf("failed to store event on db : %w", err)
+	}
+
+	if err := s.loadEventSignatures(); err != nil {
+		return fmt.Errorf("failed to load event signatures: %w", err)
+	}
+
+	sigs := s.sigs.Snapshot()
+	updated, err := redecodeEvents(s.db, sigs, sigs.signatureHashes(), batchSize, all, s.config.BinaryStorage)
+	if err != nil {
+		return fmt.Errorf("failed to re-decode events: %w", err)
+	}
+
+	log.Printf("Re-decoded %d stored events\n", updated)
+
+	if s.projector != nil {
+		if _, err := s.projector.rebuild(s.db, nil, batchSize); err != nil {
+			return fmt.Errorf("failed to rebuild projections: %w", err)
+		}
+	}
+	return nil
 }
 
 func (s *IndexerService) printConfiguration() {
@@ -103,20 +246,88 @@ func (s *IndexerService) printConfiguration() {
 	log.Printf("  RPC Endpoint: %s\n", s.config.RPC)
 	log.Printf("  Contract: %s\n", s.config.ContractAddr)
 	log.Printf("  ABI Directory: %s\n", s.config.AbiDir)
+	log.Printf("  ABI Bindings: %s\n", s.config.ABIBindingsFile)
 	log.Printf("  Start Block: %d\n", s.config.StartBlock)
 	log.Printf("  Max Retries: %d\n", s.config.MaxRetries)
 	log.Printf("  Retry Delay: %v\n", s.config.RetryDelay)
 	log.Printf("  GORM Logs: %t\n", s.config.EnableGormLogs)
-	log.Printf("  Postgres: %s:%s@%s:%s/%s\n", s.config.PgUser, "******", s.config.PgHost, s.config.PgPort, s.config.PgDbName)
+	log.Printf("  ABI Reload Interval: %v\n", s.config.ABIReloadInterval)
+	log.Printf("  HTTP Address: %s\n", s.config.HTTPAddr)
+	log.Printf("  RPC Proxy: %t\n", s.config.RPCProxy)
+	log.Printf("  gRPC Address: %s\n", s.config.GRPCAddr)
+	log.Printf("  Reorg Depth: %d\n", s.config.ReorgDepth)
+	log.Printf("  Storage: %s\n", s.config.Storage)
+	log.Printf("  Auto Migrate: %t\n", s.config.AutoMigrate)
+	log.Printf("  Notify Channel: %s\n", s.config.NotifyChannel)
+	log.Printf("  Webhook Max Attempts: %d\n", s.config.WebhookMaxAttempts)
+	if s.config.BrokerURL != "" {
+		log.Printf("  Broker: %s (topic %s, keyed by %s)\n", redactBrokerURL(s.config.BrokerURL), s.config.BrokerTopic, s.config.BrokerKey)
+	}
+	if s.config.BinaryStorage {
+		log.Println("  Binary Storage: true")
+	}
+	log.Printf("  Rollups: %t\n", s.config.Rollups)
+	log.Printf("  Retention Rules: %s\n", s.config.RetentionFile)
+	log.Printf("  Archive Directory: %s\n", s.config.ArchiveDir)
+	if s.config.Projections {
+		log.Println("  Projections: true")
+	}
+	log.Printf("  Write Mode: %s (batch size %d)\n", s.config.WriteMode, s.config.WriteBatchSize)
+	if s.config.PartitionSize > 0 {
+		log.Printf("  Partition Size: %d blocks\n", s.config.PartitionSize)
+	}
+	if s.config.Storage == StorageSQLite {
+		log.Printf("  SQLite: %s\n", s.config.SQLitePath)
+	} else {
+		log.Printf("  Postgres: %s:%s@%s:%s/%s\n", s.config.PgUser, "******", s.config.PgHost, s.config.PgPort, s.config.PgDbName)
+	}
 }
 
 func (s *IndexerService) initializeDatabase() error {
-	db, err := initDB(s.config)
+	db, sink, err := initDB(s.config)
 	if err != nil {
 		return err
 	}
 	s.db = db
-	log.Println("Successfully connected to PostgreSQL database")
+	s.sink = sink
+	if s.config.Projections {
+		s.projector = newProjector(s.config)
+		sink.AddHook(s.projector)
+	}
+	if s.config.Rollups {
+		sink.AddHook(rollups{})
+	}
+	for _, hook := range s.hooks {
+		sink.AddHook(hook)
+	}
+	sink.AddHook(s.handlers)
+	if s.config.NotifyChannel != "" {
+		if s.config.Storage != StoragePostgres {
+			return fmt.Errorf("NOTIFY_CHANNEL needs PostgreSQL storage")
+		}
+		sink.AddHook(notifier{channel: s.config.NotifyChannel})
+	}
+	sink.AddHook(webhookOutbox{})
+	if s.config.BrokerURL != "" || s.publisher != nil {
+		if s.config.BrokerKey != BrokerKeyContract && s.config.BrokerKey != BrokerKeyEvent {
+			return fmt.Errorf("invalid BROKER_KEY %q, expected %s or %s", s.config.BrokerKey, BrokerKeyContract, BrokerKeyEvent)
+		}
+		sink.AddHook(brokerOutbox{keyBy: s.config.BrokerKey})
+	}
+	s.stream = newEventStream()
+	sink.AddHook(s.stream)
+	log.Printf("Successfully connected to %s database\n", s.config.Storage)
+	return nil
+}
+
+// ensureDatabase connects to the database unless the service is already connected
+func (s *IndexerService) ensureDatabase() error {
+	if s.db != nil {
+		return nil
+	}
+	if err := s.initializeDatabase(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
 	return nil
 }
 
@@ -133,18 +344,34 @@ func (s *IndexerService) loadEventSignaturesOnDB() error {
 }
 
 func (s *IndexerService) loadEventSignatures() error {
-	s.eventSigs = make(map[string]EventSignatureInfo)
-
-	loadedSigs, err := loadEventSignatures(s.db)
+	set, err := s.loadSignatureSet()
 	if err != nil {
 		return err
 	}
 
-	s.eventSigs = loadedSigs
+	s.sigs.Replace(set)
+	if s.projector != nil {
+		return s.projector.update(s.db, set)
+	}
 	return nil
 }
 
-func (s *IndexerService) connectToBlockchain() error {
+// loadSignatureSet loads the stored ABI events together with the contract bindings
+func (s *IndexerService) loadSignatureSet() (*signatureSet, error) {
+	set, err := loadEventSignatures(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	set.bindings, err = loadABIBindings(s.config.ABIBindingsFile, set)
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func (s *IndexerService) connectToBlockchain(ctx context.Context) error {
 	// Validate RPC URL format
 	if !strings.HasPrefix(s.config.RPC, "http://") && !strings.HasPrefix(s.config.RPC, "https://") &&
 		!strings.HasPrefix(s.config.RPC, "ws://") && !strings.HasPrefix(s.config.RPC, "wss://") {
@@ -153,7 +380,7 @@ func (s *IndexerService) connectToBlockchain() error {
 
 	// Connect to node with retry logic
 	log.Println("Attempting to connect to RPC endpoint...")
-	client, err := connectWithRetry(s.config.RPC, s.config.MaxRetries, s.config.RetryDelay)
+	client, err := connectWithRetry(ctx, s.config.RPC, s.config.MaxRetries, s.config.RetryDelay)
 	if err != nil {
 		return err
 	}
@@ -162,14 +389,14 @@ func (s *IndexerService) connectToBlockchain() error {
 	return nil
 }
 
-func (s *IndexerService) getLatestBlock() (*big.Int, error) {
+func (s *IndexerService) getLatestBlock(ctx context.Context) (*big.Int, error) {
 	var header *types.Header
 	var err error
 
 	for i := 0; i < s.config.MaxRetries; i++ {
 		log.Printf("Getting latest block (attempt %d)...\n", i+1)
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectionTimeout)
-		header, err = s.client.HeaderByNumber(ctx, nil)
+		callCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		header, err = s.client.HeaderByNumber(callCtx, nil)
 		cancel()
 
 		if err == nil {
@@ -178,7 +405,9 @@ func (s *IndexerService) getLatestBlock() (*big.Int, error) {
 
 		if i < s.config.MaxRetries-1 {
 			log.Printf("Failed to get latest header (attempt %d): %v. Retrying...\n", i+1, err)
-			time.Sleep(s.config.RetryDelay)
+			if !sleepContext(ctx, s.config.RetryDelay) {
+				return nil, ctx.Err()
+			}
 		}
 	}
 
@@ -189,13 +418,15 @@ func (s *IndexerService) getLatestBlock() (*big.Int, error) {
 	return header.Number, nil
 }
 
-func (s *IndexerService) calculateStartingBlock(latestBlock *big.Int) (*big.Int, *big.Int) {
+func (s *IndexerService) calculateStartingBlock(latestBlock *big.Int) (*big.Int, *big.Int, error) {
 	var fromBlock *big.Int
 	var latestBlockSaved *big.Int
-	var counter Cursor
-	err := s.db.First(&counter).Error
+	counter, ok, err := s.sink.Cursor()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if err == nil {
+	if ok {
 		block := big.NewInt(int64(counter.Count))
 		if latestBlock.Cmp(block) < 1 {
 			fromBlock = nil
@@ -215,24 +446,30 @@ func (s *IndexerService) calculateStartingBlock(latestBlock *big.Int) (*big.Int,
 		}
 	}
 
-	return fromBlock, latestBlockSaved
+	return fromBlock, latestBlockSaved, nil
 }
 
-func (s *IndexerService) startContinuousMonitoring(contractAddress common.Address, lastProcessedBlock *big.Int) error {
+// startContinuousMonitoring indexes new blocks as they become final until ctx is done
+func (s *IndexerService) startContinuousMonitoring(ctx context.Context, contractAddress common.Address, lastProcessedBlock *big.Int) error {
 	fmt.Println("\n----------------------------------------")
 	fmt.Println("Starting continuous event monitoring...")
 
 	for {
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectionTimeout)
-		header, err := s.client.HeaderByNumber(ctx, nil)
+		headerCtx, cancel := context.WithTimeout(ctx, DefaultConnectionTimeout)
+		header, err := s.client.HeaderByNumber(headerCtx, nil)
 		cancel()
 
+		if ctx.Err() != nil {
+			return nil
+		}
 		if err != nil {
 			fmt.Printf("Error getting latest block: %v. Retrying in %v...\n", err, s.config.RetryDelay)
-			time.Sleep(s.config.RetryDelay)
+			if !sleepContext(ctx, s.config.RetryDelay) {
+				return nil
+			}
 
 			// Try to reconnect
-			if reconnectErr := s.reconnectToBlockchain(); reconnectErr != nil {
+			if reconnectErr := s.reconnectToBlockchain(ctx); reconnectErr != nil {
 				fmt.Printf("Failed to reconnect: %v\n", reconnectErr)
 				continue
 			}
@@ -243,23 +480,74 @@ func (s *IndexerService) startContinuousMonitoring(contractAddress common.Addres
 		currentBlock.Sub(currentBlock, big.NewInt(s.config.FinalityBlock))
 
 		if currentBlock.Cmp(lastProcessedBlock) > 0 {
+			rewound, err := s.rollbackReorg(ctx)
+			if err != nil {
+				fmt.Println("Failed to check for reorg: ", err)
+				if !sleepContext(ctx, s.config.RetryDelay) {
+					return nil
+				}
+				continue
+			}
+			if rewound != nil {
+				lastProcessedBlock = rewound
+			}
+
 			fromBlock := new(big.Int).Add(lastProcessedBlock, big.NewInt(1))
 			fmt.Printf("New block(s) detected! Checking for events from block %s to %s\n",
 				fromBlock.String(), currentBlock.String())
 
-			if err := processBlockRange(s.client, s.db, contractAddress, fromBlock, currentBlock, s.eventSigs, s.config.MaxRetries, s.config.RetryDelay); err != nil {
+			if err := processBlockRange(ctx, s.client, s.sink, contractAddress, fromBlock, currentBlock, s.sigs.Snapshot(), s.config.Rollups, s.config.MaxRetries, s.config.RetryDelay); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				fmt.Println("Fialed to process Block: ", err)
 				continue
 			}
 			lastProcessedBlock = currentBlock
 		}
 
-		time.Sleep(DefaultPollingInterval)
+		if !sleepContext(ctx, DefaultPollingInterval) {
+			return nil
+		}
 	}
 }
 
-func (s *IndexerService) reconnectToBlockchain() error {
-	newClient, err := connectWithRetry(s.config.RPC, s.config.MaxRetries, s.config.RetryDelay)
+// rollbackReorg compares the cursor block with the chain and rolls back ReorgDepth blocks when its hash changed.
+// It returns the new cursor after a rollback, nil when the chain is consistent.
+func (s *IndexerService) rollbackReorg(ctx context.Context) (*big.Int, error) {
+	counter, ok, err := s.sink.Cursor()
+	if err != nil || !ok || counter.BlockHash == "" {
+		return nil, err
+	}
+
+	header, err := fetchHeader(ctx, s.client, big.NewInt(int64(counter.Count)), s.config.MaxRetries, s.config.RetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash().Hex() == counter.BlockHash {
+		return nil, nil
+	}
+
+	// Never rewind below the first indexed block
+	rollbackFrom := int64(counter.Count) - s.config.ReorgDepth + 1
+	if rollbackFrom < s.config.StartBlock && s.config.StartBlock <= int64(counter.Count) {
+		rollbackFrom = s.config.StartBlock
+	}
+	if rollbackFrom < 1 {
+		rollbackFrom = 1
+	}
+	log.Printf("Reorg detected at block %d (stored %s, chain %s), rolling back from block %d\n",
+		counter.Count, counter.BlockHash, header.Hash().Hex(), rollbackFrom)
+
+	if err := s.sink.Rollback(uint64(rollbackFrom)); err != nil {
+		return nil, fmt.Errorf("failed to roll back from block %d: %w", rollbackFrom, err)
+	}
+
+	return big.NewInt(rollbackFrom - 1), nil
+}
+
+func (s *IndexerService) reconnectToBlockchain(ctx context.Context) error {
+	newClient, err := connectWithRetry(ctx, s.config.RPC, s.config.MaxRetries, s.config.RetryDelay)
 	if err != nil {
 		return err
 	}
@@ -268,3 +556,15 @@ func (s *IndexerService) reconnectToBlockchain() error {
 	s.client = newClient
 	return nil
 }
+
+// sleepContext waits for d and reports whether ctx is still running
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,276 @@
+package eventsdb
+
+import (
+	"errors"
+	"fmt"
+	logger "log"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sink persists decoded events and the indexing cursor
+type Sink interface {
+	// WriteRange stores the events of [fromBlock, toBlock] and moves the cursor to toBlock in one transaction
+	WriteRange(fromBlock, toBlock uint64, toBlockHash string, events []BlockchainEvent) error
+	// Import upserts events without moving the cursor or running hooks, used to restore archived events
+	Import(events []BlockchainEvent) error
+	// Rollback removes every event from fromBlock on and moves the cursor back to fromBlock-1 in one transaction
+	Rollback(fromBlock uint64) error
+	// Cursor returns the last written block, ok is false when nothing was written yet
+	Cursor() (cursor Cursor, ok bool, err error)
+	// AddHook registers a hook that runs inside the transaction of every write and rollback
+	AddHook(hook WriteHook)
+	// Close releases the underlying storage
+	Close() error
+}
+
+// WriteHook keeps derived data in step with the sink, an error rolls the whole write back
+type WriteHook interface {
+	// AfterWrite runs after the events of [fromBlock, toBlock] are stored
+	AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error
+	// AfterRollback runs after the events from fromBlock on are deleted
+	AfterRollback(tx *gorm.DB, fromBlock uint64) error
+}
+
+// CommitHook is a WriteHook that is also told about writes and rollbacks once they are committed.
+// It cannot fail the write anymore, it is meant for notifying consumers.
+type CommitHook interface {
+	WriteHook
+	// AfterWriteCommit runs after the transaction storing the events of [fromBlock, toBlock] committed
+	AfterWriteCommit(fromBlock, toBlock uint64, events []BlockchainEvent)
+	// AfterRollbackCommit runs after the transaction deleting the events from fromBlock on committed
+	AfterRollbackCommit(fromBlock uint64)
+}
+
+// writeHooks are the hooks registered on a sink
+type writeHooks struct {
+	mu    sync.RWMutex
+	hooks []WriteHook
+}
+
+func (h *writeHooks) AddHook(hook WriteHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, hook)
+}
+
+func (h *writeHooks) afterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		if err := hook.AfterWrite(tx, fromBlock, toBlock, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *writeHooks) afterRollback(tx *gorm.DB, fromBlock uint64) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		if err := hook.AfterRollback(tx, fromBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *writeHooks) afterWriteCommit(fromBlock, toBlock uint64, events []BlockchainEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		if commitHook, ok := hook.(CommitHook); ok {
+			commitHook.AfterWriteCommit(fromBlock, toBlock, events)
+		}
+	}
+}
+
+func (h *writeHooks) afterRollbackCommit(fromBlock uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		if commitHook, ok := hook.(CommitHook); ok {
+			commitHook.AfterRollbackCommit(fromBlock)
+		}
+	}
+}
+
+// eventUpsertColumns are overwritten when an event with the same tx hash and log index is stored again
+var eventUpsertColumns = []string{"tx_index", "block_number", "block_hash", "removed", "contract_address", "event_signature", "event_name", "event_full_signature", "other_topics", "raw_data", "decoded_params", "global_abi_match"}
+
+// Write modes of the event sinks
+const (
+	WriteModeRow   = "row"   // One INSERT ... ON CONFLICT per event
+	WriteModeBatch = "batch" // Multi-row INSERT ... ON CONFLICT of WriteBatchSize events
+	WriteModeCopy  = "copy"  // COPY into a staging table merged with one INSERT ... SELECT, PostgreSQL only
+)
+
+// eventConflictColumns identify an event, a partitioned table also needs the partition key in its unique index
+func eventConflictColumns(partitioned bool) []string {
+	if partitioned {
+		return []string{"tx_hash", "log_index", "block_number"}
+	}
+	return []string{"tx_hash", "log_index"}
+}
+
+// eventConflictClause upserts on the tx hash and log index of an event
+func eventConflictClause(partitioned bool) clause.OnConflict {
+	var columns []clause.Column
+	for _, name := range eventConflictColumns(partitioned) {
+		columns = append(columns, clause.Column{Name: name})
+	}
+
+	return clause.OnConflict{
+		Columns:   columns,
+		DoUpdates: clause.AssignmentColumns(eventUpsertColumns),
+	}
+}
+
+// storeEvents upserts events one by one inside a transaction
+func storeEvents(tx *gorm.DB, events []BlockchainEvent, partitioned bool) error {
+	for i := range events {
+		// Use upsert (OnConflict) to avoid duplicate key errors
+		if err := tx.Clauses(eventConflictClause(partitioned)).Create(&events[i]).Error; err != nil {
+			return fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// storeEventsInBatches upserts events with one multi-row statement per batch inside a transaction
+func storeEventsInBatches(tx *gorm.DB, events []BlockchainEvent, batchSize int, partitioned bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := tx.Clauses(eventConflictClause(partitioned)).CreateInBatches(events, batchSize).Error; err != nil {
+		return fmt.Errorf("failed to store events: %w", err)
+	}
+
+	return nil
+}
+
+// storeBinaryEvents upserts events stored as bytea with the row or batch write mode inside a transaction
+func storeBinaryEvents(tx *gorm.DB, events []binaryEvent, writeMode string, batchSize int, partitioned bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if writeMode == WriteModeRow {
+		batchSize = 1
+	}
+
+	if err := tx.Clauses(eventConflictClause(partitioned)).CreateInBatches(events, batchSize).Error; err != nil {
+		return fmt.Errorf("failed to store events: %w", err)
+	}
+
+	return nil
+}
+
+// storeCursor moves the cursor to block inside a transaction
+func storeCursor(tx *gorm.DB, block uint64, blockHash string) error {
+	var counter Cursor
+	if err := tx.First(&counter, 1).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Create the counter if not exists
+			counter = Cursor{
+				ID:        1,
+				Count:     int(block),
+				BlockHash: blockHash,
+			}
+			if err := tx.Create(&counter).Error; err != nil {
+				return fmt.Errorf("failed to create counter: %w", err)
+			}
+		} else {
+			return fmt.Errorf("failed to query counter: %w", err)
+		}
+	} else {
+		// Update the existing counter
+		if err := tx.Model(&Cursor{}).
+			Where("id = ?", 1).
+			Updates(map[string]interface{}{"count": int(block), "block_hash": blockHash}).Error; err != nil {
+			return fmt.Errorf("failed to update counter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeRange stores events with store, runs the hooks and moves the cursor in a single transaction.
+// Commit hooks are told once the transaction committed.
+func writeRange(db *gorm.DB, hooks *writeHooks, fromBlock, toBlock uint64, toBlockHash string, events []BlockchainEvent, store func(tx *gorm.DB, events []BlockchainEvent) error) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := store(tx, events); err != nil {
+			return err
+		}
+		if err := hooks.afterWrite(tx, fromBlock, toBlock, events); err != nil {
+			return err
+		}
+		if err := storeCursor(tx, toBlock, toBlockHash); err != nil {
+			return fmt.Errorf("failed to store Cursor: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	hooks.afterWriteCommit(fromBlock, toBlock, events)
+
+	if len(events) > 0 {
+		logger.Printf("Stored %d events up to block %d\n", len(events), toBlock)
+	}
+	return nil
+}
+
+// rollbackRange deletes the events from fromBlock on, runs the hooks and rewinds the cursor in a single transaction.
+// Commit hooks are told once the transaction committed.
+func rollbackRange(db *gorm.DB, hooks *writeHooks, fromBlock uint64) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("block_number >= ?", fromBlock).Delete(&BlockchainEvent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete events from block %d: %w", fromBlock, result.Error)
+		}
+		logger.Printf("Rolled back %d events from block %d\n", result.RowsAffected, fromBlock)
+
+		if err := hooks.afterRollback(tx, fromBlock); err != nil {
+			return err
+		}
+
+		// The hash of the new cursor block is unknown until the next range is written
+		if err := storeCursor(tx, fromBlock-1, ""); err != nil {
+			return fmt.Errorf("failed to store Cursor: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	hooks.afterRollbackCommit(fromBlock)
+	return nil
+}
+
+// readCursor returns the stored cursor, ok is false when none was stored yet
+func readCursor(db *gorm.DB) (Cursor, bool, error) {
+	var counter Cursor
+	err := db.First(&counter, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Cursor{}, false, nil
+	}
+	if err != nil {
+		return Cursor{}, false, fmt.Errorf("failed to query counter: %w", err)
+	}
+	return counter, true, nil
+}
+
+// closeDB closes the connection pool behind a gorm handle
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
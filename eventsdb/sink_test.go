@@ -0,0 +1,302 @@
+package eventsdb
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// testContract emits the events of the tests
+const testContract = "0x91Cf2D8Ed503EC52768999aA6D8DBeA6e52dbe43"
+
+// testTransferABI is the ERC-20 Transfer event the test events are decoded with
+const testTransferABI = `{"type":"event","name":"Transfer","anonymous":false,"inputs":[` +
+	`{"name":"from","type":"address","indexed":true},` +
+	`{"name":"to","type":"address","indexed":true},` +
+	`{"name":"value","type":"uint256","indexed":false}]}`
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// Every write logs, keep the output readable unless -v asks for it
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// testSQLiteConfig returns the configuration of a fresh SQLite database in a temporary directory
+func testSQLiteConfig(t testing.TB) Config {
+	t.Helper()
+	config := LoadConfig()
+	config.Storage = StorageSQLite
+	config.SQLitePath = filepath.Join(t.TempDir(), "eventsdb.sqlite")
+	config.ContractAddr = testContract
+	config.WriteMode = WriteModeBatch
+	config.WriteBatchSize = DefaultWriteBatchSize
+	config.PartitionSize = 0
+	config.BinaryStorage = false
+	config.Projections = false
+	config.Rollups = false
+	config.AutoMigrate = true
+	return config
+}
+
+// openTestSink migrates the database of config and opens its sink, both are closed when the test ends
+func openTestSink(t testing.TB, config Config) (*gorm.DB, Sink) {
+	t.Helper()
+	db, sink, err := initDB(config)
+	if err != nil {
+		t.Fatalf("failed to open %s sink: %v", config.Storage, err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return db, sink
+}
+
+// testTransferSignature parses testTransferABI into its signature hash and decoding information
+func testTransferSignature(t testing.TB) (string, EventSignatureInfo) {
+	t.Helper()
+	sigHash, sig, err := eventSignatureFromRecord(ABIEventRecord{EventName: "Transfer", ABIEventJSON: testTransferABI})
+	if err != nil {
+		t.Fatalf("failed to parse the Transfer ABI: %v", err)
+	}
+	return sigHash, sig
+}
+
+// testTransfers builds perBlock Transfer events for every block of [fromBlock, toBlock].
+// sig decodes them, with nil they are stored without a name like logs of an unknown ABI.
+func testTransfers(t testing.TB, sig *EventSignatureInfo, fromBlock, toBlock uint64, perBlock int) []BlockchainEvent {
+	t.Helper()
+	sigHash, _ := testTransferSignature(t)
+
+	var events []BlockchainEvent
+	for block := fromBlock; block <= toBlock; block++ {
+		for i := 0; i < perBlock; i++ {
+			value := new(big.Int).SetUint64(block*1000 + uint64(i))
+			eventLog := types.Log{
+				Address: common.HexToAddress(testContract),
+				Topics: []common.Hash{
+					common.HexToHash(sigHash),
+					common.BigToHash(big.NewInt(int64(i + 1))),
+					common.BigToHash(big.NewInt(int64(i + 2))),
+				},
+				Data:        common.LeftPadBytes(value.Bytes(), 32),
+				BlockNumber: block,
+				TxHash:      common.BigToHash(value),
+				TxIndex:     uint(i),
+				BlockHash:   testBlockHash(block),
+				Index:       uint(i),
+			}
+			event, err := buildEvent(eventLog, sig, false)
+			if err != nil {
+				t.Fatalf("failed to build event: %v", err)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// testBlockHash is the hash of block in the test chain
+func testBlockHash(block uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(block + 1<<32))
+}
+
+// writeTestRange writes events as the range [fromBlock, toBlock] and fails the test on an error
+func writeTestRange(t testing.TB, sink Sink, fromBlock, toBlock uint64, events []BlockchainEvent) {
+	t.Helper()
+	if err := sink.WriteRange(fromBlock, toBlock, testBlockHash(toBlock).Hex(), events); err != nil {
+		t.Fatalf("failed to write blocks %d-%d: %v", fromBlock, toBlock, err)
+	}
+}
+
+// storedEvents returns the stored events in block and log order
+func storedEvents(t testing.TB, db *gorm.DB) []BlockchainEvent {
+	t.Helper()
+	var events []BlockchainEvent
+	if err := db.Order("block_number, log_index").Find(&events).Error; err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	return events
+}
+
+// assertCursor fails the test unless the cursor is at block with blockHash
+func assertCursor(t testing.TB, sink Sink, block int, blockHash string) {
+	t.Helper()
+	cursor, ok, err := sink.Cursor()
+	if err != nil {
+		t.Fatalf("failed to read cursor: %v", err)
+	}
+	if !ok || cursor.Count != block || cursor.BlockHash != blockHash {
+		t.Fatalf("cursor is %d %q (stored %v), want %d %q", cursor.Count, cursor.BlockHash, ok, block, blockHash)
+	}
+}
+
+// recordingHook records the hook calls of a sink in calls, AfterWrite fails with err when set
+type recordingHook struct {
+	name  string
+	calls *[]string
+	err   error
+	seen  int64 // Events the transaction held when AfterWrite ran
+}
+
+func (h *recordingHook) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	*h.calls = append(*h.calls, h.name+" write")
+	if err := tx.Model(&BlockchainEvent{}).Count(&h.seen).Error; err != nil {
+		return err
+	}
+	return h.err
+}
+
+func (h *recordingHook) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	*h.calls = append(*h.calls, h.name+" rollback")
+	return nil
+}
+
+func (h *recordingHook) AfterWriteCommit(fromBlock, toBlock uint64, events []BlockchainEvent) {
+	*h.calls = append(*h.calls, h.name+" write commit")
+}
+
+func (h *recordingHook) AfterRollbackCommit(fromBlock uint64) {
+	*h.calls = append(*h.calls, h.name+" rollback commit")
+}
+
+func TestSQLiteSinkWriteRange(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+
+	if _, ok, err := sink.Cursor(); err != nil || ok {
+		t.Fatalf("fresh sink has a cursor (stored %v, error %v)", ok, err)
+	}
+
+	events := testTransfers(t, &sig, 100, 109, 3)
+	writeTestRange(t, sink, 100, 119, events)
+	assertCursor(t, sink, 119, testBlockHash(119).Hex())
+
+	stored := storedEvents(t, db)
+	if len(stored) != len(events) {
+		t.Fatalf("stored %d events, want %d", len(stored), len(events))
+	}
+	first := stored[0]
+	if first.TxHash != events[0].TxHash || first.ContractAddress != testContract || first.RawData != events[0].RawData ||
+		first.EventName == nil || *first.EventName != "Transfer" || !reflect.DeepEqual(first.OtherTopics, events[0].OtherTopics) {
+		t.Fatalf("stored event %+v does not match %+v", first, events[0])
+	}
+
+	// Writing a range again upserts its events instead of duplicating them
+	writeTestRange(t, sink, 100, 119, events)
+	if stored := storedEvents(t, db); len(stored) != len(events) {
+		t.Fatalf("rewriting stored %d events, want %d", len(stored), len(events))
+	}
+
+	// An empty range only moves the cursor
+	writeTestRange(t, sink, 120, 129, nil)
+	assertCursor(t, sink, 129, testBlockHash(129).Hex())
+}
+
+func TestSQLiteSinkWriteRangeIsAtomic(t *testing.T) {
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 100, 109, testTransfers(t, nil, 100, 109, 1))
+
+	var calls []string
+	failing := &recordingHook{name: "failing", calls: &calls, err: errors.New("hook failed")}
+	sink.AddHook(failing)
+
+	err := sink.WriteRange(110, 119, testBlockHash(119).Hex(), testTransfers(t, nil, 110, 119, 2))
+	if err == nil {
+		t.Fatal("write succeeded although a hook failed")
+	}
+	if failing.seen != 30 {
+		t.Fatalf("hook saw %d events in its transaction, want 30", failing.seen)
+	}
+
+	// Neither the events nor the cursor of the failed range were committed
+	if stored := storedEvents(t, db); len(stored) != 10 {
+		t.Fatalf("%d events stored after a failed write, want 10", len(stored))
+	}
+	assertCursor(t, sink, 109, testBlockHash(109).Hex())
+}
+
+func TestSQLiteSinkRollback(t *testing.T) {
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	writeTestRange(t, sink, 100, 109, testTransfers(t, nil, 100, 109, 2))
+	writeTestRange(t, sink, 110, 119, testTransfers(t, nil, 110, 119, 2))
+
+	if err := sink.Rollback(105); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	stored := storedEvents(t, db)
+	if len(stored) != 10 || stored[len(stored)-1].BlockNumber != 104 {
+		t.Fatalf("%d events left after rolling back from block 105, want 10 up to block 104", len(stored))
+	}
+	// The hash of the new cursor block is unknown until the next write
+	assertCursor(t, sink, 104, "")
+
+	writeTestRange(t, sink, 105, 114, testTransfers(t, nil, 105, 114, 1))
+	if stored := storedEvents(t, db); len(stored) != 20 {
+		t.Fatalf("%d events after re-indexing, want 20", len(stored))
+	}
+	assertCursor(t, sink, 114, testBlockHash(114).Hex())
+}
+
+func TestSQLiteSinkImportKeepsCursor(t *testing.T) {
+	db, sink := openTestSink(t, testSQLiteConfig(t))
+	var calls []string
+	sink.AddHook(&recordingHook{name: "hook", calls: &calls})
+	writeTestRange(t, sink, 100, 109, nil)
+	calls = nil
+
+	if err := sink.Import(testTransfers(t, nil, 50, 59, 1)); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stored := storedEvents(t, db); len(stored) != 10 {
+		t.Fatalf("%d events imported, want 10", len(stored))
+	}
+	assertCursor(t, sink, 109, testBlockHash(109).Hex())
+	if len(calls) != 0 {
+		t.Fatalf("import ran hooks %v", calls)
+	}
+}
+
+func TestSinkHookOrder(t *testing.T) {
+	_, sink := openTestSink(t, testSQLiteConfig(t))
+
+	var calls []string
+	sink.AddHook(&recordingHook{name: "first", calls: &calls})
+	sink.AddHook(&recordingHook{name: "second", calls: &calls})
+
+	writeTestRange(t, sink, 100, 109, testTransfers(t, nil, 100, 109, 1))
+	if err := sink.Rollback(105); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	// Hooks run in registration order, commit hooks only after every hook of the transaction ran
+	want := []string{
+		"first write", "second write", "first write commit", "second write commit",
+		"first rollback", "second rollback", "first rollback commit", "second rollback commit",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("hook calls %v, want %v", calls, want)
+	}
+
+	// A failing hook stops the later ones and nothing is told about a commit
+	calls = nil
+	sink.AddHook(&recordingHook{name: "third", calls: &calls})
+	sink.(*SQLiteSink).hooks[0].(*recordingHook).err = errors.New("hook failed")
+	if err := sink.WriteRange(105, 114, testBlockHash(114).Hex(), nil); err == nil {
+		t.Fatal("write succeeded although a hook failed")
+	}
+	if want := []string{"first write"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("hook calls %v after a failing hook, want %v", calls, want)
+	}
+}
@@ -0,0 +1,50 @@
+package eventsdb
+
+import (
+	"gorm.io/gorm"
+)
+
+// SQLiteSink stores events in an embedded SQLite database file.
+// It needs no server and is meant for local development and small deployments.
+type SQLiteSink struct {
+	db        *gorm.DB
+	writeMode string
+	batchSize int
+
+	writeHooks
+}
+
+// NewSQLiteSink creates a sink on an open SQLite database with the write mode of config, row or batch
+func NewSQLiteSink(db *gorm.DB, config Config) *SQLiteSink {
+	return &SQLiteSink{db: db, writeMode: config.WriteMode, batchSize: config.WriteBatchSize}
+}
+
+func (s *SQLiteSink) WriteRange(fromBlock, toBlock uint64, toBlockHash string, events []BlockchainEvent) error {
+	return writeRange(s.db, &s.writeHooks, fromBlock, toBlock, toBlockHash, events, s.storeEvents)
+}
+
+func (s *SQLiteSink) Import(events []BlockchainEvent) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return storeEventsInBatches(tx, events, s.batchSize, false)
+	})
+}
+
+func (s *SQLiteSink) Rollback(fromBlock uint64) error {
+	return rollbackRange(s.db, &s.writeHooks, fromBlock)
+}
+
+func (s *SQLiteSink) Cursor() (Cursor, bool, error) {
+	return readCursor(s.db)
+}
+
+func (s *SQLiteSink) Close() error {
+	return closeDB(s.db)
+}
+
+// storeEvents upserts events with the row or batch write mode
+func (s *SQLiteSink) storeEvents(tx *gorm.DB, events []BlockchainEvent) error {
+	if s.writeMode == WriteModeRow {
+		return storeEvents(tx, events, false)
+	}
+	return storeEventsInBatches(tx, events, s.batchSize, false)
+}
@@ -0,0 +1,386 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+// Streaming limits
+const (
+	streamBufferSize        = 1_024 // Messages buffered per subscriber before it is dropped as too slow
+	streamKeepAliveInterval = 15 * time.Second
+)
+
+// errStreamTooSlow ends a subscription that did not keep up, the client resumes from its last cursor
+var errStreamTooSlow = errors.New("subscriber did not keep up, reconnect with the last cursor")
+
+// errStreamClosed ends every subscription when the server shuts down
+var errStreamClosed = errors.New("server is shutting down, reconnect with the last cursor")
+
+// StreamMessage is pushed to stream subscribers, an event or the start block of a rollback
+type StreamMessage struct {
+	Type      string       `json:"type"`             // event or rollback
+	Cursor    string       `json:"cursor,omitempty"` // Position of the event, pass it to resume after the event
+	Event     *EventRecord `json:"event,omitempty"`
+	FromBlock uint64       `json:"fromBlock,omitempty"` // Events from this block on were removed by a reorg
+}
+
+// Stream message types
+const (
+	StreamEvent    = "event"
+	StreamRollback = "rollback"
+)
+
+// streamPosition is the last event sent to a subscriber, logIndex -1 stands before the first event of block
+type streamPosition struct {
+	block    uint64
+	logIndex int64
+}
+
+func (p streamPosition) before(block uint64, logIndex uint) bool {
+	return block > p.block || block == p.block && int64(logIndex) > p.logIndex
+}
+
+// streamSubscriber receives the live messages matching its filter
+type streamSubscriber struct {
+	contract  string
+	eventName string
+	messages  chan StreamMessage
+	dropped   chan struct{} // Closed when the subscriber fell behind
+}
+
+func (sub *streamSubscriber) matches(event *BlockchainEvent) bool {
+	if sub.contract != "" && event.ContractAddress != sub.contract {
+		return false
+	}
+	return sub.eventName == "" || event.EventName != nil && *event.EventName == sub.eventName
+}
+
+// eventStream fans committed events out to WebSocket and SSE subscribers.
+// It is a CommitHook, subscribers only see events once their transaction committed.
+type eventStream struct {
+	mu          sync.Mutex
+	subscribers map[*streamSubscriber]struct{}
+	closed      chan struct{} // Closed on shutdown
+	closeOnce   sync.Once
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{subscribers: make(map[*streamSubscriber]struct{}), closed: make(chan struct{})}
+}
+
+// close ends every subscription, streamEvents returns errStreamClosed
+func (st *eventStream) close() {
+	st.closeOnce.Do(func() { close(st.closed) })
+}
+
+func (st *eventStream) subscribe(contract, eventName string) *streamSubscriber {
+	// Addresses are stored checksummed
+	if common.IsHexAddress(contract) {
+		contract = common.HexToAddress(contract).Hex()
+	}
+	sub := &streamSubscriber{
+		contract:  contract,
+		eventName: eventName,
+		messages:  make(chan StreamMessage, streamBufferSize),
+		dropped:   make(chan struct{}),
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (st *eventStream) unsubscribe(sub *streamSubscriber) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.subscribers, sub)
+}
+
+// send queues a message without blocking the writer, a full subscriber is dropped
+func (st *eventStream) send(sub *streamSubscriber, message StreamMessage) {
+	select {
+	case sub.messages <- message:
+	default:
+		delete(st.subscribers, sub)
+		close(sub.dropped)
+	}
+}
+
+func (st *eventStream) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	return nil
+}
+
+func (st *eventStream) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	return nil
+}
+
+func (st *eventStream) AfterWriteCommit(fromBlock, toBlock uint64, events []BlockchainEvent) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.subscribers) == 0 {
+		return
+	}
+
+	for i := range events {
+		var record *EventRecord
+		for sub := range st.subscribers {
+			if !sub.matches(&events[i]) {
+				continue
+			}
+			if record == nil {
+				r := newEventRecord(&events[i])
+				record = &r
+			}
+			st.send(sub, StreamMessage{Type: StreamEvent, Cursor: encodeEventCursor(events[i].BlockNumber, events[i].LogIndex), Event: record})
+		}
+	}
+}
+
+func (st *eventStream) AfterRollbackCommit(fromBlock uint64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for sub := range st.subscribers {
+		st.send(sub, StreamMessage{Type: StreamRollback, FromBlock: fromBlock})
+	}
+}
+
+// streamRequest is a subscription, events after Cursor or from FromBlock on are replayed from the database first
+type streamRequest struct {
+	Contract  string
+	EventName string
+	Cursor    string
+	FromBlock uint64
+}
+
+// parseStreamRequest reads the filters of /stream and /ws, an SSE Last-Event-ID header takes the place of cursor
+func parseStreamRequest(r *http.Request) (streamRequest, error) {
+	values := r.URL.Query()
+	req := streamRequest{
+		Contract:  values.Get("contract"),
+		EventName: values.Get("event"),
+		Cursor:    values.Get("cursor"),
+	}
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		req.Cursor = lastEventID
+	}
+	if req.Cursor != "" {
+		if _, _, err := decodeEventCursor(req.Cursor); err != nil {
+			return req, err
+		}
+	}
+	if fromBlock := values.Get("fromBlock"); fromBlock != "" {
+		var err error
+		if req.FromBlock, err = strconv.ParseUint(fromBlock, 10, 64); err != nil {
+			return req, fmt.Errorf("invalid fromBlock %q", fromBlock)
+		}
+	}
+	return req, nil
+}
+
+// streamEvents sends the events of req until ctx is done or send fails.
+// The subscription starts before the replay, so events committed while replaying are not missed;
+// live events at or before the last replayed position are skipped.
+func (s *IndexerService) streamEvents(ctx context.Context, req streamRequest, send func(StreamMessage) error) error {
+	sub := s.stream.subscribe(req.Contract, req.EventName)
+	defer s.stream.unsubscribe(sub)
+
+	var position *streamPosition
+	if req.Cursor != "" || req.FromBlock > 0 {
+		q := EventQuery{Contract: req.Contract, EventName: req.EventName, Cursor: req.Cursor, FromBlock: req.FromBlock, Limit: MaxEventPageSize}
+		position = &streamPosition{block: req.FromBlock, logIndex: -1}
+		if req.Cursor != "" {
+			block, logIndex, _ := decodeEventCursor(req.Cursor)
+			position = &streamPosition{block: block, logIndex: int64(logIndex)}
+		}
+
+		for {
+			page, err := queryEvents(s.db, q, s.config.Storage, s.config.BinaryStorage, s.config.Rollups)
+			if err != nil {
+				return err
+			}
+			for i := range page.Events {
+				record := &page.Events[i]
+				cursor := encodeEventCursor(record.BlockNumber, uint(record.LogIndex))
+				if err := send(StreamMessage{Type: StreamEvent, Cursor: cursor, Event: record}); err != nil {
+					return err
+				}
+				position = &streamPosition{block: record.BlockNumber, logIndex: int64(record.LogIndex)}
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.dropped:
+			return errStreamTooSlow
+		case <-s.stream.closed:
+			return errStreamClosed
+		case message := <-sub.messages:
+			switch message.Type {
+			case StreamEvent:
+				if position != nil && !position.before(message.Event.BlockNumber, uint(message.Event.LogIndex)) {
+					continue
+				}
+				position = &streamPosition{block: message.Event.BlockNumber, logIndex: int64(message.Event.LogIndex)}
+			case StreamRollback:
+				// Re-indexed events of the removed blocks are sent again
+				if position != nil && position.block >= message.FromBlock {
+					position = &streamPosition{block: message.FromBlock, logIndex: -1}
+				}
+			}
+			if err := send(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleSSE streams events as Server-Sent Events, the id of each event is its cursor so browsers resume on reconnect
+func (s *IndexerService) handleSSE(w http.ResponseWriter, r *http.Request) {
+	req, err := parseStreamRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var mu sync.Mutex
+	write := func(format string, args ...interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(streamKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if write(": keep-alive\n\n") != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = s.streamEvents(ctx, req, func(message StreamMessage) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if message.Cursor != "" {
+			return write("id: %s\nevent: %s\ndata: %s\n\n", message.Cursor, message.Type, data)
+		}
+		return write("event: %s\ndata: %s\n\n", message.Type, data)
+	})
+	if err != nil {
+		write("event: error\ndata: %q\n\n", err.Error())
+	}
+}
+
+// streamUpgrader accepts WebSocket connections from any origin, the stream only serves public chain data
+var streamUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleWebSocket streams events as JSON text messages over a WebSocket
+func (s *IndexerService) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	req, err := parseStreamRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Reading handles pings and notices when the client goes away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	go func() {
+		ticker := time.NewTicker(streamKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamKeepAliveInterval))
+				mu.Unlock()
+				if err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = s.streamEvents(ctx, req, func(message StreamMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return conn.WriteJSON(message)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		log.Printf("Event stream closed: %v\n", err)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+		return
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
@@ -0,0 +1,173 @@
+package eventsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testStream runs streamEvents of req in the background, the messages it sends arrive on the returned channel.
+// Cancelling the returned function stops it and returns the error streamEvents ended with.
+func testStream(t *testing.T, s *IndexerService, req streamRequest, send func(StreamMessage)) (<-chan StreamMessage, func() error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan StreamMessage, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.streamEvents(ctx, req, func(message StreamMessage) error {
+			if send != nil {
+				send(message)
+			}
+			messages <- message
+			return nil
+		})
+	}()
+	stop := func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream did not stop")
+			return nil
+		}
+	}
+	t.Cleanup(func() { cancel() })
+	return messages, stop
+}
+
+// receiveStream returns the next n messages of a test stream and fails the test when they do not arrive
+func receiveStream(t *testing.T, messages <-chan StreamMessage, n int) []string {
+	t.Helper()
+	received := make([]string, 0, n)
+	for len(received) < n {
+		select {
+		case message := <-messages:
+			if message.Type == StreamRollback {
+				received = append(received, fmt.Sprintf("rollback %d", message.FromBlock))
+			} else {
+				received = append(received, fmt.Sprintf("%d:%d", message.Event.BlockNumber, message.Event.LogIndex))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want %d messages", received, n)
+		}
+	}
+	return received
+}
+
+// assertNoStream fails the test if the stream sends another message
+func assertNoStream(t *testing.T, messages <-chan StreamMessage) {
+	t.Helper()
+	select {
+	case message := <-messages:
+		t.Fatalf("stream sent %+v, want nothing more", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// waitSubscribed waits until the event stream has n subscribers
+func waitSubscribed(t *testing.T, st *eventStream, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		st.mu.Lock()
+		subscribers := len(st.subscribers)
+		st.mu.Unlock()
+		if subscribers == n {
+			return
+		}
+	}
+	t.Fatalf("event stream did not reach %d subscribers", n)
+}
+
+func TestStreamReplayHandoff(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	s := openTestService(t, testSQLiteConfig(t))
+	writeTestRange(t, s.sink, 100, 104, testTransfers(t, &sig, 100, 104, 2))
+
+	// Blocks 103 to 106 are committed while the first replayed event is sent, the live copies of
+	// 103 and 104 queue up behind the replay and are skipped, 105 and 106 follow it
+	rewritten := false
+	messages, stop := testStream(t, s, streamRequest{Cursor: encodeEventCursor(101, 0)}, func(StreamMessage) {
+		if !rewritten {
+			rewritten = true
+			writeTestRange(t, s.sink, 103, 106, testTransfers(t, &sig, 103, 106, 2))
+		}
+	})
+	want := []string{"101:1", "102:0", "102:1", "103:0", "103:1", "104:0", "104:1", "105:0", "105:1", "106:0", "106:1"}
+	if got := receiveStream(t, messages, len(want)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("stream sent %v, want %v", got, want)
+	}
+	assertNoStream(t, messages)
+
+	// A rollback moves the position back, the re-indexed events of the removed blocks are sent again
+	if err := s.sink.Rollback(105); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	writeTestRange(t, s.sink, 105, 107, testTransfers(t, &sig, 105, 107, 1))
+	want = []string{"rollback 105", "105:0", "106:0", "107:0"}
+	if got := receiveStream(t, messages, len(want)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("stream sent %v, want %v", got, want)
+	}
+
+	// Filters apply to live events too
+	filtered, stopFiltered := testStream(t, s, streamRequest{EventName: "Approval"}, nil)
+	waitSubscribed(t, s.stream, 2)
+	writeTestRange(t, s.sink, 108, 108, testTransfers(t, &sig, 108, 108, 1))
+	receiveStream(t, messages, 1)
+	assertNoStream(t, filtered)
+
+	if err := stop(); err != nil {
+		t.Fatalf("stream ended with %v, want nil", err)
+	}
+	if err := stopFiltered(); err != nil {
+		t.Fatalf("filtered stream ended with %v, want nil", err)
+	}
+	waitSubscribed(t, s.stream, 0)
+}
+
+func TestStreamDropsSlowSubscriber(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	s := openTestService(t, testSQLiteConfig(t))
+
+	// The subscriber blocks on its first event while more than its buffer is committed
+	release := make(chan struct{})
+	blocked := false
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.streamEvents(ctx, streamRequest{}, func(StreamMessage) error {
+			if !blocked {
+				blocked = true
+				<-release
+			}
+			return nil
+		})
+	}()
+	waitSubscribed(t, s.stream, 1)
+
+	writeTestRange(t, s.sink, 100, 100, testTransfers(t, &sig, 100, 100, 1))
+	writeTestRange(t, s.sink, 101, 211, testTransfers(t, &sig, 101, 211, 10))
+	close(release)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errStreamTooSlow) {
+			t.Fatalf("slow stream ended with %v, want %v", err, errStreamTooSlow)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber was not dropped")
+	}
+	waitSubscribed(t, s.stream, 0)
+
+	// Shutting down ends the remaining streams
+	messages, stop := testStream(t, s, streamRequest{}, nil)
+	waitSubscribed(t, s.stream, 1)
+	s.stream.close()
+	assertNoStream(t, messages)
+	if err := stop(); !errors.Is(err, errStreamClosed) {
+		t.Fatalf("stream ended with %v after shutdown, want %v", err, errStreamClosed)
+	}
+}
@@ -0,0 +1,517 @@
+package eventsdb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// Statuses of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead" // Gave up after WebhookMaxAttempts, eventsdb webhooks retry queues it again
+)
+
+// Webhook delivery settings
+const (
+	DefaultWebhookMaxAttempts = 10
+	webhookRetryDelay         = 5 * time.Second // Delay of the first retry, doubled for every further attempt
+	webhookMaxRetryDelay      = time.Hour
+	webhookPollInterval       = time.Second
+	webhookBatchSize          = 100
+	webhookTimeout            = 10 * time.Second
+	webhookKeepDelivered      = 7 * 24 * time.Hour // Delivered rows are pruned after this
+)
+
+// matches reports whether the webhook subscribes to the event
+func (w *Webhook) matches(event *BlockchainEvent) bool {
+	if w.ContractAddress != "" && w.ContractAddress != event.ContractAddress {
+		return false
+	}
+	if w.EventNames == "" {
+		return true
+	}
+	if event.EventName == nil {
+		return false
+	}
+	for _, name := range strings.Split(w.EventNames, ",") {
+		if name == *event.EventName {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookOutbox queues a delivery per matching webhook in the write transaction of the events,
+// so a committed event is never missed and a rolled back one is never sent.
+// Payloads are the messages of the event stream.
+type webhookOutbox struct{}
+
+func (webhookOutbox) AfterWrite(tx *gorm.DB, fromBlock, toBlock uint64, events []BlockchainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var webhooks []Webhook
+	if err := tx.Find(&webhooks).Error; err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	var deliveries []WebhookDelivery
+	for i := range events {
+		event := &events[i]
+		var payload []byte
+		for _, webhook := range webhooks {
+			if !webhook.matches(event) {
+				continue
+			}
+			if payload == nil {
+				record := newEventRecord(event)
+				var err error
+				payload, err = json.Marshal(StreamMessage{Type: StreamEvent, Cursor: encodeEventCursor(event.BlockNumber, event.LogIndex), Event: &record})
+				if err != nil {
+					return fmt.Errorf("failed to encode webhook payload: %w", err)
+				}
+			}
+			deliveries = append(deliveries, WebhookDelivery{
+				WebhookID:     webhook.ID,
+				Kind:          StreamEvent,
+				BlockNumber:   event.BlockNumber,
+				LogIndex:      event.LogIndex,
+				Payload:       string(payload),
+				Status:        DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := tx.CreateInBatches(deliveries, rollupBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// AfterRollback drops the undelivered events of the removed blocks and queues a rollback message for every webhook
+// with deliveries of them. Pending ones count too, the worker may be posting one while it is dropped.
+func (webhookOutbox) AfterRollback(tx *gorm.DB, fromBlock uint64) error {
+	var webhookIDs []uint
+	err := tx.Model(&WebhookDelivery{}).
+		Where("kind = ? AND block_number >= ?", StreamEvent, fromBlock).
+		Distinct("webhook_id").
+		Pluck("webhook_id", &webhookIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to query rolled back webhook deliveries: %w", err)
+	}
+	if len(webhookIDs) == 0 {
+		return nil
+	}
+
+	err = tx.Where("kind = ? AND status <> ? AND block_number >= ?", StreamEvent, DeliveryDelivered, fromBlock).
+		Delete(&WebhookDelivery{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to drop rolled back webhook deliveries: %w", err)
+	}
+
+	payload, err := json.Marshal(StreamMessage{Type: StreamRollback, FromBlock: fromBlock})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	now := time.Now().UTC()
+	deliveries := make([]WebhookDelivery, 0, len(webhookIDs))
+	for _, id := range webhookIDs {
+		deliveries = append(deliveries, WebhookDelivery{
+			WebhookID:     id,
+			Kind:          StreamRollback,
+			BlockNumber:   fromBlock,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if err := tx.Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to queue webhook rollbacks: %w", err)
+	}
+	return nil
+}
+
+// webhookSignature is the X-Eventsdb-Signature header of a payload
+func webhookSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the delay before the next attempt after attempts failed ones
+func webhookBackoff(attempts int) time.Duration {
+	if attempts > 20 {
+		return webhookMaxRetryDelay
+	}
+	return min(webhookRetryDelay<<(attempts-1), webhookMaxRetryDelay)
+}
+
+// postWebhook sends one delivery, any status but 2xx fails it
+func postWebhook(ctx context.Context, client *http.Client, webhook *Webhook, delivery *WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eventsdb-webhook")
+	req.Header.Set("X-Eventsdb-Webhook", webhook.Name)
+	req.Header.Set("X-Eventsdb-Delivery", fmt.Sprint(delivery.ID))
+	req.Header.Set("X-Eventsdb-Signature", webhookSignature(webhook.Secret, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// deliverWebhooks posts the due deliveries in queue order and returns how many were delivered.
+// A webhook that fails is skipped until its retry is due, so a down endpoint is not tried for every event.
+// It stops when ctx is done, a delivery cut off by ctx is not counted as an attempt.
+func deliverWebhooks(ctx context.Context, db *gorm.DB, client *http.Client, maxAttempts int) (int, error) {
+	now := time.Now().UTC()
+	var due []WebhookDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).Order("id").Limit(webhookBatchSize).Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	var webhooks []Webhook
+	if err := db.Find(&webhooks).Error; err != nil {
+		return 0, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	byID := make(map[uint]*Webhook, len(webhooks))
+	for i := range webhooks {
+		byID[webhooks[i].ID] = &webhooks[i]
+	}
+
+	// A webhook waiting to retry a delivery gets no new ones until the retry is due
+	var backingOff []uint
+	err := db.Model(&WebhookDelivery{}).
+		Where("status = ? AND attempts > 0 AND next_attempt_at > ?", DeliveryPending, now).
+		Distinct("webhook_id").
+		Pluck("webhook_id", &backingOff).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	failed := make(map[uint]bool)
+	for _, id := range backingOff {
+		failed[id] = true
+	}
+
+	delivered := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		delivery := &due[i]
+		webhook, ok := byID[delivery.WebhookID]
+		if !ok || failed[delivery.WebhookID] {
+			continue
+		}
+
+		attempts := delivery.Attempts + 1
+		updates := map[string]interface{}{"attempts": attempts}
+		if err := postWebhook(ctx, client, webhook, delivery); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			failed[delivery.WebhookID] = true
+			updates["last_error"] = err.Error()
+			if attempts >= maxAttempts {
+				updates["status"] = DeliveryDead
+				log.Printf("Webhook %s: delivery %d failed %d times, giving up: %v\n", webhook.Name, delivery.ID, attempts, err)
+			} else {
+				updates["next_attempt_at"] = time.Now().UTC().Add(webhookBackoff(attempts))
+				log.Printf("Webhook %s: delivery %d failed (attempt %d): %v\n", webhook.Name, delivery.ID, attempts, err)
+			}
+		} else {
+			updates["status"] = DeliveryDelivered
+			updates["delivered_at"] = time.Now().UTC()
+			delivered++
+		}
+
+		if err := db.Model(&WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			return delivered, fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, err)
+		}
+	}
+	return delivered, nil
+}
+
+// runWebhooks delivers queued webhook payloads until ctx is done, then tries the queue once more
+func (s *IndexerService) runWebhooks(ctx context.Context) {
+	client := &http.Client{Timeout: webhookTimeout}
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			// Deliver what the last writes queued
+			flushCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+			s.drainWebhooks(flushCtx, client)
+			cancel()
+			return
+		case <-ticker.C:
+		}
+
+		s.drainWebhooks(ctx, client)
+
+		if time.Since(pruned) > time.Hour {
+			pruned = time.Now()
+			cutoff := time.Now().UTC().Add(-webhookKeepDelivered)
+			if err := s.db.Where("status = ? AND delivered_at < ?", DeliveryDelivered, cutoff).Delete(&WebhookDelivery{}).Error; err != nil {
+				log.Printf("Warning: Failed to prune webhook deliveries: %v\n", err)
+			}
+		}
+	}
+}
+
+// drainWebhooks delivers batches until the due deliveries are done or ctx is
+func (s *IndexerService) drainWebhooks(ctx context.Context, client *http.Client) {
+	for ctx.Err() == nil {
+		delivered, err := deliverWebhooks(ctx, s.db, client, s.config.WebhookMaxAttempts)
+		if err != nil {
+			log.Printf("Warning: Failed to deliver webhooks: %v\n", err)
+		}
+		if err != nil || delivered < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// AddWebhook registers a webhook for the named events of contract, empty values match everything.
+// A random secret is generated when none is given.
+func (s *IndexerService) AddWebhook(name, webhookURL, contract string, eventNames []string, secret string) (*Webhook, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("webhook name is empty")
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q", webhookURL)
+	}
+	if contract != "" {
+		if !common.IsHexAddress(contract) {
+			return nil, fmt.Errorf("invalid contract address %q", contract)
+		}
+		contract = common.HexToAddress(contract).Hex()
+	}
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	names := make([]string, 0, len(eventNames))
+	for _, eventName := range eventNames {
+		if eventName = strings.TrimSpace(eventName); eventName != "" {
+			names = append(names, eventName)
+		}
+	}
+
+	webhook := &Webhook{
+		Name:            name,
+		URL:             webhookURL,
+		Secret:          secret,
+		ContractAddress: contract,
+		EventNames:      strings.Join(names, ","),
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := s.db.Create(webhook).Error; err != nil {
+		return nil, fmt.Errorf("failed to add webhook %s: %w", name, err)
+	}
+	log.Printf("Added webhook %s for %s\n", name, webhookURL)
+	return webhook, nil
+}
+
+// ListWebhooks returns the registered webhooks
+func (s *IndexerService) ListWebhooks() ([]Webhook, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return nil, err
+	}
+
+	var webhooks []Webhook
+	if err := s.db.Order("name").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// findWebhook returns the webhook called name
+func findWebhook(db *gorm.DB, name string) (*Webhook, error) {
+	var webhook Webhook
+	err := db.Where("name = ?", name).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("webhook %s not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook %s: %w", name, err)
+	}
+	return &webhook, nil
+}
+
+// RemoveWebhook deletes a webhook and its queued deliveries
+func (s *IndexerService) RemoveWebhook(name string) error {
+	if err := s.ensureDatabase(); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		webhook, err := findWebhook(tx, name)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete deliveries of webhook %s: %w", name, err)
+		}
+		if err := tx.Delete(webhook).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook %s: %w", name, err)
+		}
+		log.Printf("Removed webhook %s\n", name)
+		return nil
+	})
+}
+
+// RetryWebhook queues the dead deliveries of a webhook again and returns how many
+func (s *IndexerService) RetryWebhook(name string) (int64, error) {
+	if err := s.ensureDatabase(); err != nil {
+		return 0, err
+	}
+
+	webhook, err := findWebhook(s.db, name)
+	if err != nil {
+		return 0, err
+	}
+	result := s.db.Model(&WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, DeliveryDead).
+		Updates(map[string]interface{}{"status": DeliveryPending, "attempts": 0, "next_attempt_at": time.Now().UTC()})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to retry deliveries of webhook %s: %w", name, result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// WebhookStatus is the delivery state of a webhook
+type WebhookStatus struct {
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	Pending       int64      `json:"pending"`
+	Retrying      int64      `json:"retrying"` // Pending deliveries that failed before
+	Dead          int64      `json:"dead"`
+	Delivered     int64      `json:"delivered"` // Delivered in the last week, older rows are pruned
+	LagBlocks     uint64     `json:"lagBlocks"` // Indexed blocks since the oldest pending delivery
+	OldestPending *time.Time `json:"oldestPending,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// WebhookStatuses returns the lag and failures of every webhook
+func (s *IndexerService) WebhookStatuses() ([]WebhookStatus, error) {
+	webhooks, err := s.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	cursor, _, err := readCursor(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	type statusCount struct {
+		WebhookID  uint
+		Status     string
+		Deliveries int64
+		Retried    int64
+		FirstBlock uint64
+	}
+	var counts []statusCount
+	err = s.db.Model(&WebhookDelivery{}).
+		Select("webhook_id, status, COUNT(*) AS deliveries, SUM(CASE WHEN attempts > 0 THEN 1 ELSE 0 END) AS retried, MIN(block_number) AS first_block").
+		Group("webhook_id, status").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	statuses := make([]WebhookStatus, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		status := WebhookStatus{Name: webhook.Name, URL: webhook.URL}
+		for _, count := range counts {
+			if count.WebhookID != webhook.ID {
+				continue
+			}
+			switch count.Status {
+			case DeliveryPending:
+				status.Pending = count.Deliveries
+				status.Retrying = count.Retried
+				if uint64(cursor.Count) > count.FirstBlock {
+					status.LagBlocks = uint64(cursor.Count) - count.FirstBlock
+				}
+			case DeliveryDead:
+				status.Dead = count.Deliveries
+			case DeliveryDelivered:
+				status.Delivered = count.Deliveries
+			}
+		}
+
+		if status.Pending > 0 {
+			var oldest []WebhookDelivery
+			if err := s.db.Where("webhook_id = ? AND status = ?", webhook.ID, DeliveryPending).Order("id").Limit(1).Find(&oldest).Error; err != nil {
+				return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+			}
+			if len(oldest) > 0 {
+				status.OldestPending = &oldest[0].CreatedAt
+			}
+		}
+
+		var failed []WebhookDelivery
+		if err := s.db.Where("webhook_id = ? AND last_error <> ''", webhook.ID).Order("id DESC").Limit(1).Find(&failed).Error; err != nil {
+			return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+		}
+		if len(failed) > 0 {
+			status.LastError = failed[0].LastError
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
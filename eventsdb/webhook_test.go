@@ -0,0 +1,140 @@
+package eventsdb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// webhookReceiver records the messages posted to a test webhook endpoint
+type webhookReceiver struct {
+	mu       sync.Mutex
+	messages []StreamMessage
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	var message StreamMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.messages = append(r.messages, message)
+	r.mu.Unlock()
+}
+
+// received returns the messages posted so far and forgets them
+func (r *webhookReceiver) received() []StreamMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := r.messages
+	r.messages = nil
+	return messages
+}
+
+// deliverTestWebhooks delivers every due delivery and fails the test unless want were delivered
+func deliverTestWebhooks(t *testing.T, s *IndexerService, want int) {
+	t.Helper()
+	delivered, err := deliverWebhooks(context.Background(), s.db, &http.Client{Timeout: webhookTimeout}, DefaultWebhookMaxAttempts)
+	if err != nil || delivered != want {
+		t.Fatalf("delivered %d webhook payloads (error %v), want %d", delivered, err, want)
+	}
+}
+
+// countDeliveries returns the queued deliveries of kind with status
+func countDeliveries(t *testing.T, s *IndexerService, kind, status string) int64 {
+	t.Helper()
+	var count int64
+	if err := s.db.Model(&WebhookDelivery{}).Where("kind = ? AND status = ?", kind, status).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count webhook deliveries: %v", err)
+	}
+	return count
+}
+
+func TestWebhookAddedWhileRunning(t *testing.T) {
+	s := openTestService(t, testSQLiteConfig(t))
+	writeTestRange(t, s.sink, 100, 109, testTransfers(t, nil, 100, 109, 1))
+	if queued := countDeliveries(t, s, StreamEvent, DeliveryPending); queued != 0 {
+		t.Fatalf("%d deliveries queued without webhooks", queued)
+	}
+
+	// A webhook added to a running indexer gets the events committed from then on
+	if _, err := s.AddWebhook("all", "http://localhost/hook", "", nil, ""); err != nil {
+		t.Fatalf("failed to add webhook: %v", err)
+	}
+	writeTestRange(t, s.sink, 110, 119, testTransfers(t, nil, 110, 119, 1))
+	var blocks []uint64
+	if err := s.db.Model(&WebhookDelivery{}).Where("kind = ? AND status = ?", StreamEvent, DeliveryPending).
+		Order("block_number").Pluck("block_number", &blocks).Error; err != nil {
+		t.Fatalf("failed to load webhook deliveries: %v", err)
+	}
+	if len(blocks) != 10 || blocks[0] != 110 || blocks[9] != 119 {
+		t.Fatalf("deliveries queued for blocks %v, want 110 to 119", blocks)
+	}
+}
+
+func TestWebhookOutboxRollback(t *testing.T) {
+	_, sig := testTransferSignature(t)
+	config := testSQLiteConfig(t)
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	setup := NewIndexerService(config)
+	for _, webhook := range []struct{ name, contract, event string }{
+		{"transfers", testContract, "Transfer"},
+		{"approvals", "", "Approval"},
+	} {
+		var events []string
+		if webhook.event != "" {
+			events = []string{webhook.event}
+		}
+		if _, err := setup.AddWebhook(webhook.name, server.URL, webhook.contract, events, ""); err != nil {
+			t.Fatalf("failed to add webhook: %v", err)
+		}
+	}
+	setup.sink.Close()
+
+	s := openTestService(t, config)
+	writeTestRange(t, s.sink, 100, 109, testTransfers(t, &sig, 100, 109, 1))
+	deliverTestWebhooks(t, s, 10)
+	if messages := receiver.received(); len(messages) != 10 || messages[0].Event.BlockNumber != 100 || messages[9].Event.TxHash == "" {
+		t.Fatalf("webhook received %+v, want the 10 transfers", messages)
+	}
+
+	// A rollback below the delivered events drops nothing delivered and tells the webhook
+	if err := s.sink.Rollback(105); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	deliverTestWebhooks(t, s, 1)
+	if messages := receiver.received(); len(messages) != 1 || messages[0].Type != StreamRollback || messages[0].FromBlock != 105 {
+		t.Fatalf("webhook received %+v, want a rollback from block 105", messages)
+	}
+
+	// Pending deliveries of the removed blocks are dropped, the worker may be posting them so a rollback follows them
+	writeTestRange(t, s.sink, 105, 119, testTransfers(t, &sig, 105, 119, 1))
+	if err := s.sink.Rollback(115); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if pending := countDeliveries(t, s, StreamEvent, DeliveryPending); pending != 10 {
+		t.Fatalf("%d event deliveries pending after the rollback, want the 10 of blocks 105 to 114", pending)
+	}
+	if rollbacks := countDeliveries(t, s, StreamRollback, DeliveryPending); rollbacks != 1 {
+		t.Fatalf("%d rollback deliveries pending, want one for the transfers webhook only", rollbacks)
+	}
+	deliverTestWebhooks(t, s, 11)
+	messages := receiver.received()
+	if len(messages) != 11 || messages[9].Event.BlockNumber != 114 || messages[10].Type != StreamRollback || messages[10].FromBlock != 115 {
+		t.Fatalf("webhook received %+v, want blocks 105 to 114 and a rollback from block 115", messages)
+	}
+
+	// Delivered rows are kept until they are pruned
+	if delivered := countDeliveries(t, s, StreamEvent, DeliveryDelivered); delivered != 20 {
+		t.Fatalf("%d event deliveries delivered, want 20", delivered)
+	}
+}
@@ -0,0 +1,132 @@
+package eventsdb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// writeModeTestEvents are decoded, undecoded and anonymous events, the shapes a write mode has to store alike
+func writeModeTestEvents(t testing.TB) []BlockchainEvent {
+	t.Helper()
+	_, sig := testTransferSignature(t)
+
+	events := testTransfers(t, &sig, 100, 149, 4)
+	events = append(events, testTransfers(t, nil, 150, 159, 2)...)
+	anonymous, err := buildEvent(types.Log{
+		Address:     common.HexToAddress(testContract),
+		BlockNumber: 160,
+		TxHash:      common.HexToHash("0xa0"),
+		BlockHash:   testBlockHash(160),
+	}, nil, false)
+	if err != nil {
+		t.Fatalf("failed to build event: %v", err)
+	}
+	return append(events, anonymous)
+}
+
+// writeModeRows writes the test events with config and returns the stored rows without their ids and insert times.
+// The second write overlaps the first so upserts are covered too.
+func writeModeRows(t *testing.T, config Config) []BlockchainEvent {
+	t.Helper()
+	db, sink := openTestSink(t, config)
+
+	// The sink sets the ids of written events, the indexer builds new events for every range
+	writeTestRange(t, sink, 100, 129, writeModeTestEvents(t)[:120])
+	writeTestRange(t, sink, 100, 169, writeModeTestEvents(t))
+
+	stored := storedEvents(t, db)
+	for i := range stored {
+		stored[i].ID = 0
+		stored[i].InsertTime = time.Time{}
+	}
+	return stored
+}
+
+// assertWriteModesAgree fails the test unless every mode stores the rows of the first one
+func assertWriteModesAgree(t *testing.T, newConfig func(testing.TB) Config, modes []string) {
+	var want []BlockchainEvent
+	for _, mode := range modes {
+		config := newConfig(t)
+		config.WriteMode = mode
+		config.WriteBatchSize = 7 // Leaves a partial last batch
+
+		got := writeModeRows(t, config)
+		if want == nil {
+			want = got
+			if len(want) != len(writeModeTestEvents(t)) {
+				t.Fatalf("%s mode stored %d events, want %d", mode, len(want), len(writeModeTestEvents(t)))
+			}
+			continue
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s mode stored %d events, %s mode %d", mode, len(got), modes[0], len(want))
+		}
+		for i := range want {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Fatalf("%s mode stored %+v, %s mode %+v", mode, got[i], modes[0], want[i])
+			}
+		}
+	}
+}
+
+func TestWriteModesStoreSameRows(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		assertWriteModesAgree(t, testSQLiteConfig, []string{WriteModeRow, WriteModeBatch})
+	})
+	t.Run("postgres", func(t *testing.T) {
+		assertWriteModesAgree(t, testPostgresConfig, []string{WriteModeRow, WriteModeBatch, WriteModeCopy})
+	})
+	t.Run("postgres binary", func(t *testing.T) {
+		assertWriteModesAgree(t, func(t testing.TB) Config {
+			config := testPostgresConfig(t)
+			config.BinaryStorage = true
+			return config
+		}, []string{WriteModeRow, WriteModeBatch, WriteModeCopy})
+	})
+	t.Run("postgres partitioned", func(t *testing.T) {
+		assertWriteModesAgree(t, func(t testing.TB) Config {
+			config := testPostgresConfig(t)
+			config.PartitionSize = 25
+			return config
+		}, []string{WriteModeRow, WriteModeBatch, WriteModeCopy})
+	})
+}
+
+// benchmarkWriteRange writes ranges of 100 blocks with 10 events each, every iteration into new blocks
+func benchmarkWriteRange(b *testing.B, config Config) {
+	const blocks, perBlock = 100, 10
+	_, sig := testTransferSignature(b)
+	_, sink := openTestSink(b, config)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		fromBlock := uint64(i * blocks)
+		events := testTransfers(b, &sig, fromBlock, fromBlock+blocks-1, perBlock)
+		b.StartTimer()
+
+		writeTestRange(b, sink, fromBlock, fromBlock+blocks-1, events)
+	}
+	b.ReportMetric(float64(b.N*blocks*perBlock)/b.Elapsed().Seconds(), "events/s")
+}
+
+func BenchmarkWriteRange(b *testing.B) {
+	for _, mode := range []string{WriteModeRow, WriteModeBatch} {
+		b.Run("sqlite/"+mode, func(b *testing.B) {
+			config := testSQLiteConfig(b)
+			config.WriteMode = mode
+			benchmarkWriteRange(b, config)
+		})
+	}
+	for _, mode := range []string{WriteModeRow, WriteModeBatch, WriteModeCopy} {
+		b.Run("postgres/"+mode, func(b *testing.B) {
+			config := testPostgresConfig(b)
+			config.WriteMode = mode
+			benchmarkWriteRange(b, config)
+		})
+	}
+}
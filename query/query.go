@@ -0,0 +1,7 @@
+// Package query embeds the shipped report queries, the files next to it are PostgreSQL and sqlite/ holds the SQLite versions.
+package query
+
+import "embed"
+
+//go:embed *.sql sqlite/*.sql
+var Files embed.FS
@@ -1,13 +1,14 @@
--- Unknown Events: Events without decoded names
+-- Unknown Events: Signatures without an event in the loaded ABIs
 SELECT
-    event_signature,
-    COUNT(*) as occurrences,
-    MIN(block_number) as first_seen,
-    MAX(block_number) as last_seen,
-    array_agg(DISTINCT tx_hash) as sample_txs
-FROM blockchain_events
-WHERE event_name IS NULL OR event_signature IS NULL 
-GROUP BY event_signature
+    c.event_signature,
+    SUM(c.events) as occurrences,
+    MIN(c.first_block) as first_seen,
+    MAX(c.last_block) as last_seen
+FROM event_counts_daily c
+WHERE NOT EXISTS (
+    SELECT 1 FROM abi_event_records a WHERE a.event_signature_hash = c.event_signature
+)
+GROUP BY c.event_signature
 ORDER BY occurrences DESC
 LIMIT 10
-;
\ No newline at end of file
+;
@@ -1,8 +1,8 @@
--- Gives a daily count of events for trend analysis
-SELECT 
-    DATE(insert_time) AS day,
-    COUNT(*) AS event_count
-FROM blockchain_events
-GROUP BY DATE(insert_time)
-ORDER BY day DESC
+-- Gives a daily count of events by block time for trend analysis
+SELECT
+    (bucket AT TIME ZONE 'UTC')::date AS day,
+    SUM(events) AS event_count
+FROM event_counts_daily
+GROUP BY bucket
+ORDER BY bucket DESC
 LIMIT 30;
@@ -0,0 +1,32 @@
+-- Event totals and events per block of all time and of the blocks of the last 24 hours, from the rollup tables
+WITH
+blocks AS (
+    SELECT block_number, MAX(block_time) AS block_time, SUM(events) AS event_count
+    FROM event_counts_block
+    GROUP BY block_number
+),
+last_24h_blocks AS (
+    SELECT block_number, event_count
+    FROM blocks
+    WHERE block_time >= datetime('now', '-24 hours')
+)
+SELECT
+    (SELECT COALESCE(SUM(events), 0) FROM event_counts_daily) AS total_events,
+    (SELECT COALESCE(SUM(event_count), 0) FROM last_24h_blocks) AS total_events_24h,
+
+    -- Formatted numeric outputs
+    (SELECT
+        CASE
+            WHEN COUNT(*) = 0 THEN '0.00'
+            ELSE printf('%.2f', CAST(SUM(event_count) AS REAL) / COUNT(*))
+        END
+     FROM blocks) AS avg_events_per_block,
+    (SELECT
+        CASE
+            WHEN COUNT(*) = 0 THEN '0.00'
+            ELSE printf('%.2f', CAST(SUM(event_count) AS REAL) / COUNT(*))
+        END
+     FROM last_24h_blocks) AS avg_events_per_block_last_24h,
+
+    (SELECT COALESCE(MAX(event_count), 0) FROM blocks) AS max_event_count,
+    (SELECT COALESCE(MAX(event_count), 0) FROM last_24h_blocks) AS max_event_count_per_24h;
@@ -0,0 +1,17 @@
+-- Top 5 most frequent events in the last 24 hours, counted by block time in hourly buckets
+WITH event_names AS (
+    SELECT event_signature_hash, MIN(event_name) AS event_name
+    FROM abi_event_records
+    GROUP BY event_signature_hash
+)
+SELECT
+    n.event_name,
+    SUM(c.events) AS event_count,
+    MIN(c.first_block) as first_block,
+    MAX(c.last_block) as last_block
+FROM event_counts_hourly c
+LEFT JOIN event_names n ON n.event_signature_hash = c.event_signature
+WHERE c.bucket >= strftime('%Y-%m-%d %H:00:00', 'now', '-24 hours')
+GROUP BY n.event_name
+ORDER BY event_count DESC
+LIMIT 5;
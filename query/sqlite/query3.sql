@@ -0,0 +1,16 @@
+-- Top 5 most frequent events of all time
+WITH event_names AS (
+    SELECT event_signature_hash, MIN(event_name) AS event_name
+    FROM abi_event_records
+    GROUP BY event_signature_hash
+)
+SELECT
+    n.event_name,
+    SUM(c.events) AS event_count,
+    MIN(c.first_block) as first_block,
+    MAX(c.last_block) as last_block
+FROM event_counts_daily c
+LEFT JOIN event_names n ON n.event_signature_hash = c.event_signature
+GROUP BY n.event_name
+ORDER BY event_count DESC
+LIMIT 5;
@@ -0,0 +1,14 @@
+-- Unknown Events: Signatures without an event in the loaded ABIs
+SELECT
+    c.event_signature,
+    SUM(c.events) as occurrences,
+    MIN(c.first_block) as first_seen,
+    MAX(c.last_block) as last_seen
+FROM event_counts_daily c
+WHERE NOT EXISTS (
+    SELECT 1 FROM abi_event_records a WHERE a.event_signature_hash = c.event_signature
+)
+GROUP BY c.event_signature
+ORDER BY occurrences DESC
+LIMIT 10
+;
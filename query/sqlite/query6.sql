@@ -0,0 +1,8 @@
+-- Gives a daily count of events by block time for trend analysis
+SELECT
+    date(bucket) AS day,
+    SUM(events) AS event_count
+FROM event_counts_daily
+GROUP BY bucket
+ORDER BY bucket DESC
+LIMIT 30;
@@ -1,3 +1,3 @@
 export CONTRACT_ADDRESS="0x91Cf2D8Ed503EC52768999aA6D8DBeA6e52dbe43"
 export RPC_URL="https://0xrpc.io/base"
-go run service.go
+go run ./cmd/eventsdb